@@ -0,0 +1,299 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+const (
+	// Deposit/withdrawal value tolerance enforced by the vault: 0.1% (+1 wei)
+	contractToleranceBps = 10
+)
+
+// Withdrawal rounding policies (WITHDRAWAL_ROUNDING): how each token's share of the withdrawal
+// value is converted to a token amount
+const (
+	// Round down, then top up the largest-weight token if the total is short of the target, like deposits
+	withdrawalRoundingFloorTopUp = "floor-with-topup"
+	// Round up, so no token provides less than its share
+	withdrawalRoundingCeil = "ceil"
+	// Round to the nearest unit, topping up only if the total falls outside the vault's tolerance
+	withdrawalRoundingNearest = "nearest"
+)
+
+// roundedDiv returns a / b for positive a and b, rounded per the withdrawal rounding policy
+// (down for floor-with-topup)
+func roundedDiv(a, b *big.Int, rounding string) *big.Int {
+	switch rounding {
+	case withdrawalRoundingCeil:
+		return new(big.Int).Div(new(big.Int).Add(a, new(big.Int).Sub(b, big.NewInt(1))), b)
+	case withdrawalRoundingNearest:
+		return new(big.Int).Div(new(big.Int).Add(a, new(big.Int).Rsh(b, 1)), b)
+	default:
+		return new(big.Int).Div(a, b)
+	}
+}
+
+// normalizeDecimals converts amount from one decimal base to another, truncating like the vault does
+func normalizeDecimals(amount *big.Int, fromDecimals, toDecimals uint8) *big.Int {
+	if fromDecimals >= toDecimals {
+		return new(big.Int).Div(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(fromDecimals-toDecimals)), nil))
+	}
+	return new(big.Int).Mul(amount, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(toDecimals-fromDecimals)), nil))
+}
+
+// computeDepositAmounts calculates the underlying token amounts to deliver for a deposit.
+//
+// The quote amount is normalized to oracle decimals, allocated across tokens by weight and
+// converted to token amounts with floor division: amount = (allocation * 10^tokenDecimals) / price.
+// If flooring leaves the total value below the target, the largest-weight token is topped up
+// with ceiling division so the vault always receives at least the deposited value.
+// Returns an error if the result is still outside toleranceBps (+1 wei) of the target.
+func computeDepositAmounts(
+	quoteAmount *big.Int,
+	weights []*big.Int,
+	prices []*big.Int,
+	tokenDecimals []uint8,
+	quoteDecimals uint8,
+	oracleDecimals uint8,
+	toleranceBps int64,
+) ([]*big.Int, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no underlying tokens")
+	}
+	if len(prices) != len(weights) || len(tokenDecimals) != len(weights) {
+		return nil, fmt.Errorf("input length mismatch: %d weights, %d prices, %d decimals", len(weights), len(prices), len(tokenDecimals))
+	}
+
+	if quoteAmount.Sign() <= 0 {
+		return nil, fmt.Errorf("quote amount must be positive, got %s", quoteAmount.String())
+	}
+
+	totalWeight := big.NewInt(0)
+	for _, weight := range weights {
+		totalWeight = new(big.Int).Add(totalWeight, weight)
+	}
+	if totalWeight.Sign() <= 0 {
+		return nil, fmt.Errorf("total weight is zero - vault target weights are misconfigured")
+	}
+
+	for i, price := range prices {
+		if price.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid oracle price %s for token index %d", price.String(), i)
+		}
+	}
+
+	// Normalize quote amount to oracle decimals for calculations
+	// oracle.getValue() returns values in oracle decimals, so we must normalize quoteAmount
+	normalizedQuoteAmount := normalizeDecimals(quoteAmount, quoteDecimals, oracleDecimals)
+
+	// Step 1: Calculate base amounts for each token using floor division
+	// oracle.getValue(token, amount) = (amount * price) / 10^tokenDecimals
+	// We want: amount = floor((valueAllocation * 10^tokenDecimals) / price)
+	underlyingAmounts := make([]*big.Int, len(weights))
+	totalProvidedValue := big.NewInt(0)
+
+	for i, weight := range weights {
+		// valueAllocation = normalizedQuoteAmount * weight / totalWeight
+		valueAllocation := new(big.Int).Div(new(big.Int).Mul(normalizedQuoteAmount, weight), totalWeight)
+
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals[i])), nil)
+		numerator := new(big.Int).Mul(valueAllocation, tokenDecMultiplier)
+		amount := new(big.Int).Div(numerator, prices[i])
+		underlyingAmounts[i] = amount
+
+		// actualValue = (amount * price) / 10^tokenDecimals
+		actualValue := new(big.Int).Div(new(big.Int).Mul(amount, prices[i]), tokenDecMultiplier)
+		totalProvidedValue = new(big.Int).Add(totalProvidedValue, actualValue)
+	}
+
+	// Step 2: If we're providing less than required, top up the largest-weight token (usually most liquid)
+	if totalProvidedValue.Cmp(normalizedQuoteAmount) < 0 {
+		maxWeightIdx := 0
+		for i, w := range weights {
+			if w.Cmp(weights[maxWeightIdx]) > 0 {
+				maxWeightIdx = i
+			}
+		}
+
+		shortfall := new(big.Int).Sub(normalizedQuoteAmount, totalProvidedValue)
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals[maxWeightIdx])), nil)
+		price := prices[maxWeightIdx]
+
+		// Ceiling division: (a + b - 1) / b
+		increaseNumerator := new(big.Int).Mul(shortfall, tokenDecMultiplier)
+		increaseAmount := new(big.Int).Div(
+			new(big.Int).Add(increaseNumerator, new(big.Int).Sub(price, big.NewInt(1))),
+			price,
+		)
+
+		// Always add at least 1 token to ensure value improvement
+		if increaseAmount.Sign() <= 0 {
+			increaseAmount = big.NewInt(1)
+		}
+
+		underlyingAmounts[maxWeightIdx] = new(big.Int).Add(underlyingAmounts[maxWeightIdx], increaseAmount)
+
+		increaseValue := new(big.Int).Div(new(big.Int).Mul(increaseAmount, price), tokenDecMultiplier)
+		totalProvidedValue = new(big.Int).Add(totalProvidedValue, increaseValue)
+	}
+
+	// Step 3: Verify the result matches the target within tolerance
+	tolerance := valueTolerance(normalizedQuoteAmount, toleranceBps)
+	difference := new(big.Int).Abs(new(big.Int).Sub(totalProvidedValue, normalizedQuoteAmount))
+	if difference.Cmp(tolerance) > 0 {
+		return nil, fmt.Errorf("computed value %s differs from target %s by %s, exceeding tolerance %s",
+			totalProvidedValue.String(), normalizedQuoteAmount.String(), difference.String(), tolerance.String())
+	}
+
+	return underlyingAmounts, nil
+}
+
+// valueTolerance is how far a provided value may differ from target: toleranceBps of it, plus 1 wei
+func valueTolerance(target *big.Int, toleranceBps int64) *big.Int {
+	tolerance := new(big.Int).Div(new(big.Int).Mul(target, big.NewInt(toleranceBps)), big.NewInt(10000))
+	return tolerance.Add(tolerance, big.NewInt(1))
+}
+
+// Deposit allocation modes (DEPOSIT_ALLOCATION_MODE)
+const (
+	// Split every deposit by target weight, preserving the vault's current skew
+	depositAllocationTarget = "target"
+	// Split deposits so the vault's value-weighted composition moves toward target
+	depositAllocationRebalancing = "rebalancing"
+)
+
+// rebalancingAllocation splits depositValue (oracle units) across tokens so the vault's
+// composition after the deposit is as close to the target weights as the deposit allows.
+//
+// Each token's shortfall is its target share of the post-deposit value minus its current value,
+// or zero for overweight tokens. The shortfalls add up to at least the deposit, so splitting the
+// deposit in proportion to them fills underweight tokens first and gives overweight tokens
+// nothing; a vault already on target gets a split by target weight. The result is meant as the
+// weights argument of computeDepositAmounts.
+func rebalancingAllocation(
+	depositValue *big.Int,
+	weights []*big.Int,
+	balances []*big.Int,
+	prices []*big.Int,
+	tokenDecimals []uint8,
+) ([]*big.Int, error) {
+	if len(balances) != len(weights) || len(prices) != len(weights) || len(tokenDecimals) != len(weights) {
+		return nil, fmt.Errorf("input length mismatch: %d weights, %d balances, %d prices, %d decimals",
+			len(weights), len(balances), len(prices), len(tokenDecimals))
+	}
+
+	if depositValue.Sign() <= 0 {
+		return nil, fmt.Errorf("deposit value must be positive, got %s", depositValue.String())
+	}
+
+	totalWeight := big.NewInt(0)
+	for _, weight := range weights {
+		totalWeight = new(big.Int).Add(totalWeight, weight)
+	}
+	if totalWeight.Sign() <= 0 {
+		return nil, fmt.Errorf("total weight is zero - vault target weights are misconfigured")
+	}
+
+	values := make([]*big.Int, len(weights))
+	total := new(big.Int).Set(depositValue)
+	for i := range weights {
+		values[i] = totalValue(balances[i:i+1], prices[i:i+1], tokenDecimals[i:i+1])
+		total.Add(total, values[i])
+	}
+
+	shortfalls := make([]*big.Int, len(weights))
+	totalShortfall := big.NewInt(0)
+	for i, weight := range weights {
+		target := new(big.Int).Div(new(big.Int).Mul(total, weight), totalWeight)
+		shortfalls[i] = new(big.Int).Sub(target, values[i])
+		if shortfalls[i].Sign() < 0 {
+			shortfalls[i].SetInt64(0)
+		}
+		totalShortfall.Add(totalShortfall, shortfalls[i])
+	}
+
+	if totalShortfall.Sign() == 0 {
+		return weights, nil // only possible through rounding of tiny values
+	}
+
+	allocation := make([]*big.Int, len(weights))
+	for i := range weights {
+		allocation[i] = new(big.Int).Div(new(big.Int).Mul(depositValue, shortfalls[i]), totalShortfall)
+	}
+	return allocation, nil
+}
+
+// totalValue sums oracle values of amounts: sum(amount * price / 10^tokenDecimals)
+func totalValue(amounts, prices []*big.Int, tokenDecimals []uint8) *big.Int {
+	total := big.NewInt(0)
+	for i, amount := range amounts {
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals[i])), nil)
+		total.Add(total, new(big.Int).Div(new(big.Int).Mul(amount, prices[i]), tokenDecMultiplier))
+	}
+	return total
+}
+
+// exceedsBps reports whether value is more than bps of target above target
+func exceedsBps(value, target *big.Int, bps int64) bool {
+	limit := new(big.Int).Div(new(big.Int).Mul(target, big.NewInt(bps)), big.NewInt(10000))
+	limit.Add(limit, target)
+	return value.Cmp(limit) > 0
+}
+
+// trimExcessValue reduces amounts when their total value exceeds target by more than
+// toleranceBps (+1 wei), trimming the lowest-weight tokens first. Each trim removes at most
+// the excess over target (floor division), so trimming cannot push the total meaningfully
+// below target. Returns the trimmed amounts and their total value; amounts is not modified.
+func trimExcessValue(
+	amounts []*big.Int,
+	weights []*big.Int,
+	prices []*big.Int,
+	tokenDecimals []uint8,
+	target *big.Int,
+	toleranceBps int64,
+) ([]*big.Int, *big.Int) {
+	trimmed := make([]*big.Int, len(amounts))
+	for i, amount := range amounts {
+		trimmed[i] = new(big.Int).Set(amount)
+	}
+
+	upperBound := valueTolerance(target, toleranceBps)
+	upperBound.Add(upperBound, target)
+
+	total := totalValue(trimmed, prices, tokenDecimals)
+	if total.Cmp(upperBound) <= 0 {
+		return trimmed, total
+	}
+
+	// Lowest weight first
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return weights[order[a]].Cmp(weights[order[b]]) < 0
+	})
+
+	for _, i := range order {
+		if total.Cmp(upperBound) <= 0 {
+			break
+		}
+
+		excess := new(big.Int).Sub(total, target)
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals[i])), nil)
+		reduce := new(big.Int).Div(new(big.Int).Mul(excess, tokenDecMultiplier), prices[i])
+		if reduce.Cmp(trimmed[i]) > 0 {
+			reduce = new(big.Int).Set(trimmed[i])
+		}
+		if reduce.Sign() <= 0 {
+			continue
+		}
+
+		trimmed[i].Sub(trimmed[i], reduce)
+		total = totalValue(trimmed, prices, tokenDecimals)
+	}
+
+	return trimmed, total
+}
@@ -0,0 +1,321 @@
+package engine
+
+import (
+	"math/big"
+	"testing"
+)
+
+func bigInts(values ...int64) []*big.Int {
+	out := make([]*big.Int, len(values))
+	for i, v := range values {
+		out[i] = big.NewInt(v)
+	}
+	return out
+}
+
+func TestNormalizeDecimals(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		from, to uint8
+		want     int64
+	}{
+		{amount: 1234567, from: 6, to: 6, want: 1234567},
+		{amount: 1234567, from: 6, to: 8, want: 123456700},
+		{amount: 1234567, from: 6, to: 4, want: 12345},
+		{amount: 9, from: 6, to: 5, want: 0},
+	}
+
+	for _, tt := range tests {
+		got := normalizeDecimals(big.NewInt(tt.amount), tt.from, tt.to)
+		if got.Int64() != tt.want {
+			t.Errorf("normalizeDecimals(%d, %d, %d) = %s, want %d", tt.amount, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestComputeDepositAmounts(t *testing.T) {
+	tests := []struct {
+		name           string
+		quoteAmount    int64
+		weights        []*big.Int
+		prices         []*big.Int
+		tokenDecimals  []uint8
+		quoteDecimals  uint8
+		oracleDecimals uint8
+		want           []*big.Int
+		wantErr        bool
+	}{
+		{
+			name:           "exact split",
+			quoteAmount:    100_000000,
+			weights:        bigInts(5000, 5000),
+			prices:         bigInts(1_000000, 2_000000),
+			tokenDecimals:  []uint8{6, 6},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			want:           bigInts(50_000000, 25_000000),
+		},
+		{
+			name:           "quote decimals above oracle decimals",
+			quoteAmount:    5_000000_999999999999, // the sub-oracle-unit remainder truncates away
+			weights:        bigInts(5000, 5000),
+			prices:         bigInts(1_000000, 2_000000),
+			tokenDecimals:  []uint8{6, 6},
+			quoteDecimals:  18,
+			oracleDecimals: 6,
+			want:           bigInts(2_500000, 1_250000),
+		},
+		{
+			name:           "quote decimals below oracle decimals",
+			quoteAmount:    100_000000,
+			weights:        bigInts(5000, 5000),
+			prices:         bigInts(1_00000000, 2_00000000),
+			tokenDecimals:  []uint8{6, 6},
+			quoteDecimals:  6,
+			oracleDecimals: 8,
+			want:           bigInts(50_000000, 25_000000),
+		},
+		{
+			name:           "floor shortfall topped up on largest weight",
+			quoteAmount:    1000,
+			weights:        bigInts(3000, 7000),
+			prices:         bigInts(3, 3),
+			tokenDecimals:  []uint8{0, 0},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			// allocations 300 and 700 floor to 100 and 233 tokens (value 999); top up 1 token on index 1
+			want: bigInts(100, 234),
+		},
+		{
+			name:           "coarse token cannot meet tolerance",
+			quoteAmount:    1_000000,
+			weights:        bigInts(10000),
+			prices:         bigInts(700000),
+			tokenDecimals:  []uint8{0},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+		{
+			name:           "zero quote amount",
+			quoteAmount:    0,
+			weights:        bigInts(10000),
+			prices:         bigInts(1_000000),
+			tokenDecimals:  []uint8{6},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+		{
+			name:           "zero total weight",
+			quoteAmount:    1_000000,
+			weights:        bigInts(0, 0),
+			prices:         bigInts(1_000000, 1_000000),
+			tokenDecimals:  []uint8{6, 6},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+		{
+			name:           "zero price",
+			quoteAmount:    1_000000,
+			weights:        bigInts(10000),
+			prices:         bigInts(0),
+			tokenDecimals:  []uint8{6},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+		{
+			name:           "length mismatch",
+			quoteAmount:    1_000000,
+			weights:        bigInts(5000, 5000),
+			prices:         bigInts(1_000000),
+			tokenDecimals:  []uint8{6, 6},
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+		{
+			name:           "no tokens",
+			quoteAmount:    1_000000,
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			wantErr:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := computeDepositAmounts(big.NewInt(tt.quoteAmount), tt.weights, tt.prices, tt.tokenDecimals,
+				tt.quoteDecimals, tt.oracleDecimals, contractToleranceBps)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got amounts %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d amounts, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i].Cmp(tt.want[i]) != 0 {
+					t.Errorf("amount[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExceedsBps(t *testing.T) {
+	tests := []struct {
+		value, target, bps int64
+		want               bool
+	}{
+		{value: 1_000000, target: 1_000000, bps: 200, want: false},
+		{value: 1_020000, target: 1_000000, bps: 200, want: false},
+		{value: 1_020001, target: 1_000000, bps: 200, want: true},
+		{value: 999999, target: 1_000000, bps: 0, want: false},
+		{value: 1_000001, target: 1_000000, bps: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := exceedsBps(big.NewInt(tt.value), big.NewInt(tt.target), tt.bps); got != tt.want {
+			t.Errorf("exceedsBps(%d, %d, %d) = %v, want %v", tt.value, tt.target, tt.bps, got, tt.want)
+		}
+	}
+}
+
+func TestTrimExcessValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		amounts   []*big.Int
+		weights   []*big.Int
+		want      []*big.Int
+		wantValue int64
+	}{
+		{
+			name:      "within band is unchanged",
+			amounts:   bigInts(700_500, 300_000),
+			weights:   bigInts(7000, 3000),
+			want:      bigInts(700_500, 300_000),
+			wantValue: 1_000_500,
+		},
+		{
+			name:      "excess trimmed from lowest weight",
+			amounts:   bigInts(700_000, 310_000),
+			weights:   bigInts(7000, 3000),
+			want:      bigInts(700_000, 300_000),
+			wantValue: 1_000_000,
+		},
+		{
+			name:      "spills over to next lowest weight",
+			amounts:   bigInts(1_020_000, 5_000),
+			weights:   bigInts(7000, 3000),
+			want:      bigInts(1_000_000, 0),
+			wantValue: 1_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, value := trimExcessValue(
+				tt.amounts,
+				tt.weights,
+				bigInts(1_000000, 1_000000),
+				[]uint8{6, 6},
+				big.NewInt(1_000_000),
+				contractToleranceBps,
+			)
+			for i := range tt.want {
+				if got[i].Cmp(tt.want[i]) != 0 {
+					t.Errorf("amount[%d] = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+			if value.Int64() != tt.wantValue {
+				t.Errorf("value = %s, want %d", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestRebalancingAllocation(t *testing.T) {
+	e18, _ := new(big.Int).SetString("50000000000000000000", 10) // 50 tokens with 18 decimals
+	tests := []struct {
+		name         string
+		depositValue int64
+		weights      []*big.Int
+		balances     []*big.Int
+		prices       []*big.Int
+		decimals     []uint8
+		want         []int64
+	}{
+		{
+			name:         "deposit smaller than the gap goes to the underweight token",
+			depositValue: 50,
+			weights:      bigInts(5000, 5000),
+			balances:     bigInts(100, 0),
+			prices:       bigInts(1000000, 1000000),
+			decimals:     []uint8{6, 6},
+			want:         []int64{0, 50},
+		},
+		{
+			name:         "deposit larger than the gap ends on target",
+			depositValue: 300,
+			weights:      bigInts(5000, 5000),
+			balances:     bigInts(100, 0),
+			prices:       bigInts(1000000, 1000000),
+			decimals:     []uint8{6, 6},
+			want:         []int64{100, 200},
+		},
+		{
+			name:         "balanced vault splits by target weight",
+			depositValue: 50,
+			weights:      bigInts(5000, 5000),
+			balances:     bigInts(100, 100),
+			prices:       bigInts(1000000, 1000000),
+			decimals:     []uint8{6, 6},
+			want:         []int64{25, 25},
+		},
+		{
+			name:         "three tokens with one overweight",
+			depositValue: 700,
+			weights:      bigInts(6000, 3000, 1000),
+			balances:     bigInts(0, 300, 0),
+			prices:       bigInts(1000000, 1000000, 1000000),
+			decimals:     []uint8{6, 6, 6},
+			want:         []int64{600, 0, 100},
+		},
+		{
+			name:         "balances are compared by oracle value",
+			depositValue: 100,
+			weights:      bigInts(5000, 5000),
+			balances:     []*big.Int{big.NewInt(0), e18}, // 50 tokens at price 2 = 100
+			prices:       bigInts(1000000, 2),
+			decimals:     []uint8{6, 18},
+			want:         []int64{100, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rebalancingAllocation(big.NewInt(tt.depositValue), tt.weights, tt.balances, tt.prices, tt.decimals)
+			if err != nil {
+				t.Fatalf("rebalancingAllocation: %v", err)
+			}
+			for i, want := range tt.want {
+				if got[i].Int64() != want {
+					t.Errorf("allocation = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	if _, err := rebalancingAllocation(big.NewInt(0), bigInts(1), bigInts(0), bigInts(1), []uint8{6}); err == nil {
+		t.Error("rebalancingAllocation accepted a zero deposit")
+	}
+}
@@ -0,0 +1,668 @@
+package engine
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Default and maximum number of requests examined by list endpoints
+	apiDefaultLimit = 100
+	apiMaxLimit     = 1000
+	// Response header with the before= value of the next page of a list endpoint
+	apiNextBeforeHeader = "X-Next-Before"
+	// Timeout for draining in-flight API requests on shutdown
+	apiShutdownTimeout = 5 * time.Second
+)
+
+// APIVault is the JSON representation of a managed vault
+type APIVault struct {
+	Name             string   `json:"name"`
+	Address          string   `json:"address"`
+	QuoteToken       string   `json:"quote_token"`
+	UnderlyingTokens []string `json:"underlying_tokens"`
+	NextDepositID    string   `json:"next_deposit_id"`
+	NextWithdrawalID string   `json:"next_withdrawal_id"`
+}
+
+// APIStatus is returned by GET /status
+type APIStatus struct {
+	FulfillerAddress string           `json:"fulfiller_address"`
+	ChainID          string           `json:"chain_id"`
+	Nonce            uint64           `json:"nonce"` // pending nonce of the fulfiller account
+	Paused           bool             `json:"paused"`
+	PausedSince      string           `json:"paused_since,omitempty"` // RFC 3339
+	Vaults           []APIVaultStatus `json:"vaults"`
+}
+
+// APIVaultStatus is the per-vault part of APIStatus. Balances are the fulfiller's own.
+type APIVaultStatus struct {
+	Name               string            `json:"name"`
+	Address            string            `json:"address"`
+	LastProcessedBlock uint64            `json:"last_processed_block"`
+	QuoteToken         APITokenBalance   `json:"quote_token"`
+	UnderlyingTokens   []APITokenBalance `json:"underlying_tokens"`
+}
+
+// APITokenBalance is a token and the fulfiller's balance of it (token base units)
+type APITokenBalance struct {
+	Token   string `json:"token"`
+	Balance string `json:"balance"`
+}
+
+// APIRequest is the JSON representation of a deposit or withdrawal request.
+// The vault deletes requests once fulfilled or cancelled, so a cleared slot
+// (zero user) is reported as fulfilled.
+type APIRequest struct {
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Amount    string `json:"amount"`
+	Fulfilled bool   `json:"fulfilled"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// APIFulfillRequest is the body of POST /vaults/{name}/fulfill
+type APIFulfillRequest struct {
+	Type string `json:"type"` // "deposit" or "withdrawal"
+	ID   string `json:"id"`
+}
+
+// APIFulfillResponse is returned by POST /vaults/{name}/fulfill
+type APIFulfillResponse struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	TxHash string `json:"tx_hash,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// APILogLevel is the body of POST /loglevel and the response of GET and POST /loglevel
+type APILogLevel struct {
+	Level string `json:"level"` // DEBUG, INFO, WARN or ERROR
+}
+
+// APIPauseState is returned by POST /pause and POST /resume
+type APIPauseState struct {
+	Paused      bool   `json:"paused"`
+	PausedSince string `json:"paused_since,omitempty"` // RFC 3339
+}
+
+// APIServer exposes a JSON API over the managed vaults. Read endpoints are
+// public; operator endpoints require the admin bearer token.
+type APIServer struct {
+	config     *Config
+	fulfillers []*Fulfiller
+	rpcClient  *RPCClient
+	server     *http.Server
+	ctx        context.Context // engine context, used for operator-triggered fulfillments
+}
+
+func NewAPIServer(config *Config, fulfillers []*Fulfiller, rpcClient *RPCClient) *APIServer {
+	s := &APIServer{
+		config:     config,
+		fulfillers: fulfillers,
+		rpcClient:  rpcClient,
+		ctx:        context.Background(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/loglevel", s.handleLogLevel)
+	mux.HandleFunc("/pause", s.handlePause)
+	mux.HandleFunc("/resume", s.handlePause)
+	mux.HandleFunc("/vaults", s.handleVaults)
+	mux.HandleFunc("/vaults/", s.handleVault)
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", config.APIPort),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Start serves the API until the context is cancelled, then shuts down gracefully
+func (s *APIServer) Start(ctx context.Context) error {
+	s.ctx = ctx
+
+	errChan := make(chan error, 1)
+	go func() {
+		Logger.Info("API server listening", "addr", s.server.Addr)
+		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errChan <- err
+		}
+		close(errChan)
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			Logger.Warn("API server shutdown error", "error", err)
+		}
+		Logger.Info("API server stopped")
+		return ctx.Err()
+	}
+}
+
+func (s *APIServer) findFulfiller(name string) *Fulfiller {
+	for _, f := range s.fulfillers {
+		if strings.EqualFold(f.vaultConfig.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// handleHealth serves GET /healthz (liveness: the process is up)
+func (s *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady serves GET /readyz (readiness: the RPC connection is healthy)
+func (s *APIServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	connected := s.rpcClient.Connected()
+	status := http.StatusOK
+	if !connected {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]bool{"rpc_connected": connected})
+}
+
+// handleMetrics serves GET /metrics: fulfillment latency histograms and last oracle prices in the
+// Prometheus text format
+func (s *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP tone_fulfillment_latency_seconds Time from the on-chain request to the confirmed fulfillment.")
+	fmt.Fprintln(w, "# TYPE tone_fulfillment_latency_seconds histogram")
+	for _, f := range s.fulfillers {
+		f.latency.writePrometheus(w, f.vaultConfig.Name)
+	}
+	fmt.Fprintln(w, "# HELP tone_oracle_price Last oracle price read for the token, in oracle decimals.")
+	fmt.Fprintln(w, "# TYPE tone_oracle_price gauge")
+	for _, f := range s.fulfillers {
+		f.prices.writePrometheus(w, f.vaultConfig.Name)
+	}
+}
+
+// handleLogLevel serves GET /loglevel and POST /loglevel, which changes the log level until
+// the next restart or config reload
+func (s *APIServer) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !s.authorized(r) {
+			writeAPIError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		var req APILogLevel
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+			writeAPIError(w, http.StatusBadRequest, "level must be DEBUG, INFO, WARN or ERROR")
+			return
+		}
+		previous := logLevel.Level()
+		logLevel.Set(level)
+		Logger.Warn("Log level changed via API",
+			"previous_level", previous.String(),
+			"level", level.String(),
+			"remote_addr", r.RemoteAddr,
+		)
+	default:
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, APILogLevel{Level: logLevel.Level().String()})
+}
+
+// handlePause serves POST /pause and POST /resume. Pausing stops new fulfillments; the
+// listeners keep tracking events and fulfill the requests deferred meanwhile once resumed.
+func (s *APIServer) handlePause(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorized(r) {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	if r.URL.Path == "/pause" {
+		if fulfillmentPause.pause() {
+			Logger.Warn("Fulfillment paused via API", "remote_addr", r.RemoteAddr)
+		}
+	} else if fulfillmentPause.resume() {
+		Logger.Warn("Fulfillment resumed via API", "remote_addr", r.RemoteAddr)
+	}
+	writeJSON(w, http.StatusOK, apiPauseState())
+}
+
+func apiPauseState() APIPauseState {
+	since := fulfillmentPause.pausedSince()
+	if since.IsZero() {
+		return APIPauseState{}
+	}
+	return APIPauseState{Paused: true, PausedSince: since.UTC().Format(time.RFC3339)}
+}
+
+// handleStatus serves GET /status: the fulfiller wallet, its funding and each vault's progress
+func (s *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if len(s.fulfillers) == 0 {
+		writeAPIError(w, http.StatusServiceUnavailable, "no vaults configured")
+		return
+	}
+	ctx := r.Context()
+	account := s.fulfillers[0].account // all vaults share the fulfiller account
+
+	chainID, err := withCallTimeout(ctx, s.config.RPCCallTimeout, "NetworkID", s.rpcClient.NetworkID)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, fmt.Sprintf("failed to get chain id: %v", err))
+		return
+	}
+	nonce, err := withCallTimeout(ctx, s.config.RPCCallTimeout, "PendingNonceAt", func(ctx context.Context) (uint64, error) {
+		return s.rpcClient.PendingNonceAt(ctx, account.fromAddress)
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, fmt.Sprintf("failed to get nonce: %v", err))
+		return
+	}
+
+	pause := apiPauseState()
+	status := APIStatus{
+		FulfillerAddress: account.fromAddress.Hex(),
+		ChainID:          chainID.String(),
+		Nonce:            nonce,
+		Paused:           pause.Paused,
+		PausedSince:      pause.PausedSince,
+		Vaults:           make([]APIVaultStatus, 0, len(s.fulfillers)),
+	}
+	for _, f := range s.fulfillers {
+		vault, err := s.vaultStatus(ctx, f)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		status.Vaults = append(status.Vaults, vault)
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleVaults serves GET /vaults
+func (s *APIServer) handleVaults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	vaults := make([]APIVault, 0, len(s.fulfillers))
+	for _, f := range s.fulfillers {
+		vault, err := s.describeVault(r.Context(), f)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		vaults = append(vaults, vault)
+	}
+	writeJSON(w, http.StatusOK, vaults)
+}
+
+// handleVault serves GET /vaults/{name}, /vaults/{name}/deposits, /vaults/{name}/withdrawals,
+// /vaults/{name}/composition, /vaults/{name}/nav, /vaults/{name}/gas, /vaults/{name}/held,
+// /vaults/{name}/dead-letters and POST /vaults/{name}/fulfill
+func (s *APIServer) handleVault(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/vaults/"), "/"), "/")
+	if len(parts) == 0 || parts[0] == "" || len(parts) > 2 {
+		writeAPIError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	f := s.findFulfiller(parts[0])
+	if f == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("unknown vault %q", parts[0]))
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "fulfill" {
+		s.handleFulfill(w, r, f)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if len(parts) == 1 {
+		vault, err := s.describeVault(r.Context(), f)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, vault)
+		return
+	}
+
+	switch parts[1] {
+	case "deposits":
+		s.handleRequests(w, r, f.GetNextDepositId, func(ctx context.Context, id *big.Int) (*APIRequest, error) {
+			deposit, err := f.GetPendingDeposit(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return newAPIRequest(id, deposit.User, deposit.QuoteAmount, deposit.Fulfilled, deposit.Timestamp), nil
+		})
+	case "withdrawals":
+		s.handleRequests(w, r, f.GetNextWithdrawalId, func(ctx context.Context, id *big.Int) (*APIRequest, error) {
+			withdrawal, err := f.GetPendingWithdrawal(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return newAPIRequest(id, withdrawal.User, withdrawal.SharesAmount, withdrawal.Fulfilled, withdrawal.Timestamp), nil
+		})
+	case "held":
+		writeJSON(w, http.StatusOK, f.holds.List())
+	case "dead-letters":
+		writeJSON(w, http.StatusOK, f.deadLetters.List(f.vaultConfig.Name))
+	case "composition":
+		composition, err := f.GetVaultComposition(r.Context())
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, composition)
+	case "nav":
+		price, err := f.GetSharePrice(r.Context())
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, price)
+	case "gas":
+		writeJSON(w, http.StatusOK, f.gas.report(f.vaultConfig.Name))
+	default:
+		writeAPIError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleRequests lists the most recent requests, newest first.
+// Query parameters: status=pending|fulfilled|all (default all), limit=N (default 100),
+// before=ID (start below this id). At most limit ids are read per call, one eth_call each, so a
+// status filter can return fewer entries; the X-Next-Before header holds the before= value of
+// the next page.
+func (s *APIServer) handleRequests(
+	w http.ResponseWriter,
+	r *http.Request,
+	nextId func(context.Context) (*big.Int, error),
+	get func(context.Context, *big.Int) (*APIRequest, error),
+) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "all"
+	}
+	if status != "all" && status != "pending" && status != "fulfilled" {
+		writeAPIError(w, http.StatusBadRequest, "status must be one of: pending, fulfilled, all")
+		return
+	}
+
+	limit := apiDefaultLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		val, err := strconv.Atoi(limitStr)
+		if err != nil || val <= 0 {
+			writeAPIError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(val, apiMaxLimit)
+	}
+
+	next, err := nextId(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		before, ok := new(big.Int).SetString(beforeStr, 10)
+		if !ok || before.Sign() < 0 {
+			writeAPIError(w, http.StatusBadRequest, "before must be a non-negative request id")
+			return
+		}
+		if before.Cmp(next) < 0 {
+			next = before
+		}
+	}
+
+	// Request ids are uint256, so count down with a big.Int rather than an int64 that could overflow
+	requests := make([]APIRequest, 0)
+	one := big.NewInt(1)
+	id := new(big.Int).Sub(next, one)
+	for examined := 0; id.Sign() >= 0 && examined < limit; examined++ {
+		req, err := get(r.Context(), id)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		id.Sub(id, one)
+		if (status == "pending" && req.Fulfilled) || (status == "fulfilled" && !req.Fulfilled) {
+			continue
+		}
+		requests = append(requests, *req)
+	}
+	if id.Sign() >= 0 {
+		w.Header().Set(apiNextBeforeHeader, new(big.Int).Add(id, one).String())
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// handleFulfill serves POST /vaults/{name}/fulfill, forcing fulfillment of a single request
+func (s *APIServer) handleFulfill(w http.ResponseWriter, r *http.Request, f *Fulfiller) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !s.authorized(r) {
+		writeAPIError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req APIFulfillRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	id, ok := new(big.Int).SetString(req.ID, 10)
+	if !ok || id.Sign() < 0 {
+		writeAPIError(w, http.StatusBadRequest, "id must be a non-negative integer")
+		return
+	}
+	if fulfillmentPause.paused() {
+		writeAPIError(w, http.StatusConflict, "fulfillment is paused - POST /resume first")
+		return
+	}
+
+	Logger.Info("Manual fulfillment requested",
+		"vault_name", f.vaultConfig.Name,
+		"type", req.Type,
+		"id", id.String(),
+		"remote_addr", r.RemoteAddr,
+	)
+
+	// Use the engine context so a client disconnect does not abort a transaction mid-flight
+	ctx := s.ctx
+	var txHash common.Hash
+	switch req.Type {
+	case "deposit":
+		deposit, err := f.GetPendingDeposit(r.Context(), id)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		if deposit.Fulfilled || deposit.User == (common.Address{}) {
+			// Settled elsewhere, so there is nothing left to replay
+			f.deadLetters.Remove(f.vaultConfig.Name, req.Type, id.String())
+			writeAPIError(w, http.StatusConflict, fmt.Sprintf("deposit %s is not pending", id.String()))
+			return
+		}
+		// A manual fulfillment is an operator approval, so it bypasses the auto-fulfillment limits
+		f.holds.Approve("deposit", id)
+		txHash, err = f.FulfillDeposit(ctx, id, deposit.QuoteAmount, time.Unix(deposit.Timestamp.Int64(), 0))
+		if errors.Is(err, errAlreadySettled) {
+			writeAPIError(w, http.StatusConflict, fmt.Sprintf("deposit %s is not pending", id.String()))
+			return
+		}
+		if err != nil {
+			writeFulfillResult(w, http.StatusInternalServerError, req.Type, id, txHash, err)
+			return
+		}
+	case "withdrawal":
+		withdrawal, err := f.GetPendingWithdrawal(r.Context(), id)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		if withdrawal.Fulfilled || withdrawal.User == (common.Address{}) {
+			f.deadLetters.Remove(f.vaultConfig.Name, req.Type, id.String())
+			writeAPIError(w, http.StatusConflict, fmt.Sprintf("withdrawal %s is not pending", id.String()))
+			return
+		}
+		f.holds.Approve("withdrawal", id)
+		txHash, err = f.FulfillWithdrawal(ctx, id, withdrawal.SharesAmount, time.Unix(withdrawal.Timestamp.Int64(), 0))
+		if errors.Is(err, errAlreadySettled) {
+			writeAPIError(w, http.StatusConflict, fmt.Sprintf("withdrawal %s is not pending", id.String()))
+			return
+		}
+		if err != nil {
+			writeFulfillResult(w, http.StatusInternalServerError, req.Type, id, txHash, err)
+			return
+		}
+	default:
+		writeAPIError(w, http.StatusBadRequest, "type must be one of: deposit, withdrawal")
+		return
+	}
+
+	writeFulfillResult(w, http.StatusOK, req.Type, id, txHash, nil)
+}
+
+// authorized checks the bearer token; operator endpoints are disabled when no token is configured
+func (s *APIServer) authorized(r *http.Request) bool {
+	if s.config.AdminAPIToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminAPIToken)) == 1
+}
+
+func writeFulfillResult(w http.ResponseWriter, status int, kind string, id *big.Int, txHash common.Hash, err error) {
+	resp := APIFulfillResponse{Type: kind, ID: id.String()}
+	if txHash != (common.Hash{}) {
+		resp.TxHash = txHash.Hex()
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *APIServer) describeVault(ctx context.Context, f *Fulfiller) (APIVault, error) {
+	nextDeposit, err := f.GetNextDepositId(ctx)
+	if err != nil {
+		return APIVault{}, fmt.Errorf("failed to get nextDepositId: %v", err)
+	}
+	nextWithdrawal, err := f.GetNextWithdrawalId(ctx)
+	if err != nil {
+		return APIVault{}, fmt.Errorf("failed to get nextWithdrawalId: %v", err)
+	}
+
+	underlying := f.underlying().tokens
+	tokens := make([]string, len(underlying))
+	for i, token := range underlying {
+		tokens[i] = token.Hex()
+	}
+
+	return APIVault{
+		Name:             f.vaultConfig.Name,
+		Address:          f.vaultConfig.Address.Hex(),
+		QuoteToken:       f.quoteTokenAddress.Hex(),
+		UnderlyingTokens: tokens,
+		NextDepositID:    nextDeposit.String(),
+		NextWithdrawalID: nextWithdrawal.String(),
+	}, nil
+}
+
+func (s *APIServer) vaultStatus(ctx context.Context, f *Fulfiller) (APIVaultStatus, error) {
+	balance := func(token common.Address) (APITokenBalance, error) {
+		amount, err := f.getTokenBalance(ctx, token, f.account.fromAddress)
+		if err != nil {
+			return APITokenBalance{}, fmt.Errorf("failed to get balance for token %s: %v", token.Hex(), err)
+		}
+		return APITokenBalance{Token: token.Hex(), Balance: amount.String()}, nil
+	}
+
+	quote, err := balance(f.quoteTokenAddress)
+	if err != nil {
+		return APIVaultStatus{}, err
+	}
+	underlying := f.underlying().tokens
+	tokens := make([]APITokenBalance, len(underlying))
+	for i, token := range underlying {
+		if tokens[i], err = balance(token); err != nil {
+			return APIVaultStatus{}, err
+		}
+	}
+
+	return APIVaultStatus{
+		Name:               f.vaultConfig.Name,
+		Address:            f.vaultConfig.Address.Hex(),
+		LastProcessedBlock: f.processedBlock.Load(),
+		QuoteToken:         quote,
+		UnderlyingTokens:   tokens,
+	}, nil
+}
+
+func newAPIRequest(id *big.Int, user common.Address, amount *big.Int, fulfilled bool, timestamp *big.Int) *APIRequest {
+	return &APIRequest{
+		ID:        id.String(),
+		User:      user.Hex(),
+		Amount:    amount.String(),
+		Fulfilled: fulfilled || user == (common.Address{}),
+		Timestamp: timestamp.Int64(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		Logger.Warn("Failed to write API response", "error", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
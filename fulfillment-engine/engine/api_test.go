@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleRequestsBeyondInt64(t *testing.T) {
+	// More ids than an int64 can count
+	next := new(big.Int).Lsh(big.NewInt(1), 64)
+	next.Add(next, big.NewInt(2))
+
+	s := &APIServer{}
+	rec := httptest.NewRecorder()
+	s.handleRequests(rec, httptest.NewRequest("GET", "/vaults/AI/deposits?limit=2", nil),
+		func(context.Context) (*big.Int, error) { return next, nil },
+		func(ctx context.Context, id *big.Int) (*APIRequest, error) {
+			return &APIRequest{ID: id.String()}, nil
+		},
+	)
+
+	var requests []APIRequest
+	if err := json.NewDecoder(rec.Body).Decode(&requests); err != nil {
+		t.Fatalf("decode response (status %d): %v", rec.Code, err)
+	}
+	want := []string{"18446744073709551617", "18446744073709551616"}
+	if len(requests) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(requests), len(want))
+	}
+	for i, req := range requests {
+		if req.ID != want[i] {
+			t.Errorf("request %d id %s, want %s", i, req.ID, want[i])
+		}
+	}
+	if next.String() != "18446744073709551618" {
+		t.Errorf("next id modified to %s", next)
+	}
+}
+
+func TestHandleRequestsBoundsScan(t *testing.T) {
+	// Ids 0-9; only 2 and 7 are pending
+	next := big.NewInt(10)
+	calls := 0
+	s := &APIServer{}
+	list := func(query string) ([]APIRequest, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleRequests(rec, httptest.NewRequest("GET", "/vaults/AI/deposits?"+query, nil),
+			func(context.Context) (*big.Int, error) { return next, nil },
+			func(ctx context.Context, id *big.Int) (*APIRequest, error) {
+				calls++
+				return &APIRequest{ID: id.String(), Fulfilled: id.Int64() != 2 && id.Int64() != 7}, nil
+			},
+		)
+		var requests []APIRequest
+		if err := json.NewDecoder(rec.Body).Decode(&requests); err != nil {
+			t.Fatalf("decode response (status %d): %v", rec.Code, err)
+		}
+		return requests, rec.Header().Get(apiNextBeforeHeader)
+	}
+
+	requests, cursor := list("status=pending&limit=4")
+	if calls != 4 {
+		t.Errorf("read %d requests, want at most limit=4", calls)
+	}
+	if len(requests) != 1 || requests[0].ID != "7" || cursor != "6" {
+		t.Fatalf("first page %v, next before %q; want [7] and 6", requests, cursor)
+	}
+
+	requests, cursor = list("status=pending&limit=4&before=" + cursor)
+	if len(requests) != 1 || requests[0].ID != "2" || cursor != "2" {
+		t.Fatalf("second page %v, next before %q; want [2] and 2", requests, cursor)
+	}
+
+	requests, cursor = list("status=pending&limit=4&before=" + cursor)
+	if len(requests) != 0 || cursor != "" {
+		t.Errorf("last page %v, next before %q; want no entries and no cursor", requests, cursor)
+	}
+}
+
+// postFulfill calls handleFulfill with a JSON body and, unless token is empty, a bearer token
+func postFulfill(s *APIServer, f *Fulfiller, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/vaults/Test/fulfill", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.handleFulfill(rec, req, f)
+	return rec
+}
+
+func TestHandleFulfillUnauthorized(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	body := `{"type":"deposit","id":"1"}`
+
+	tests := []struct {
+		name       string
+		configured string
+		token      string
+	}{
+		{name: "missing token", configured: "secret"},
+		{name: "wrong token", configured: "secret", token: "guess"},
+		{name: "no token configured", token: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &APIServer{config: &Config{AdminAPIToken: tt.configured}, ctx: context.Background()}
+			if rec := postFulfill(s, f, tt.token, body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions without authorization, want none", len(client.sent))
+	}
+}
+
+func TestHandleFulfillSettled(t *testing.T) {
+	client := newMockEthClient()
+	client.settled[1] = true
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	s := &APIServer{config: &Config{AdminAPIToken: "secret"}, ctx: context.Background()}
+
+	if rec := postFulfill(s, f, "secret", `{"type":"deposit","id":"1"}`); rec.Code != http.StatusConflict {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions for a settled deposit, want none", len(client.sent))
+	}
+}
+
+func TestHandleFulfillReleasesHeld(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	f.holds = newRequestHolds()
+	f.config.MaxDepositValue = big.NewInt(0) // the mock's pending deposit of 1 is above it
+	s := &APIServer{config: &Config{AdminAPIToken: "secret"}, ctx: context.Background()}
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1), time.Time{}); !errors.Is(err, ErrRequestHeld) {
+		t.Fatalf("FulfillDeposit error = %v, want ErrRequestHeld", err)
+	}
+	if held := f.holds.List(); len(held) != 1 {
+		t.Fatalf("%d held requests, want 1", len(held))
+	}
+
+	rec := postFulfill(s, f, "secret", `{"type":"deposit","id":"1"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d (%s), want %d", rec.Code, rec.Body.String(), http.StatusOK)
+	}
+	var resp APIFulfillResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(client.sent) == 0 || resp.TxHash != client.sent[len(client.sent)-1].Hash().Hex() {
+		t.Errorf("response tx %q, want the sent fulfillment", resp.TxHash)
+	}
+	if held := f.holds.List(); len(held) != 0 {
+		t.Errorf("%d held requests after the manual fulfillment, want 0", len(held))
+	}
+}
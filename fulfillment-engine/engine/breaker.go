@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How often a subscription-mode listener retries requests deferred by an open circuit breaker
+const breakerRetryInterval = 30 * time.Second
+
+// circuitBreaker pauses a vault's fulfillments after repeated failures. It opens after threshold
+// consecutive failures within window and stays open for cooldown. Then it lets a single probe
+// request through: a successful fulfillment closes it, a failure reopens it for another cooldown.
+// A nil breaker never opens.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	now       func() time.Time
+
+	failures []time.Time // consecutive failures, oldest first
+	openedAt time.Time   // zero while closed
+	probing  bool        // a probe request is in flight
+}
+
+// newCircuitBreaker returns a breaker, or nil (disabled) if threshold is 0
+func newCircuitBreaker(threshold int, window, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &circuitBreaker{threshold: threshold, window: window, cooldown: cooldown, now: time.Now}
+}
+
+// ready reports whether a request would be let through, without claiming the probe
+func (b *circuitBreaker) ready() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openedAt.IsZero() || (!b.probing && b.now().Sub(b.openedAt) >= b.cooldown)
+}
+
+// allow reports whether a request may be fulfilled now. Once the cooldown has passed, the
+// first caller becomes the probe and later callers wait for its outcome.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record updates the breaker with the outcome of a request it let through. It returns whether
+// the breaker opened (or reopened) and whether it closed.
+func (b *circuitBreaker) record(outcome requestOutcome) (opened, closed bool) {
+	if b == nil {
+		return false, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasProbe := b.probing
+	b.probing = false
+	now := b.now()
+
+	switch outcome {
+	case outcomeFulfilled:
+		b.failures = nil
+		if !b.openedAt.IsZero() {
+			b.openedAt = time.Time{}
+			return false, true
+		}
+	case outcomeFailed:
+		if wasProbe {
+			b.openedAt = now
+			return true, false
+		}
+		// Only failures within the window count towards the threshold
+		b.failures = append(b.failures, now)
+		for len(b.failures) > 0 && now.Sub(b.failures[0]) > b.window {
+			b.failures = b.failures[1:]
+		}
+		if b.openedAt.IsZero() && len(b.failures) >= b.threshold {
+			b.openedAt = now
+			return true, false
+		}
+	}
+	// Settled, held or filtered requests say nothing about the vault; a probe is simply released
+	return false, false
+}
+
+// state returns "closed", "open" or "half-open" (cooldown over, awaiting a probe)
+func (b *circuitBreaker) state() string {
+	if b == nil {
+		return "closed"
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return "closed"
+	case b.probing || b.now().Sub(b.openedAt) >= b.cooldown:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// CircuitBreakerAlert is the JSON payload posted to the alert webhook when a vault's circuit
+// breaker opens. The Text field makes it directly consumable by Slack incoming webhooks.
+type CircuitBreakerAlert struct {
+	Text      string `json:"text"`
+	VaultName string `json:"vault_name"`
+	Vault     string `json:"vault"`
+	Failures  int    `json:"failures"`
+	Cooldown  string `json:"cooldown"`
+}
+
+// sendBreakerAlert posts a circuit breaker alert to webhookURL in the background
+func sendBreakerAlert(webhookURL string, alert CircuitBreakerAlert) {
+	if webhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		Logger.Error("Failed to marshal circuit breaker alert", "error", err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertWebhookTimeout)
+		defer cancel()
+		if err := postJSON(ctx, &http.Client{Timeout: alertWebhookTimeout}, webhookURL, body); err != nil {
+			Logger.Error("Failed to send circuit breaker alert", "vault_name", alert.VaultName, "error", err)
+		}
+	}()
+}
+
+// breakerAlert builds the alert for a vault whose breaker just opened
+func breakerAlert(vaultConfig VaultConfig, failures int, cooldown time.Duration) CircuitBreakerAlert {
+	return CircuitBreakerAlert{
+		Text: fmt.Sprintf("Circuit breaker open: fulfillments for vault %s (%s) paused for %s after %d consecutive failures",
+			vaultConfig.Name, vaultConfig.Address.Hex(), cooldown, failures),
+		VaultName: vaultConfig.Name,
+		Vault:     vaultConfig.Address.Hex(),
+		Failures:  failures,
+		Cooldown:  cooldown.String(),
+	}
+}
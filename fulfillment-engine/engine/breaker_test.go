@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newCircuitBreaker(3, time.Minute, 5*time.Minute)
+	b.now = func() time.Time { return now }
+
+	// Failures further apart than the window do not add up
+	b.record(outcomeFailed)
+	now = now.Add(2 * time.Minute)
+	b.record(outcomeFailed)
+	b.record(outcomeFailed)
+	if b.state() != "closed" {
+		t.Fatalf("state %s after 2 failures within the window, want closed", b.state())
+	}
+
+	// Requests that say nothing about the vault neither reset nor add to the count
+	b.record(outcomeSettled)
+	if opened, _ := b.record(outcomeFailed); !opened || b.allow() {
+		t.Fatalf("3rd consecutive failure: opened=%v, allow=%v; want the breaker open", opened, b.allow())
+	}
+
+	// After the cooldown exactly one probe is let through; its failure reopens the breaker
+	now = now.Add(5 * time.Minute)
+	if !b.ready() || !b.allow() || b.allow() {
+		t.Fatalf("after the cooldown want a single probe")
+	}
+	if opened, _ := b.record(outcomeFailed); !opened || b.state() != "open" {
+		t.Fatalf("failed probe: opened=%v, state %s; want reopened", opened, b.state())
+	}
+
+	// A successful probe closes it
+	now = now.Add(5 * time.Minute)
+	b.allow()
+	if _, closed := b.record(outcomeFulfilled); !closed || b.state() != "closed" || !b.allow() {
+		t.Fatalf("successful probe: closed=%v, state %s; want closed", closed, b.state())
+	}
+
+	var disabled *circuitBreaker
+	if newCircuitBreaker(0, time.Minute, time.Minute) != nil || !disabled.allow() {
+		t.Error("a zero threshold should disable the breaker")
+	}
+}
+
+func TestProcessLogDefersWhileBreakerOpen(t *testing.T) {
+	user := common.HexToAddress("0x01")
+	fake := &fakeFulfillment{
+		deposits:    map[int64]*PendingDeposit{1: {User: user}, 2: {User: user}, 3: {User: user}},
+		withdrawals: map[int64]*PendingWithdrawal{},
+		err:         errors.New("execution reverted"),
+	}
+	config := &Config{BreakerFailures: 2, BreakerWindow: time.Minute, BreakerCooldown: time.Minute}
+	l := NewEventListener(&fakeListenerClient{}, config, VaultConfig{Name: "Test"}, fake)
+	now := time.Unix(1700000000, 0)
+	l.breaker.now = func() time.Time { return now }
+
+	deposit := func(id int64) types.Log {
+		vLog := lifecycleLog(depositRequestedSignature, id)
+		vLog.TxHash = common.BigToHash(big.NewInt(id))
+		vLog.Data = append(common.LeftPadBytes(big.NewInt(1000000).Bytes(), 32), common.LeftPadBytes(big.NewInt(1700000000).Bytes(), 32)...)
+		return vLog
+	}
+
+	ctx := context.Background()
+	l.processLog(ctx, deposit(1))
+	l.processLog(ctx, deposit(2))
+	if outcome := l.processLog(ctx, deposit(3)); outcome != outcomeDeferred {
+		t.Fatalf("outcome %v after 2 failures, want deferred", outcome)
+	}
+	if len(fake.fulfilled) != 2 {
+		t.Fatalf("fulfill attempted %d times, want 2 before the breaker opened", len(fake.fulfilled))
+	}
+
+	// Nothing is retried during the cooldown
+	l.retryDeferred(ctx)
+	if len(fake.fulfilled) != 2 || len(l.deferred) != 1 {
+		t.Fatalf("retried during the cooldown: %v", fake.fulfilled)
+	}
+
+	// After the cooldown the deferred request is the probe, and its success closes the breaker
+	now = now.Add(time.Minute)
+	fake.err = nil
+	l.retryDeferred(ctx)
+	if len(fake.fulfilled) != 3 || len(l.deferred) != 0 || l.breaker.state() != "closed" {
+		t.Fatalf("after the cooldown: fulfilled %v, deferred %d, state %s", fake.fulfilled, len(l.deferred), l.breaker.state())
+	}
+}
@@ -0,0 +1,1076 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/joho/godotenv"
+)
+
+type VaultConfig struct {
+	Address common.Address
+	Name    string
+
+	// Startup backfill bounds for mature vaults (everything before is known to be settled)
+	StartBlock        uint64 // Scan no earlier than this block (combined with SCAN_FROM_BLOCK, the later wins)
+	StartDepositID    uint64 // Ignore deposits with a lower id
+	StartWithdrawalID uint64 // Ignore withdrawals with a lower id
+
+	// Immutable deployment values that skip their startup read when set (read from the vault if nil)
+	OracleAddress  *common.Address
+	OracleDecimals *uint8
+	QuoteToken     *common.Address
+	QuoteDecimals  *uint8
+
+	FulfillmentWebhookURL string // Receives a POST for each confirmed fulfillment (disabled if empty)
+
+	PollInterval int // Seconds between this vault's polls (POLL_INTERVAL if 0)
+}
+
+type Config struct {
+	PrivateKey      string
+	RPCURLs         []string // RPC endpoints in failover order; the first is the primary
+	WSURL           string   // Separate WebSocket endpoint for log subscriptions (RPC endpoints are used if empty)
+	SectorVaults    []VaultConfig
+	PollInterval    int
+	SharedListener  bool // Poll all vaults with a single FilterLogs query
+	SubscribeLogs   bool // Stream logs over a WebSocket subscription instead of polling
+	LogLevel        string
+	LogFormat       string
+	ShutdownTimeout time.Duration // Graceful shutdown timeout
+	RPCCallTimeout  time.Duration // Timeout applied to each individual RPC call
+	RPCMaxRPS       int           // Max outbound RPC calls per second, across all vaults (unlimited if 0)
+	ExpectedChainID uint64        // Abort at startup if the RPC serves another chain (unchecked if 0)
+	ScanFromBlock   uint64        // First block of the startup backfill (vault deployment block)
+	LogChunkSize    uint64        // Block range per FilterLogs call during the backfill
+	PollChunkSize   uint64        // Block range per FilterLogs call when polling new blocks
+	PollJitter      time.Duration // Max random delay added to each poll interval (disabled if 0)
+	MaxRequestAge   time.Duration // Backfill skips requests older than this (disabled if 0)
+	FulfillMode     string        // Requests this engine fulfills: both (default), deposits or withdrawals
+	RequestOrder    string        // Order of a batch of pending requests: fifo (default), largest or smallest
+
+	MaxHistoricalScan uint64 // Startup scan covers at most this many of the latest blocks (unlimited if 0)
+
+	// Log queries: rpc (eth_getLogs, default) or etherscan (getLogs HTTP API for all but the newest blocks)
+	LogBackend         string
+	EtherscanAPIURL    string // Etherscan-style API endpoint
+	EtherscanAPIKey    string // API key (optional on some explorers)
+	EtherscanChainID   uint64 // chainid parameter of the API (the RPC's chain if 0)
+	EtherscanLagBlocks uint64 // Newest blocks still queried over the RPC, which the explorer may not have indexed
+	EtherscanMaxRPS    int    // Max API requests per second (unlimited if 0)
+
+	// Log file output (stdout only if LogFile is empty)
+	LogFile     string // Write logs to this file, rotated by size
+	LogStdout   bool   // Also write logs to stdout when LogFile is set
+	LogMaxSize  int64  // Rotate the log file once it would exceed this many bytes
+	LogMaxFiles int    // Rotated log files to keep
+
+	// Nonce management
+	NonceFile        string // Persisted next nonce of the fulfiller account (disabled if empty)
+	NonceGapRecovery bool   // Fill dropped nonces after repeated transaction timeouts
+
+	NonceSyncInterval  time.Duration // How often to compare the tracked nonce with the network (disabled if 0)
+	NonceSyncThreshold uint64        // Nonce difference tolerated before resyncing
+
+	GasPriceCache time.Duration // Reuse a suggested gas price for this long when sending (disabled if 0)
+	MaxGasPrice   *big.Int      // Defer transactions while the suggested gas price is above this, in wei (disabled if nil)
+
+	// Stuck transaction replacement
+	MaxReplacements int           // Gas-bumped replacements per transaction (disabled if 0)
+	MinMempoolTime  time.Duration // Time a transaction must stay pending before it may be replaced
+
+	DeadLetterFile string // JSON file recording failed fulfillments for operators (disabled if empty)
+	LeaderLock     string // Lock file electing the active instance among several engines (disabled if empty)
+
+	VerifyConfigOnchain bool // Read configured vault deployment values at startup anyway and require a match
+
+	DryRun bool // Compute and log fulfillments without sending any transaction
+
+	// Per-vault circuit breaker (disabled if BreakerFailures is 0)
+	BreakerFailures int           // Consecutive failed fulfillments within BreakerWindow that pause the vault
+	BreakerWindow   time.Duration // Failures further apart than this do not add up
+	BreakerCooldown time.Duration // How long a vault stays paused before a probe request is let through
+
+	// Write-ahead log of fulfillment attempts (disabled if WALFile is empty)
+	WALFile     string // JSONL file recording every stage of each fulfillment attempt
+	WALMaxSize  int64  // Rotate the WAL once it would exceed this many bytes
+	WALMaxFiles int    // Rotated WAL files to keep; they are replayed at startup
+
+	WithdrawalToleranceBps int64  // Allowed overshoot of withdrawal value above the target, in bps
+	WithdrawalPayoutMode   string // How withdrawals are fulfilled: underlying (default) or quote
+	WithdrawalRounding     string // How withdrawal token amounts are rounded: floor-with-topup (default), ceil or nearest
+
+	DepositAllocationMode string // How deposits are split across tokens: target (default) or rebalancing
+
+	MaxDepositOvercommitBps int64 // Abort a deposit whose underlying value exceeds its quote value by more, in bps (0 = off)
+
+	// ERC20 approvals granted to the vault
+	ApprovalMode       string                  // infinite (default), exact or fixed
+	ApprovalCap        *big.Int                // Allowance approved in fixed mode (token base units)
+	ApproveResetTokens map[common.Address]bool // Tokens whose allowance must be reset to zero before approving
+	ApprovalNoWait     bool                    // Send the fulfillment without waiting for its approvals to be mined
+
+	// Auto-fulfillment limits; larger requests are held for manual approval (unlimited if nil)
+	MaxDepositValue     *big.Int // Max deposit quote amount (quote token base units)
+	MaxWithdrawalShares *big.Int // Max withdrawal shares (sector token base units)
+
+	Oracle      OracleVariant                     // How prices are read from the oracle
+	OracleFeeds map[common.Address]common.Address // Per-token price feeds for aggregator-style oracles
+
+	OracleDecimalsFallback uint8 // Oracle decimals used when its decimals call reverts (fail if 0)
+
+	// Staging only: fixed prices in oracle units, returned instead of the oracle's (PRICE_OVERRIDE_<TOKEN>)
+	PriceOverrides map[common.Address]*big.Int
+
+	// Off-chain price cross-check before fulfilling (disabled if PriceCheckURL is empty)
+	PriceCheckURL        string // Price API URL; {token} is replaced by the lowercase token address
+	PriceCheckField      string // Dot-separated path to the USD price in the JSON response
+	MaxPriceDeviationBps int64  // Abort a fulfillment if oracle and API prices differ by more, in bps
+
+	// Low-balance alerting
+	AlertWebhookURL             string                      // Slack-compatible webhook for balance alerts (disabled if empty)
+	BalanceCheckInterval        time.Duration               // How often to check fulfiller balances
+	BalanceAlertCooldown        time.Duration               // Minimum time between repeated alerts for the same token
+	QuoteBalanceThreshold       *big.Int                    // Alert when quote token balance drops below this (base units)
+	UnderlyingBalanceThreshold  *big.Int                    // Default threshold for underlying tokens (base units)
+	UnderlyingBalanceThresholds map[common.Address]*big.Int // Per-token threshold overrides (base units)
+
+	// Top-up hook, called when a balance drops below its alert threshold (disabled if both are empty)
+	TopUpWebhookURL string        // Receives a JSON POST with the token and shortfall
+	TopUpCommand    string        // Run with the token and shortfall as its last arguments
+	TopUpCooldown   time.Duration // Minimum time between top-up requests for the same token
+
+	// Fulfillment notifications
+	NotifyWebhookURL string // Generic (Slack/Discord-compatible) webhook for fulfillment events
+	TelegramBotToken string // Telegram bot API token
+	TelegramChatID   string // Telegram chat to post to
+	NotifyQueueSize  int    // Bounded queue size; events are dropped when full
+
+	APIPort       int    // HTTP API port (disabled if 0)
+	AdminAPIToken string // Bearer token for operator endpoints (disabled if empty)
+
+	GasReportInterval time.Duration // How often to log per-vault gas totals (disabled if 0)
+
+	WeightSyncInterval time.Duration // How often to re-read vault target weights (disabled if 0)
+
+	EventTopics map[string]common.Hash // Topic overrides by event name for vaults with different event signatures
+
+	// Requests from these users are never fulfilled; when the allowlist is set, only its users are
+	FulfillDenylist  map[common.Address]bool
+	FulfillAllowlist map[common.Address]bool
+
+	SettledReverts []revertMatcher // Fulfillment reverts meaning the request was already settled
+
+	// Signing key derived from a BIP-39 mnemonic instead of PrivateKey
+	Mnemonic       string
+	DerivationPath accounts.DerivationPath // HD path of the key within Mnemonic
+
+	VaultPauseCheck bool // Defer fulfillments while a vault's paused() returns true (for vaults that have it)
+}
+
+func LoadConfig() (*Config, error) {
+	// Load .env file
+	_ = godotenv.Load()
+
+	// The signing key is either a raw PRIVATE_KEY or derived from MNEMONIC at DERIVATION_PATH
+	privateKey := os.Getenv("PRIVATE_KEY")
+	mnemonic := strings.TrimSpace(os.Getenv("MNEMONIC"))
+	switch {
+	case privateKey != "" && mnemonic != "":
+		return nil, fmt.Errorf("both PRIVATE_KEY and MNEMONIC are set - configure only one signing key")
+	case privateKey == "" && mnemonic == "":
+		return nil, fmt.Errorf("neither PRIVATE_KEY nor MNEMONIC is set")
+	}
+	derivationPath := accounts.DefaultBaseDerivationPath
+	if val := strings.TrimSpace(os.Getenv("DERIVATION_PATH")); val != "" {
+		if mnemonic == "" {
+			return nil, fmt.Errorf("DERIVATION_PATH is set without MNEMONIC")
+		}
+		parsed, err := accounts.ParseDerivationPath(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DERIVATION_PATH %q: %v", val, err)
+		}
+		derivationPath = parsed
+	}
+
+	// RPC_URLS=primary,fallback1,... takes precedence over the single RPC_URL
+	var rpcURLs []string
+	for _, url := range strings.Split(configEnv("RPC_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			rpcURLs = append(rpcURLs, url)
+		}
+	}
+	if len(rpcURLs) == 0 {
+		rpcURL := os.Getenv("RPC_URL")
+		if rpcURL == "" {
+			rpcURL = "https://sepolia.base.org"
+		}
+		rpcURLs = []string{rpcURL}
+	}
+
+	// WS_URL=wss://... serves LISTENER_MODE=subscribe while RPC_URL(S) handle calls and sends
+	wsURL := strings.TrimSpace(os.Getenv("WS_URL"))
+
+	// Support both legacy SECTOR_VAULT (single) and new SECTOR_VAULTS (multiple)
+	var vaults []VaultConfig
+
+	// Check for new multi-vault format first: SECTOR_VAULTS=addr1,addr2,addr3
+	sectorVaultsStr := configEnv("SECTOR_VAULTS")
+	if sectorVaultsStr != "" {
+		addresses := strings.Split(sectorVaultsStr, ",")
+		for i, addr := range addresses {
+			addr = strings.TrimSpace(addr)
+			if addr != "" {
+				vaults = append(vaults, VaultConfig{
+					Address: common.HexToAddress(addr),
+					Name:    fmt.Sprintf("Vault-%d", i+1),
+				})
+			}
+		}
+	}
+
+	// Check for named vaults: SECTOR_VAULT_AI=0x..., SECTOR_VAULT_MIA=0x...
+	// A -vaults flag replaces them along with SECTOR_VAULTS.
+	vaultNames := []string{"AI", "MIA", "DEFI", "GAMING", "MEME"} // Common sector names
+	if _, ok := flagOverrides["SECTOR_VAULTS"]; ok {
+		vaultNames = nil
+	}
+	for _, name := range vaultNames {
+		envKey := fmt.Sprintf("SECTOR_VAULT_%s", name)
+		if addr := os.Getenv(envKey); addr != "" {
+			vaults = append(vaults, VaultConfig{
+				Address: common.HexToAddress(addr),
+				Name:    name,
+			})
+		}
+	}
+
+	// Fallback to legacy single vault format
+	if len(vaults) == 0 {
+		sectorVault := os.Getenv("SECTOR_VAULT")
+		if sectorVault == "" {
+			return nil, fmt.Errorf("no sector vaults configured - set SECTOR_VAULTS, SECTOR_VAULT_<NAME>, or SECTOR_VAULT")
+		}
+		vaults = append(vaults, VaultConfig{
+			Address: common.HexToAddress(sectorVault),
+			Name:    "Default",
+		})
+	}
+
+	// ORACLE_DECIMALS applies to every vault without its own SECTOR_VAULT_<NAME>_ORACLE_DECIMALS
+	// (FULFILLMENT_WEBHOOK_URL likewise, below)
+	var oracleDecimals *uint8
+	if str := os.Getenv("ORACLE_DECIMALS"); str != "" {
+		val, err := strconv.ParseUint(str, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ORACLE_DECIMALS: %q", str)
+		}
+		decimals := uint8(val)
+		if err := validateDecimals(decimals); err != nil {
+			return nil, fmt.Errorf("invalid ORACLE_DECIMALS: %v", err)
+		}
+		oracleDecimals = &decimals
+	}
+
+	// Per-vault backfill bounds: SECTOR_VAULT_<NAME>_START_BLOCK, _START_DEPOSIT_ID, _START_WITHDRAWAL_ID
+	for i := range vaults {
+		prefix := "SECTOR_VAULT_" + vaultEnvName(vaults[i].Name) + "_"
+		for suffix, target := range map[string]*uint64{
+			"START_BLOCK":         &vaults[i].StartBlock,
+			"START_DEPOSIT_ID":    &vaults[i].StartDepositID,
+			"START_WITHDRAWAL_ID": &vaults[i].StartWithdrawalID,
+		} {
+			str := os.Getenv(prefix + suffix)
+			if str == "" {
+				continue
+			}
+			val, err := strconv.ParseUint(str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s%s: %q", prefix, suffix, str)
+			}
+			*target = val
+		}
+
+		// SECTOR_VAULT_<NAME>_ORACLE, _ORACLE_DECIMALS, _QUOTE_TOKEN, _QUOTE_DECIMALS
+		for suffix, target := range map[string]**common.Address{
+			"ORACLE":      &vaults[i].OracleAddress,
+			"QUOTE_TOKEN": &vaults[i].QuoteToken,
+		} {
+			str := strings.TrimSpace(os.Getenv(prefix + suffix))
+			if str == "" {
+				continue
+			}
+			if !common.IsHexAddress(str) {
+				return nil, fmt.Errorf("invalid %s%s: %q is not an address", prefix, suffix, str)
+			}
+			address := common.HexToAddress(str)
+			*target = &address
+		}
+		for suffix, target := range map[string]**uint8{
+			"ORACLE_DECIMALS": &vaults[i].OracleDecimals,
+			"QUOTE_DECIMALS":  &vaults[i].QuoteDecimals,
+		} {
+			str := os.Getenv(prefix + suffix)
+			if str == "" {
+				continue
+			}
+			val, err := strconv.ParseUint(str, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s%s: %q", prefix, suffix, str)
+			}
+			decimals := uint8(val)
+			if err := validateDecimals(decimals); err != nil {
+				return nil, fmt.Errorf("invalid %s%s: %v", prefix, suffix, err)
+			}
+			*target = &decimals
+		}
+		if vaults[i].OracleDecimals == nil {
+			vaults[i].OracleDecimals = oracleDecimals
+		}
+		vaults[i].FulfillmentWebhookURL = os.Getenv(prefix + "FULFILLMENT_WEBHOOK_URL")
+		if vaults[i].FulfillmentWebhookURL == "" {
+			vaults[i].FulfillmentWebhookURL = os.Getenv("FULFILLMENT_WEBHOOK_URL")
+		}
+		if str := os.Getenv(prefix + "POLL_INTERVAL"); str != "" {
+			val, err := strconv.Atoi(str)
+			if err != nil || val <= 0 {
+				return nil, fmt.Errorf("invalid %sPOLL_INTERVAL: %q must be a positive number of seconds", prefix, str)
+			}
+			vaults[i].PollInterval = val
+		}
+	}
+
+	// Used when an oracle has no working decimals function and no decimals are configured (0 = fail)
+	oracleDecimalsFallback := uint8(8)
+	if str := os.Getenv("ORACLE_DECIMALS_FALLBACK"); str != "" {
+		val, err := strconv.ParseUint(str, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ORACLE_DECIMALS_FALLBACK: %q", str)
+		}
+		if val != 0 {
+			if err := validateDecimals(uint8(val)); err != nil {
+				return nil, fmt.Errorf("invalid ORACLE_DECIMALS_FALLBACK: %v", err)
+			}
+		}
+		oracleDecimalsFallback = uint8(val)
+	}
+
+	pollIntervalStr := configEnv("POLL_INTERVAL")
+	pollInterval := 12 // default
+	if pollIntervalStr != "" {
+		if val, err := strconv.Atoi(pollIntervalStr); err == nil {
+			pollInterval = val
+		}
+	}
+
+	// Logging configuration
+	logLevel := configEnv("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "INFO"
+	}
+
+	logFormat := os.Getenv("LOG_FORMAT")
+	if logFormat == "" {
+		logFormat = "TEXT"
+	}
+
+	shutdownTimeoutStr := os.Getenv("SHUTDOWN_TIMEOUT")
+	shutdownTimeout := 30 * time.Second // default 30 seconds
+	if shutdownTimeoutStr != "" {
+		if val, err := strconv.Atoi(shutdownTimeoutStr); err == nil && val > 0 {
+			shutdownTimeout = time.Duration(val) * time.Second
+		}
+	}
+
+	rpcCallTimeout := 15 * time.Second // default 15 seconds
+	if val, err := strconv.Atoi(os.Getenv("RPC_CALL_TIMEOUT_SECONDS")); err == nil && val > 0 {
+		rpcCallTimeout = time.Duration(val) * time.Second
+	}
+
+	var rpcMaxRPS int
+	if val := os.Getenv("RPC_MAX_RPS"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid RPC_MAX_RPS %q - expected a non-negative number of calls per second", val)
+		}
+		rpcMaxRPS = parsed
+	}
+
+	var expectedChainID uint64
+	if val := os.Getenv("EXPECTED_CHAIN_ID"); val != "" {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("invalid EXPECTED_CHAIN_ID %q", val)
+		}
+		expectedChainID = parsed
+	}
+
+	logMaxSize := int64(100) << 20 // default 100 MB
+	if val, err := strconv.ParseInt(os.Getenv("LOG_MAX_SIZE_MB"), 10, 64); err == nil && val > 0 {
+		logMaxSize = val << 20
+	}
+
+	logMaxFiles := 5 // default
+	if val, err := strconv.Atoi(os.Getenv("LOG_MAX_FILES")); err == nil && val >= 0 {
+		logMaxFiles = val
+	}
+
+	walMaxSize := int64(100) << 20 // default 100 MB
+	if val, err := strconv.ParseInt(os.Getenv("WAL_MAX_SIZE_MB"), 10, 64); err == nil && val > 0 {
+		walMaxSize = val << 20
+	}
+
+	walMaxFiles := 5 // default
+	if val, err := strconv.Atoi(os.Getenv("WAL_MAX_FILES")); err == nil && val >= 0 {
+		walMaxFiles = val
+	}
+
+	var breakerFailures int
+	if val := os.Getenv("CIRCUIT_BREAKER_FAILURES"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid CIRCUIT_BREAKER_FAILURES %q - expected a non-negative number", val)
+		}
+		breakerFailures = parsed
+	}
+
+	breakerWindow := 10 * time.Minute // default 10 minutes
+	if val, err := strconv.Atoi(os.Getenv("CIRCUIT_BREAKER_WINDOW_SECONDS")); err == nil && val > 0 {
+		breakerWindow = time.Duration(val) * time.Second
+	}
+
+	breakerCooldown := 5 * time.Minute // default 5 minutes
+	if val, err := strconv.Atoi(os.Getenv("CIRCUIT_BREAKER_COOLDOWN_SECONDS")); err == nil && val > 0 {
+		breakerCooldown = time.Duration(val) * time.Second
+	}
+
+	scanFromBlock := uint64(0)
+	if str := os.Getenv("SCAN_FROM_BLOCK"); str != "" {
+		val, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCAN_FROM_BLOCK: %q must be a block number", str)
+		}
+		scanFromBlock = val
+	}
+
+	maxHistoricalScan := uint64(0) // default: unlimited
+	if str := os.Getenv("MAX_HISTORICAL_SCAN"); str != "" {
+		val, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_HISTORICAL_SCAN: %q must be a number of blocks", str)
+		}
+		maxHistoricalScan = val
+	}
+
+	// The startup scan never defaults to genesis: every vault needs a first block or a scan limit
+	if os.Getenv("SCAN_FROM_BLOCK") == "" && maxHistoricalScan == 0 {
+		for _, vault := range vaults {
+			if os.Getenv("SECTOR_VAULT_"+vaultEnvName(vault.Name)+"_START_BLOCK") == "" {
+				return nil, fmt.Errorf("no startup scan range for vault %s - set SCAN_FROM_BLOCK or SECTOR_VAULT_%s_START_BLOCK to the vault's deployment block, or MAX_HISTORICAL_SCAN",
+					vault.Name, vaultEnvName(vault.Name))
+			}
+		}
+	}
+
+	logChunkSize := uint64(10000) // default, within common provider limits
+	if val, err := strconv.ParseUint(os.Getenv("LOG_CHUNK_SIZE"), 10, 64); err == nil && val > 0 {
+		logChunkSize = val
+	}
+
+	pollChunkSize := uint64(2000) // default 2000 blocks per poll query
+	if val, err := strconv.ParseUint(os.Getenv("LOG_QUERY_CHUNK_SIZE"), 10, 64); err == nil && val > 0 {
+		pollChunkSize = val
+	}
+
+	// Log backend: LOG_BACKEND=rpc (default) or etherscan, for providers restricting eth_getLogs
+	logBackend := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_BACKEND")))
+	switch logBackend {
+	case "":
+		logBackend = logBackendRPC
+	case logBackendRPC, logBackendEtherscan:
+	default:
+		return nil, fmt.Errorf("invalid LOG_BACKEND %q - expected rpc or etherscan", logBackend)
+	}
+
+	etherscanAPIURL := os.Getenv("ETHERSCAN_API_URL")
+	if etherscanAPIURL == "" {
+		etherscanAPIURL = "https://api.etherscan.io/v2/api" // unified v2 endpoint, chain selected by chainid
+	}
+
+	var etherscanChainID uint64
+	if str := os.Getenv("ETHERSCAN_CHAIN_ID"); str != "" {
+		val, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ETHERSCAN_CHAIN_ID %q", str)
+		}
+		etherscanChainID = val
+	}
+
+	etherscanLagBlocks := uint64(20) // default, comfortably behind explorer indexing
+	if val, err := strconv.ParseUint(os.Getenv("ETHERSCAN_LAG_BLOCKS"), 10, 64); err == nil {
+		etherscanLagBlocks = val
+	}
+
+	etherscanMaxRPS := 4 // default, under the free tier's 5 requests per second
+	if val, err := strconv.Atoi(os.Getenv("ETHERSCAN_MAX_RPS")); err == nil && val >= 0 {
+		etherscanMaxRPS = val
+	}
+
+	pollJitter := time.Duration(0) // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("POLL_JITTER_MS")); err == nil && val > 0 {
+		pollJitter = time.Duration(val) * time.Millisecond
+	}
+
+	// Listener mode: LISTENER_MODE=poll (default) or subscribe (requires a ws:// RPC URL)
+	sharedListener := os.Getenv("SHARED_LISTENER") == "true"
+	subscribeLogs := false
+	switch listenerMode := os.Getenv("LISTENER_MODE"); listenerMode {
+	case "", "poll":
+	case "subscribe":
+		if sharedListener {
+			return nil, fmt.Errorf("SHARED_LISTENER is not supported with LISTENER_MODE=subscribe")
+		}
+		subscribeLogs = true
+	default:
+		return nil, fmt.Errorf("invalid LISTENER_MODE %q - expected poll or subscribe", listenerMode)
+	}
+	if sharedListener {
+		for _, vault := range vaults {
+			if vault.PollInterval > 0 {
+				return nil, fmt.Errorf("SECTOR_VAULT_%s_POLL_INTERVAL is not supported with SHARED_LISTENER", vaultEnvName(vault.Name))
+			}
+		}
+	}
+
+	// Fulfill mode: FULFILL_MODE=both (default), deposits or withdrawals
+	fulfillMode := os.Getenv("FULFILL_MODE")
+	switch fulfillMode {
+	case "":
+		fulfillMode = fulfillModeBoth
+	case fulfillModeBoth, fulfillModeDeposits, fulfillModeWithdrawals:
+	default:
+		return nil, fmt.Errorf("invalid FULFILL_MODE %q - expected both, deposits or withdrawals", fulfillMode)
+	}
+
+	// Periodic nonce reconciliation: every NONCE_SYNC_INTERVAL seconds, tolerating NONCE_SYNC_THRESHOLD
+	nonceSyncInterval := time.Duration(0) // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("NONCE_SYNC_INTERVAL")); err == nil && val > 0 {
+		nonceSyncInterval = time.Duration(val) * time.Second
+	}
+	var nonceSyncThreshold uint64
+	if val := os.Getenv("NONCE_SYNC_THRESHOLD"); val != "" {
+		parsed, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NONCE_SYNC_THRESHOLD %q - expected a non-negative integer", val)
+		}
+		nonceSyncThreshold = parsed
+	}
+
+	gasPriceCache := time.Duration(0) // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("GAS_PRICE_CACHE_MS")); err == nil && val > 0 {
+		gasPriceCache = time.Duration(val) * time.Millisecond
+	}
+
+	var maxGasPrice *big.Int
+	if val := strings.TrimSpace(os.Getenv("MAX_GAS_PRICE_GWEI")); val != "" {
+		parsed, err := parseGwei(val)
+		if err != nil || parsed.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid MAX_GAS_PRICE_GWEI %q - expected a positive gas price in gwei", val)
+		}
+		maxGasPrice = parsed
+	}
+
+	// Stuck transaction replacement: MAX_REPLACEMENTS gas bumps, each after MIN_MEMPOOL_SECONDS pending
+	maxReplacements := 0 // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("MAX_REPLACEMENTS")); err == nil && val > 0 {
+		maxReplacements = val
+	}
+	minMempoolTime := 30 * time.Second
+	if val, err := strconv.Atoi(os.Getenv("MIN_MEMPOOL_SECONDS")); err == nil && val > 0 {
+		minMempoolTime = time.Duration(val) * time.Second
+	}
+	if maxReplacements > 0 && minMempoolTime >= txWaitTimeout*time.Second {
+		return nil, fmt.Errorf("MIN_MEMPOOL_SECONDS must be below the %ds transaction wait timeout", txWaitTimeout)
+	}
+
+	// Request order: REQUEST_ORDER=fifo (default), largest or smallest
+	requestOrder := os.Getenv("REQUEST_ORDER")
+	switch requestOrder {
+	case "":
+		requestOrder = requestOrderFIFO
+	case requestOrderFIFO, requestOrderLargest, requestOrderSmallest:
+	default:
+		return nil, fmt.Errorf("invalid REQUEST_ORDER %q - expected fifo, largest or smallest", requestOrder)
+	}
+
+	// Approval mode: APPROVAL_MODE=infinite (default), exact, or fixed with APPROVAL_CAP
+	approvalMode := os.Getenv("APPROVAL_MODE")
+	if approvalMode == "" {
+		approvalMode = approvalModeInfinite
+	}
+	approvalCap, err := parseBigIntEnv("APPROVAL_CAP")
+	if err != nil {
+		return nil, err
+	}
+	switch approvalMode {
+	case approvalModeInfinite, approvalModeExact:
+	case approvalModeFixed:
+		if approvalCap == nil || approvalCap.Sign() <= 0 {
+			return nil, fmt.Errorf("APPROVAL_MODE=fixed requires a positive APPROVAL_CAP")
+		}
+	default:
+		return nil, fmt.Errorf("invalid APPROVAL_MODE %q - expected infinite, exact or fixed", approvalMode)
+	}
+
+	// Approval confirmations: APPROVAL_CONFIRMATIONS=1 (default) waits for each approval to be
+	// mined before fulfilling, 0 sends the fulfillment right behind it (nonce order keeps them in sequence)
+	approvalNoWait := false
+	switch val := os.Getenv("APPROVAL_CONFIRMATIONS"); val {
+	case "", "1":
+	case "0":
+		approvalNoWait = true
+	default:
+		return nil, fmt.Errorf("invalid APPROVAL_CONFIRMATIONS %q - expected 0 or 1", val)
+	}
+
+	// USDT-style tokens: APPROVE_RESET_TOKENS=0xToken1,0xToken2
+	approveResetTokens := make(map[common.Address]bool)
+	for _, entry := range strings.Split(os.Getenv("APPROVE_RESET_TOKENS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !common.IsHexAddress(entry) {
+			return nil, fmt.Errorf("invalid APPROVE_RESET_TOKENS entry %q - expected a token address", entry)
+		}
+		approveResetTokens[common.HexToAddress(entry)] = true
+	}
+
+	// Depositor filters: FULFILL_DENYLIST=0xUser1,0xUser2 and FULFILL_ALLOWLIST=0xUser3
+	fulfillDenylist, err := parseAddressSet("FULFILL_DENYLIST")
+	if err != nil {
+		return nil, err
+	}
+	fulfillAllowlist, err := parseAddressSet("FULFILL_ALLOWLIST")
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverts treated as "already settled": SETTLED_REVERTS=DepositNotFound(),0x12345678,already fulfilled
+	settledRevertEntries := defaultSettledReverts
+	if val := os.Getenv("SETTLED_REVERTS"); val != "" {
+		settledRevertEntries = strings.Split(val, ",")
+	}
+	var settledReverts []revertMatcher
+	for _, entry := range settledRevertEntries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		matcher, err := parseRevertMatcher(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SETTLED_REVERTS: %v", err)
+		}
+		settledReverts = append(settledReverts, matcher)
+	}
+
+	maxRequestAge := time.Duration(0) // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("MAX_REQUEST_AGE_SECONDS")); err == nil && val > 0 {
+		maxRequestAge = time.Duration(val) * time.Second
+	}
+
+	withdrawalToleranceBps := int64(contractToleranceBps) // default matches the vault's 0.1%
+	if val, err := strconv.ParseInt(os.Getenv("WITHDRAWAL_TOLERANCE_BPS"), 10, 64); err == nil && val >= 0 {
+		withdrawalToleranceBps = val
+	}
+
+	// Withdrawal payout: underlying (vault sends underlying tokens for the quote payout) or quote
+	withdrawalPayoutMode := strings.ToLower(os.Getenv("WITHDRAWAL_PAYOUT_MODE"))
+	switch withdrawalPayoutMode {
+	case "":
+		withdrawalPayoutMode = withdrawalPayoutUnderlying
+	case withdrawalPayoutUnderlying, withdrawalPayoutQuote:
+	default:
+		return nil, fmt.Errorf("invalid WITHDRAWAL_PAYOUT_MODE %q - expected underlying or quote", withdrawalPayoutMode)
+	}
+
+	// Withdrawal rounding: floor-with-topup (floor, then top up one token), ceil or nearest
+	withdrawalRounding := strings.ToLower(strings.TrimSpace(os.Getenv("WITHDRAWAL_ROUNDING")))
+	switch withdrawalRounding {
+	case "":
+		withdrawalRounding = withdrawalRoundingFloorTopUp
+	case withdrawalRoundingFloorTopUp, withdrawalRoundingCeil, withdrawalRoundingNearest:
+	default:
+		return nil, fmt.Errorf("invalid WITHDRAWAL_ROUNDING %q - expected floor-with-topup, ceil or nearest", withdrawalRounding)
+	}
+
+	// Deposit allocation: target (by target weight) or rebalancing (toward target weights)
+	depositAllocationMode := strings.ToLower(strings.TrimSpace(os.Getenv("DEPOSIT_ALLOCATION_MODE")))
+	switch depositAllocationMode {
+	case "":
+		depositAllocationMode = depositAllocationTarget
+	case depositAllocationTarget, depositAllocationRebalancing:
+	default:
+		return nil, fmt.Errorf("invalid DEPOSIT_ALLOCATION_MODE %q - expected target or rebalancing", depositAllocationMode)
+	}
+
+	maxDepositValue, err := parseBigIntEnv("MAX_DEPOSIT_VALUE")
+	if err != nil {
+		return nil, err
+	}
+
+	maxWithdrawalShares, err := parseBigIntEnv("MAX_WITHDRAWAL_SHARES")
+	if err != nil {
+		return nil, err
+	}
+
+	gasReportInterval := time.Hour // default 1 hour
+	if val, err := strconv.Atoi(os.Getenv("GAS_REPORT_INTERVAL")); err == nil && val >= 0 {
+		gasReportInterval = time.Duration(val) * time.Second
+	}
+
+	weightSyncInterval := time.Duration(0) // disabled by default
+	if val, err := strconv.Atoi(os.Getenv("WEIGHT_SYNC_INTERVAL")); err == nil && val > 0 {
+		weightSyncInterval = time.Duration(val) * time.Second
+	}
+
+	// Low-balance alerting configuration
+	alertWebhookURL := os.Getenv("ALERT_WEBHOOK_URL")
+
+	balanceCheckInterval := 60 * time.Second // default 60 seconds
+	if val, err := strconv.Atoi(os.Getenv("BALANCE_CHECK_INTERVAL")); err == nil && val > 0 {
+		balanceCheckInterval = time.Duration(val) * time.Second
+	}
+
+	balanceAlertCooldown := time.Hour // default 1 hour
+	if val, err := strconv.Atoi(os.Getenv("BALANCE_ALERT_COOLDOWN")); err == nil && val > 0 {
+		balanceAlertCooldown = time.Duration(val) * time.Second
+	}
+
+	topUpCooldown := time.Hour // default 1 hour
+	if val, err := strconv.Atoi(os.Getenv("TOPUP_COOLDOWN")); err == nil && val > 0 {
+		topUpCooldown = time.Duration(val) * time.Second
+	}
+
+	quoteBalanceThreshold, err := parseBigIntEnv("QUOTE_BALANCE_THRESHOLD")
+	if err != nil {
+		return nil, err
+	}
+
+	underlyingBalanceThreshold, err := parseBigIntEnv("UNDERLYING_BALANCE_THRESHOLD")
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-token thresholds: UNDERLYING_BALANCE_THRESHOLDS=0xToken1:1000,0xToken2:5000
+	underlyingBalanceThresholds := make(map[common.Address]*big.Int)
+	if thresholdsStr := os.Getenv("UNDERLYING_BALANCE_THRESHOLDS"); thresholdsStr != "" {
+		for _, entry := range strings.Split(thresholdsStr, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 || !common.IsHexAddress(strings.TrimSpace(parts[0])) {
+				return nil, fmt.Errorf("invalid UNDERLYING_BALANCE_THRESHOLDS entry %q - expected 0xToken:amount", entry)
+			}
+			amount, ok := new(big.Int).SetString(strings.TrimSpace(parts[1]), 10)
+			if !ok || amount.Sign() < 0 {
+				return nil, fmt.Errorf("invalid UNDERLYING_BALANCE_THRESHOLDS amount %q", parts[1])
+			}
+			underlyingBalanceThresholds[common.HexToAddress(strings.TrimSpace(parts[0]))] = amount
+		}
+	}
+
+	// Event topic overrides: EVENT_TOPICS=DepositRequested:0xTopic,WithdrawalRequested:0xTopic
+	eventTopics := make(map[string]common.Hash)
+	for _, entry := range strings.Split(os.Getenv("EVENT_TOPICS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		topic := ""
+		if len(parts) == 2 {
+			topic = strings.TrimSpace(parts[1])
+		}
+		if len(parts) != 2 || len(topic) != 66 || !strings.HasPrefix(topic, "0x") {
+			return nil, fmt.Errorf("invalid EVENT_TOPICS entry %q - expected EventName:0xTopic", entry)
+		}
+		name := strings.TrimSpace(parts[0])
+		switch name {
+		case "DepositRequested", "WithdrawalRequested", "DepositFulfilled", "DepositCancelled", "WithdrawalFulfilled", "WithdrawalCancelled":
+		default:
+			return nil, fmt.Errorf("invalid EVENT_TOPICS event %q", name)
+		}
+		eventTopics[name] = common.HexToHash(topic)
+	}
+
+	// Oracle backend: ORACLE_VARIANT selects a built-in interface, ORACLE_PRICE_FN/ORACLE_DECIMALS_FN override its function names
+	oracle := defaultOracleVariant
+	if variantName := os.Getenv("ORACLE_VARIANT"); variantName != "" {
+		variant, ok := oracleVariants[strings.ToLower(variantName)]
+		if !ok {
+			return nil, fmt.Errorf("invalid ORACLE_VARIANT %q - expected custom or chainlink", variantName)
+		}
+		oracle = variant
+	}
+	if priceFn := os.Getenv("ORACLE_PRICE_FN"); priceFn != "" {
+		oracle.PriceFn = priceFn
+	}
+	if decimalsFn := os.Getenv("ORACLE_DECIMALS_FN"); decimalsFn != "" {
+		oracle.DecimalsFn = decimalsFn
+	}
+	if _, err := oracle.ParseABI(); err != nil {
+		return nil, fmt.Errorf("invalid oracle function names: %v", err)
+	}
+
+	// Per-token feeds: ORACLE_FEEDS=0xToken1:0xFeed1,0xToken2:0xFeed2
+	oracleFeeds := make(map[common.Address]common.Address)
+	if feedsStr := os.Getenv("ORACLE_FEEDS"); feedsStr != "" {
+		for _, entry := range strings.Split(feedsStr, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) != 2 || !common.IsHexAddress(strings.TrimSpace(parts[0])) || !common.IsHexAddress(strings.TrimSpace(parts[1])) {
+				return nil, fmt.Errorf("invalid ORACLE_FEEDS entry %q - expected 0xToken:0xFeed", entry)
+			}
+			oracleFeeds[common.HexToAddress(strings.TrimSpace(parts[0]))] = common.HexToAddress(strings.TrimSpace(parts[1]))
+		}
+	}
+	if oracle.PerTokenFeed && len(oracleFeeds) == 0 {
+		return nil, fmt.Errorf("ORACLE_FEEDS is required for ORACLE_VARIANT=%s", oracle.Name)
+	}
+
+	// Price overrides for staging: PRICE_OVERRIDE_0xToken=price (oracle units)
+	priceOverrides := make(map[common.Address]*big.Int)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, "PRICE_OVERRIDE_") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(kv, "PRICE_OVERRIDE_"), "=", 2)
+		if len(parts) != 2 || !common.IsHexAddress(parts[0]) {
+			return nil, fmt.Errorf("invalid price override %q - expected PRICE_OVERRIDE_0xToken=price", kv)
+		}
+		price, ok := new(big.Int).SetString(strings.TrimSpace(parts[1]), 10)
+		if !ok || price.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid PRICE_OVERRIDE_%s %q - expected a positive integer price in oracle units", parts[0], parts[1])
+		}
+		priceOverrides[common.HexToAddress(parts[0])] = price
+	}
+
+	// Off-chain price cross-check: PRICE_CHECK_URL=https://...{token}..., defaults suit CoinGecko
+	priceCheckURL := strings.TrimSpace(os.Getenv("PRICE_CHECK_URL"))
+	if priceCheckURL != "" && !strings.Contains(priceCheckURL, "{token}") {
+		return nil, fmt.Errorf("invalid PRICE_CHECK_URL: must contain {token}")
+	}
+	priceCheckField := strings.TrimSpace(os.Getenv("PRICE_CHECK_FIELD"))
+	if priceCheckField == "" {
+		priceCheckField = "{token}.usd"
+	}
+	// Hard cap on the value a deposit commits, far above the tolerance rounding stays within
+	maxDepositOvercommitBps := int64(200) // default 2%
+	if str := os.Getenv("MAX_DEPOSIT_OVERCOMMIT_BPS"); str != "" {
+		val, err := strconv.ParseInt(str, 10, 64)
+		if err != nil || val < 0 {
+			return nil, fmt.Errorf("invalid MAX_DEPOSIT_OVERCOMMIT_BPS: %q", str)
+		}
+		maxDepositOvercommitBps = val
+	}
+
+	maxPriceDeviationBps := int64(200) // default 2%
+	if str := os.Getenv("MAX_PRICE_DEVIATION_BPS"); str != "" {
+		val, err := strconv.ParseInt(str, 10, 64)
+		if err != nil || val <= 0 {
+			return nil, fmt.Errorf("invalid MAX_PRICE_DEVIATION_BPS: %q", str)
+		}
+		maxPriceDeviationBps = val
+	}
+
+	// Fulfillment notification configuration
+	notifyQueueSize := 100 // default
+	if val, err := strconv.Atoi(os.Getenv("NOTIFY_QUEUE_SIZE")); err == nil && val > 0 {
+		notifyQueueSize = val
+	}
+
+	apiPort := 0 // disabled by default
+	if apiPortStr := os.Getenv("API_PORT"); apiPortStr != "" {
+		val, err := strconv.Atoi(apiPortStr)
+		if err != nil || val <= 0 || val > 65535 {
+			return nil, fmt.Errorf("invalid API_PORT: %q", apiPortStr)
+		}
+		apiPort = val
+	}
+
+	return &Config{
+		PrivateKey:      privateKey,
+		RPCURLs:         rpcURLs,
+		WSURL:           wsURL,
+		SectorVaults:    vaults,
+		PollInterval:    pollInterval,
+		SharedListener:  sharedListener,
+		SubscribeLogs:   subscribeLogs,
+		LogLevel:        logLevel,
+		LogFormat:       logFormat,
+		ShutdownTimeout: shutdownTimeout,
+		RPCCallTimeout:  rpcCallTimeout,
+		RPCMaxRPS:       rpcMaxRPS,
+		ExpectedChainID: expectedChainID,
+		ScanFromBlock:   scanFromBlock,
+		LogChunkSize:    logChunkSize,
+		PollChunkSize:   pollChunkSize,
+		PollJitter:      pollJitter,
+		MaxRequestAge:   maxRequestAge,
+		FulfillMode:     fulfillMode,
+		RequestOrder:    requestOrder,
+
+		MaxHistoricalScan: maxHistoricalScan,
+
+		LogBackend:         logBackend,
+		EtherscanAPIURL:    etherscanAPIURL,
+		EtherscanAPIKey:    os.Getenv("ETHERSCAN_API_KEY"),
+		EtherscanChainID:   etherscanChainID,
+		EtherscanLagBlocks: etherscanLagBlocks,
+		EtherscanMaxRPS:    etherscanMaxRPS,
+
+		LogFile:     os.Getenv("LOG_FILE"),
+		LogStdout:   os.Getenv("LOG_STDOUT") == "true",
+		LogMaxSize:  logMaxSize,
+		LogMaxFiles: logMaxFiles,
+
+		NonceFile:        os.Getenv("NONCE_FILE"),
+		NonceGapRecovery: os.Getenv("NONCE_GAP_RECOVERY") == "true",
+
+		NonceSyncInterval:  nonceSyncInterval,
+		NonceSyncThreshold: nonceSyncThreshold,
+
+		GasPriceCache: gasPriceCache,
+		MaxGasPrice:   maxGasPrice,
+
+		MaxReplacements: maxReplacements,
+		MinMempoolTime:  minMempoolTime,
+
+		DeadLetterFile: os.Getenv("DEAD_LETTER_FILE"),
+		LeaderLock:     os.Getenv("LEADER_LOCK"),
+
+		VerifyConfigOnchain: os.Getenv("VERIFY_CONFIG_ONCHAIN") == "true",
+
+		DryRun: configEnv("DRY_RUN") == "true",
+
+		BreakerFailures: breakerFailures,
+		BreakerWindow:   breakerWindow,
+		BreakerCooldown: breakerCooldown,
+
+		WALFile:     os.Getenv("WAL_FILE"),
+		WALMaxSize:  walMaxSize,
+		WALMaxFiles: walMaxFiles,
+
+		WithdrawalToleranceBps: withdrawalToleranceBps,
+		WithdrawalPayoutMode:   withdrawalPayoutMode,
+		WithdrawalRounding:     withdrawalRounding,
+		MaxDepositValue:        maxDepositValue,
+		MaxWithdrawalShares:    maxWithdrawalShares,
+
+		DepositAllocationMode: depositAllocationMode,
+
+		MaxDepositOvercommitBps: maxDepositOvercommitBps,
+
+		ApprovalMode:       approvalMode,
+		ApprovalCap:        approvalCap,
+		ApproveResetTokens: approveResetTokens,
+		ApprovalNoWait:     approvalNoWait,
+
+		Oracle:      oracle,
+		OracleFeeds: oracleFeeds,
+
+		OracleDecimalsFallback: oracleDecimalsFallback,
+
+		PriceOverrides: priceOverrides,
+
+		PriceCheckURL:        priceCheckURL,
+		PriceCheckField:      priceCheckField,
+		MaxPriceDeviationBps: maxPriceDeviationBps,
+
+		AlertWebhookURL:             alertWebhookURL,
+		BalanceCheckInterval:        balanceCheckInterval,
+		BalanceAlertCooldown:        balanceAlertCooldown,
+		QuoteBalanceThreshold:       quoteBalanceThreshold,
+		UnderlyingBalanceThreshold:  underlyingBalanceThreshold,
+		UnderlyingBalanceThresholds: underlyingBalanceThresholds,
+
+		TopUpWebhookURL: os.Getenv("TOPUP_WEBHOOK_URL"),
+		TopUpCommand:    os.Getenv("TOPUP_COMMAND"),
+		TopUpCooldown:   topUpCooldown,
+
+		NotifyWebhookURL: os.Getenv("NOTIFY_WEBHOOK_URL"),
+		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramChatID:   os.Getenv("TELEGRAM_CHAT_ID"),
+		NotifyQueueSize:  notifyQueueSize,
+
+		APIPort:       apiPort,
+		AdminAPIToken: os.Getenv("ADMIN_API_TOKEN"),
+
+		GasReportInterval: gasReportInterval,
+
+		WeightSyncInterval: weightSyncInterval,
+
+		EventTopics: eventTopics,
+
+		FulfillDenylist:  fulfillDenylist,
+		FulfillAllowlist: fulfillAllowlist,
+
+		SettledReverts: settledReverts,
+
+		Mnemonic:       mnemonic,
+		DerivationPath: derivationPath,
+
+		VaultPauseCheck: os.Getenv("VAULT_PAUSE_CHECK") == "true",
+	}, nil
+}
+
+// parseAddressSet parses a comma-separated list of addresses from the named env var
+func parseAddressSet(name string) (map[common.Address]bool, error) {
+	set := make(map[common.Address]bool)
+	for _, entry := range strings.Split(os.Getenv(name), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !common.IsHexAddress(entry) {
+			return nil, fmt.Errorf("invalid %s entry %q - expected an address", name, entry)
+		}
+		set[common.HexToAddress(entry)] = true
+	}
+	return set, nil
+}
+
+// vaultEnvName maps a vault name to its env var form, e.g. "Vault-1" -> "VAULT_1"
+func vaultEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
+
+// parseBigIntEnv parses an optional base-10 integer env var, returning nil if unset
+func parseBigIntEnv(key string) (*big.Int, error) {
+	str := strings.TrimSpace(os.Getenv(key))
+	if str == "" {
+		return nil, nil
+	}
+	val, ok := new(big.Int).SetString(str, 10)
+	if !ok || val.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s: %q is not a non-negative integer", key, str)
+	}
+	return val, nil
+}
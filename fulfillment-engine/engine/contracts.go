@@ -1,4 +1,4 @@
-package main
+package engine
 
 import (
 	"math/big"
@@ -77,6 +77,48 @@ const SectorVaultABI = `[
 		"name": "WithdrawalRequested",
 		"type": "event"
 	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "user", "type": "address"},
+			{"indexed": true, "name": "depositId", "type": "uint256"},
+			{"indexed": false, "name": "sharesAmount", "type": "uint256"},
+			{"indexed": false, "name": "timestamp", "type": "uint256"}
+		],
+		"name": "DepositFulfilled",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "user", "type": "address"},
+			{"indexed": true, "name": "depositId", "type": "uint256"},
+			{"indexed": false, "name": "quoteAmount", "type": "uint256"}
+		],
+		"name": "DepositCancelled",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "user", "type": "address"},
+			{"indexed": true, "name": "withdrawalId", "type": "uint256"},
+			{"indexed": false, "name": "usdcAmount", "type": "uint256"},
+			{"indexed": false, "name": "timestamp", "type": "uint256"}
+		],
+		"name": "WithdrawalFulfilled",
+		"type": "event"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+			{"indexed": true, "name": "user", "type": "address"},
+			{"indexed": true, "name": "withdrawalId", "type": "uint256"},
+			{"indexed": false, "name": "sharesAmount", "type": "uint256"}
+		],
+		"name": "WithdrawalCancelled",
+		"type": "event"
+	},
 	{
 		"constant": false,
 		"inputs": [
@@ -97,6 +139,15 @@ const SectorVaultABI = `[
 		"outputs": [],
 		"type": "function"
 	},
+	{
+		"constant": false,
+		"inputs": [
+			{"name": "withdrawalId", "type": "uint256"}
+		],
+		"name": "fulfillWithdrawal",
+		"outputs": [],
+		"type": "function"
+	},
 	{
 		"constant": true,
 		"inputs": [{"name": "", "type": "uint256"}],
@@ -193,10 +244,17 @@ const SectorVaultABI = `[
 		"name": "SECTOR_TOKEN",
 		"outputs": [{"name": "", "type": "address"}],
 		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [],
+		"name": "paused",
+		"outputs": [{"name": "", "type": "bool"}],
+		"type": "function"
 	}
 ]`
 
-// Oracle ABI (getPrice function)
+// Oracle ABI (getPrice and getValue functions)
 const OracleABI = `[
 	{
 		"constant": true,
@@ -205,6 +263,13 @@ const OracleABI = `[
 		"outputs": [{"name": "", "type": "uint256"}],
 		"type": "function"
 	},
+	{
+		"constant": true,
+		"inputs": [{"name": "token", "type": "address"}, {"name": "amount", "type": "uint256"}],
+		"name": "getValue",
+		"outputs": [{"name": "value", "type": "uint256"}],
+		"type": "function"
+	},
 	{
 		"constant": true,
 		"inputs": [],
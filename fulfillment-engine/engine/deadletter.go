@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a request whose automatic fulfillment failed and needs operator attention
+type DeadLetter struct {
+	VaultName string    `json:"vault_name"`
+	Type      string    `json:"type"` // "deposit" or "withdrawal"
+	ID        string    `json:"id"`
+	Reason    string    `json:"reason"`
+	TxHash    string    `json:"tx_hash,omitempty"`
+	FailedAt  time.Time `json:"failed_at"`
+	Attempts  int       `json:"attempts"`
+}
+
+// deadLetterStore persists failed requests to a JSON file so they survive restarts.
+// A request is listed once; a repeated failure updates its reason and attempt count,
+// and a later successful fulfillment removes it. A nil store records nothing.
+type deadLetterStore struct {
+	mu      sync.Mutex
+	path    string
+	entries []DeadLetter
+}
+
+// openDeadLetterStore loads the entries already stored at path
+func openDeadLetterStore(path string) (*deadLetterStore, error) {
+	s := &deadLetterStore{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read dead-letter file: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("parse dead-letter file: %v", err)
+	}
+	return s, nil
+}
+
+// Add records a failed request, or updates it if it is already listed
+func (s *deadLetterStore) Add(entry DeadLetter) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.find(entry.VaultName, entry.Type, entry.ID); i >= 0 {
+		entry.Attempts = s.entries[i].Attempts + 1
+		s.entries[i] = entry
+	} else {
+		entry.Attempts = 1
+		s.entries = append(s.entries, entry)
+	}
+	return s.save()
+}
+
+// Remove drops a request from the store; it reports whether it was listed
+func (s *deadLetterStore) Remove(vaultName, kind, id string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(vaultName, kind, id)
+	if i < 0 {
+		return false, nil
+	}
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+	return true, s.save()
+}
+
+// List returns the entries for vaultName, oldest failure first
+func (s *deadLetterStore) List(vaultName string) []DeadLetter {
+	entries := []DeadLetter{}
+	if s == nil {
+		return entries
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.VaultName == vaultName {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (s *deadLetterStore) find(vaultName, kind, id string) int {
+	for i, entry := range s.entries {
+		if entry.VaultName == vaultName && entry.Type == kind && entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// save atomically replaces the file with the current entries
+func (s *deadLetterStore) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".dead-letters-*")
+	if err != nil {
+		return fmt.Errorf("create temp dead-letter file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write dead-letter file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write dead-letter file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace dead-letter file: %v", err)
+	}
+	return nil
+}
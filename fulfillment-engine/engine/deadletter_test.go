@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letters.json")
+	s, err := openDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	for _, entry := range []DeadLetter{
+		{VaultName: "AI", Type: "deposit", ID: "1", Reason: "first"},
+		{VaultName: "AI", Type: "deposit", ID: "1", Reason: "second"},
+		{VaultName: "AI", Type: "withdrawal", ID: "1", Reason: "other type"},
+		{VaultName: "DeFi", Type: "deposit", ID: "1", Reason: "other vault"},
+	} {
+		if err := s.Add(entry); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	// Entries survive a reopen
+	s, err = openDeadLetterStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := s.List("AI")
+	if len(got) != 2 || got[0].Reason != "second" || got[0].Attempts != 2 || got[1].Type != "withdrawal" {
+		t.Fatalf("List(AI) = %+v", got)
+	}
+
+	if removed, err := s.Remove("AI", "deposit", "1"); !removed || err != nil {
+		t.Fatalf("Remove = %v, %v; want true", removed, err)
+	}
+	if removed, _ := s.Remove("AI", "deposit", "1"); removed {
+		t.Error("second Remove reported an entry")
+	}
+	if got := s.List("AI"); len(got) != 1 {
+		t.Errorf("List(AI) after remove = %+v, want 1 entry", got)
+	}
+}
+
+func TestFulfillerDeadLetter(t *testing.T) {
+	s, err := openDeadLetterStore(filepath.Join(t.TempDir(), "dead-letters.json"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	f := newTestFulfiller(t, newMockEthClient(), 6, 6, nil)
+	f.deadLetters = s
+
+	f.deadLetter("deposit", big.NewInt(7), common.Hash{}, fmt.Errorf("wrapped: %w", ErrRequestHeld))
+	if got := s.List("Test"); len(got) != 0 {
+		t.Fatalf("held request dead-lettered: %+v", got)
+	}
+
+	f.deadLetter("deposit", big.NewInt(7), common.Hash{}, ErrTxReverted)
+	if got := s.List("Test"); len(got) != 1 || got[0].ID != "7" || got[0].Reason != ErrTxReverted.Error() {
+		t.Fatalf("List after failure = %+v", got)
+	}
+
+	f.deadLetter("deposit", big.NewInt(7), common.Hash{1}, nil)
+	if got := s.List("Test"); len(got) != 0 {
+		t.Errorf("entry not cleared after success: %+v", got)
+	}
+}
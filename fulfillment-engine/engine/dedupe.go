@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Number of processed logs remembered for duplicate suppression
+	processedLogCacheSize = 10000
+)
+
+// logKey identifies a log by the transaction that emitted it and its position in the block
+type logKey struct {
+	txHash   common.Hash
+	logIndex uint
+}
+
+// logDedupe is a bounded LRU set of processed logs, used to suppress logs
+// re-delivered after a reorg moves their transaction into a new block
+type logDedupe struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front = most recently seen
+	entries  map[logKey]*list.Element
+}
+
+func newLogDedupe(capacity int) *logDedupe {
+	return &logDedupe{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[logKey]*list.Element),
+	}
+}
+
+// Seen reports whether key was already recorded, and records it if not
+func (d *logDedupe) Seen(key logKey) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if elem, ok := d.entries[key]; ok {
+		d.order.MoveToFront(elem)
+		return true
+	}
+
+	d.entries[key] = d.order.PushFront(key)
+	if d.order.Len() > d.capacity {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.entries, oldest.Value.(logKey))
+	}
+	return false
+}
+
+// Forget removes key, so the log is processed again the next time it is seen
+func (d *logDedupe) Forget(key logKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if elem, ok := d.entries[key]; ok {
+		d.order.Remove(elem)
+		delete(d.entries, key)
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestLogDedupe(t *testing.T) {
+	d := newLogDedupe(2)
+	a := logKey{txHash: common.HexToHash("0xa"), logIndex: 0}
+	b := logKey{txHash: common.HexToHash("0xb"), logIndex: 0}
+	c := logKey{txHash: common.HexToHash("0xb"), logIndex: 1}
+
+	if d.Seen(a) || d.Seen(b) {
+		t.Fatal("first sighting reported as duplicate")
+	}
+	if !d.Seen(a) {
+		t.Fatal("duplicate not detected")
+	}
+
+	// a was just touched, so adding c evicts b
+	if d.Seen(c) {
+		t.Fatal("distinct log index reported as duplicate")
+	}
+	if !d.Seen(a) {
+		t.Fatal("recently used entry evicted")
+	}
+	if d.Seen(b) {
+		t.Fatal("least recently used entry not evicted")
+	}
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TokenComposition is one underlying token's share of the vault versus its target weight
+type TokenComposition struct {
+	Token      common.Address `json:"token"`
+	Balance    string         `json:"balance"`
+	Value      string         `json:"value"` // oracle value in oracle decimals
+	CurrentBps int64          `json:"current_bps"`
+	TargetBps  int64          `json:"target_bps"`
+	DriftBps   int64          `json:"drift_bps"` // current - target
+}
+
+// VaultComposition is the value-weighted composition of a vault's holdings
+type VaultComposition struct {
+	VaultName   string             `json:"vault_name"`
+	Vault       string             `json:"vault"`
+	TotalValue  string             `json:"total_value"`
+	TotalSupply string             `json:"total_supply"`
+	Tokens      []TokenComposition `json:"tokens"`
+}
+
+// computeComposition converts balances to oracle values and compares each token's share
+// of the total value with its share of the total target weight, in basis points
+func computeComposition(
+	tokens []common.Address,
+	balances []*big.Int,
+	prices []*big.Int,
+	tokenDecimals []uint8,
+	weights []*big.Int,
+) ([]TokenComposition, *big.Int, error) {
+	if len(balances) != len(tokens) || len(prices) != len(tokens) || len(tokenDecimals) != len(tokens) || len(weights) != len(tokens) {
+		return nil, nil, fmt.Errorf("input length mismatch for %d tokens", len(tokens))
+	}
+
+	totalWeight := big.NewInt(0)
+	for _, weight := range weights {
+		totalWeight = new(big.Int).Add(totalWeight, weight)
+	}
+	if totalWeight.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("total weight is zero - vault target weights are misconfigured")
+	}
+
+	values := make([]*big.Int, len(tokens))
+	total := big.NewInt(0)
+	for i := range tokens {
+		values[i] = totalValue(balances[i:i+1], prices[i:i+1], tokenDecimals[i:i+1])
+		total = new(big.Int).Add(total, values[i])
+	}
+
+	bps := func(part, whole *big.Int) int64 {
+		if whole.Sign() == 0 {
+			return 0
+		}
+		return new(big.Int).Div(new(big.Int).Mul(part, big.NewInt(10000)), whole).Int64()
+	}
+
+	composition := make([]TokenComposition, len(tokens))
+	for i, token := range tokens {
+		current := bps(values[i], total)
+		target := bps(weights[i], totalWeight)
+		composition[i] = TokenComposition{
+			Token:      token,
+			Balance:    balances[i].String(),
+			Value:      values[i].String(),
+			CurrentBps: current,
+			TargetBps:  target,
+			DriftBps:   current - target,
+		}
+	}
+	return composition, total, nil
+}
+
+// GetVaultComposition reads vault balances, oracle prices and sector token supply and
+// reports how far each underlying token has drifted from its target weight
+func (f *Fulfiller) GetVaultComposition(ctx context.Context) (*VaultComposition, error) {
+	balancesByToken, err := f.getVaultBalances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault balances: %v", err)
+	}
+
+	u := f.underlying()
+	balances := make([]*big.Int, len(u.tokens))
+	prices := make([]*big.Int, len(u.tokens))
+	for i, token := range u.tokens {
+		balance, ok := balancesByToken[token]
+		if !ok {
+			balance = big.NewInt(0)
+		}
+		balances[i] = balance
+
+		price, err := f.getTokenPrice(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get price for token %s: %v", token.Hex(), err)
+		}
+		prices[i] = price
+	}
+
+	totalSupply, err := f.getSectorTokenTotalSupply(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sector token supply: %v", err)
+	}
+
+	tokens, totalValue, err := computeComposition(u.tokens, balances, prices, u.decimals, u.weights)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VaultComposition{
+		VaultName:   f.vaultConfig.Name,
+		Vault:       f.vaultConfig.Address.Hex(),
+		TotalValue:  totalValue.String(),
+		TotalSupply: totalSupply.String(),
+		Tokens:      tokens,
+	}, nil
+}
+
+// driftReportTimeout bounds the one-shot drift report
+const driftReportTimeout = 2 * time.Minute
+
+// runDriftReport writes the composition drift table for every vault to w
+func runDriftReport(fulfillers []*Fulfiller, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), driftReportTimeout)
+	defer cancel()
+
+	compositions := make([]*VaultComposition, 0, len(fulfillers))
+	for _, f := range fulfillers {
+		composition, err := f.GetVaultComposition(ctx)
+		if err != nil {
+			return fmt.Errorf("vault %s: %v", f.vaultConfig.Name, err)
+		}
+		compositions = append(compositions, composition)
+	}
+	return writeCompositionTable(w, compositions)
+}
+
+// writeCompositionTable prints one table per vault with per-token drift in basis points
+func writeCompositionTable(w io.Writer, compositions []*VaultComposition) error {
+	for _, c := range compositions {
+		fmt.Fprintf(w, "%s (%s) total_value=%s total_supply=%s\n", c.VaultName, c.Vault, c.TotalValue, c.TotalSupply)
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(tw, "token\tbalance\tvalue\tcurrent_bps\ttarget_bps\tdrift_bps\t")
+		for _, t := range c.Tokens {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%+d\t\n", t.Token.Hex(), t.Balance, t.Value, t.CurrentBps, t.TargetBps, t.DriftBps)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
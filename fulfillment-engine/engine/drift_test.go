@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestComputeComposition(t *testing.T) {
+	tokens := []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")}
+
+	// Token A: 600 units @ 1.0, token B: 200 units @ 2.0 (8 decimals) -> values 600 and 400
+	composition, total, err := computeComposition(
+		tokens,
+		[]*big.Int{big.NewInt(600_000000), big.NewInt(200_00000000)},
+		bigInts(1_000000, 2_000000),
+		[]uint8{6, 8},
+		bigInts(5000, 5000),
+	)
+	if err != nil {
+		t.Fatalf("computeComposition: %v", err)
+	}
+
+	if total.Int64() != 1000_000000 {
+		t.Errorf("total value = %s, want 1000000000", total)
+	}
+
+	want := []struct{ current, target, drift int64 }{
+		{current: 6000, target: 5000, drift: 1000},
+		{current: 4000, target: 5000, drift: -1000},
+	}
+	for i, w := range want {
+		got := composition[i]
+		if got.CurrentBps != w.current || got.TargetBps != w.target || got.DriftBps != w.drift {
+			t.Errorf("token %d: got current=%d target=%d drift=%d, want %d/%d/%d",
+				i, got.CurrentBps, got.TargetBps, got.DriftBps, w.current, w.target, w.drift)
+		}
+	}
+
+	if _, _, err := computeComposition(tokens, bigInts(1, 1), bigInts(1, 1), []uint8{6, 6}, bigInts(0, 0)); err == nil {
+		t.Error("expected error for zero total weight")
+	}
+}
@@ -0,0 +1,444 @@
+package engine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Engine fulfills the deposit and withdrawal requests of every configured vault. Create it
+// with New, start it with Run and release its connections and files with Shutdown.
+type Engine struct {
+	config *Config
+
+	client   *RPCClient
+	wsClient *RPCClient // separate log subscription endpoint (nil if WS_URL is unset)
+	account  *fulfillerAccount
+
+	notifier    *NotificationQueue
+	wal         *fulfillmentWAL
+	fulfillers  []*Fulfiller
+	listeners   []*EventListener
+	listenerRPC listenerClient
+
+	mu       sync.Mutex
+	stop     context.CancelFunc // cancels the running Run (nil when not running)
+	running  sync.WaitGroup
+	shutdown bool
+}
+
+// errEngineShutdown is returned by Run after Shutdown
+var errEngineShutdown = errors.New("engine is shut down")
+
+// New connects to the RPC endpoints and sets up a fulfiller and an event listener per vault.
+// Nothing is fulfilled until Run is called. InitLogger should be called first; otherwise
+// logs go to stdout at the configured level.
+func New(config *Config) (e *Engine, err error) {
+	if Logger == nil {
+		InitLogger(config.LogLevel, config.LogFormat, os.Stdout)
+	}
+
+	e = &Engine{config: config}
+	defer func() {
+		if err != nil {
+			e.close()
+		}
+	}()
+
+	// Price overrides must never go unnoticed outside staging
+	for token, price := range config.PriceOverrides {
+		Logger.Warn("PRICE OVERRIDE ACTIVE - fulfillments use this price instead of the oracle",
+			"token", token.Hex(),
+			"price", price.String(),
+		)
+	}
+
+	if config.DryRun {
+		Logger.Warn("DRY RUN - fulfillments are computed and logged, no transactions are sent")
+	}
+
+	// Vaults with different event signatures need their topics overridden before any listener starts
+	applyEventTopicOverrides(config.EventTopics)
+
+	// Connect to Ethereum client (shared across all vaults)
+	e.client, err = DialRPCClient(config.RPCURLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ethereum client: %v", err)
+	}
+	e.client.limiter = newRPCLimiter(config.RPCMaxRPS)
+	if config.RPCMaxRPS > 0 {
+		Logger.Info("RPC rate limit enabled", "max_rps", config.RPCMaxRPS)
+	}
+
+	// Sign for the chain the RPC serves, and refuse to start if that is not the intended network
+	chainID, err := withCallTimeout(context.Background(), config.RPCCallTimeout, "NetworkID", e.client.NetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain id: %v", err)
+	}
+	if config.ExpectedChainID != 0 && (!chainID.IsUint64() || chainID.Uint64() != config.ExpectedChainID) {
+		return nil, fmt.Errorf("connected to chain %s, expected %d - check RPC_URL or RPC_URLS", chainID.String(), config.ExpectedChainID)
+	}
+	Logger.Info("Connected to chain", "chain_id", chainID.String())
+
+	// Log subscriptions go over WS_URL when set; calls and sends stay on the RPC endpoints
+	e.listenerRPC = e.client
+	if config.WSURL != "" {
+		if config.SubscribeLogs {
+			e.wsClient, err = DialRPCClient([]string{config.WSURL})
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to websocket endpoint: %v", err)
+			}
+			e.wsClient.limiter = e.client.limiter
+			e.listenerRPC = &splitRPCClient{RPCClient: e.client, ws: e.wsClient}
+		} else {
+			Logger.Warn("WS_URL is only used with LISTENER_MODE=subscribe, ignoring")
+		}
+	}
+
+	// LOG_BACKEND=etherscan fetches logs from the getLogs HTTP API; headers and sends stay on the RPC
+	if config.LogBackend == logBackendEtherscan {
+		apiChainID := chainID.String()
+		if config.EtherscanChainID != 0 {
+			apiChainID = strconv.FormatUint(config.EtherscanChainID, 10)
+		}
+		e.listenerRPC = newEtherscanLogClient(e.listenerRPC, config.EtherscanAPIURL, config.EtherscanAPIKey,
+			apiChainID, config.EtherscanLagBlocks, config.EtherscanMaxRPS)
+		Logger.Info("Fetching logs from getLogs API",
+			"chain_id", apiChainID,
+			"lag_blocks", config.EtherscanLagBlocks,
+			"max_rps", config.EtherscanMaxRPS,
+		)
+	}
+
+	// Parse or derive the private key (shared across all vaults)
+	var privateKey *ecdsa.PrivateKey
+	if config.Mnemonic != "" {
+		privateKey, err = deriveKey(config.Mnemonic, config.DerivationPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mnemonic: %v", err)
+		}
+		Logger.Info("Derived fulfiller key from mnemonic", "derivation_path", config.DerivationPath.String())
+	} else {
+		privateKey, err = crypto.HexToECDSA(strings.TrimPrefix(config.PrivateKey, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %v", err)
+		}
+	}
+
+	publicKey := privateKey.Public()
+	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
+	}
+
+	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+
+	Logger.Info("Fulfiller wallet initialized",
+		"address", fromAddress.Hex(),
+	)
+
+	e.account = &fulfillerAccount{
+		nonce:       nil, // fetch on first use
+		fromAddress: fromAddress,
+		privateKey:  privateKey,
+		client:      e.client,
+		callTimeout: config.RPCCallTimeout,
+		chainID:     chainID,
+		gapRecovery: config.NonceGapRecovery,
+
+		gasPriceCache: config.GasPriceCache,
+		maxGasPrice:   config.MaxGasPrice,
+	}
+	if config.NonceFile != "" {
+		e.account.nonceStore = newNonceStore(config.NonceFile)
+	}
+
+	// Fulfillment notifications (nil when no notifier is configured)
+	e.notifier = NewNotificationQueueFromConfig(config, e.client)
+
+	// Failed fulfillments are recorded for operators (nil when DEAD_LETTER_FILE is unset)
+	var deadLetters *deadLetterStore
+	if config.DeadLetterFile != "" {
+		deadLetters, err = openDeadLetterStore(config.DeadLetterFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dead-letter store: %v", err)
+		}
+	}
+
+	// Fulfillment attempts are journaled so a restart does not resubmit (nil when WAL_FILE is unset).
+	// A dry run sends nothing, so it has nothing to journal.
+	if config.WALFile != "" && !config.DryRun {
+		e.wal, err = openFulfillmentWAL(config.WALFile, config.WALMaxSize, config.WALMaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open fulfillment WAL: %v", err)
+		}
+	}
+
+	// Create fulfillers and listeners for each vault
+	for _, vaultConfig := range config.SectorVaults {
+		Logger.Debug("Initializing vault",
+			"vault_name", vaultConfig.Name,
+			"vault_address", vaultConfig.Address.Hex(),
+		)
+
+		fulfiller, err := NewFulfiller(config, vaultConfig, e.account, e.notifier, deadLetters, e.wal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fulfiller for vault %s: %v", vaultConfig.Name, err)
+		}
+		e.fulfillers = append(e.fulfillers, fulfiller)
+
+		e.listeners = append(e.listeners, NewEventListener(e.listenerRPC, config, vaultConfig, fulfiller))
+	}
+
+	return e, nil
+}
+
+// Run fulfills requests until ctx is cancelled or Shutdown is called, then waits up to
+// SHUTDOWN_TIMEOUT for in-flight fulfillments and returns nil. It returns early with the
+// error of a listener or the HTTP API that stops unexpectedly.
+func (e *Engine) Run(ctx context.Context) error {
+	e.mu.Lock()
+	if e.shutdown {
+		e.mu.Unlock()
+		return errEngineShutdown
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	e.stop = cancel
+	e.running.Add(1)
+	e.mu.Unlock()
+	defer e.running.Done()
+	defer cancel()
+
+	config := e.config
+
+	// Start notification delivery worker
+	if e.notifier != nil {
+		e.notifier.Start(ctx)
+	}
+
+	// Track listener completion
+	var wg sync.WaitGroup
+	listenerErr := make(chan error, len(e.listeners)+1)
+
+	// Leader election: a standby keeps listening but leaves fulfillment to the leader
+	if config.LeaderLock != "" {
+		leader := newLeaderElector(config.LeaderLock)
+		isLeader, err := leader.TryAcquire()
+		if err != nil {
+			return fmt.Errorf("failed to acquire leader lock %s: %v", config.LeaderLock, err)
+		}
+		role := "standby"
+		if isLeader {
+			role = "leader"
+		}
+		Logger.Info("Leader election enabled", "lock", config.LeaderLock, "role", role)
+
+		for _, l := range e.listeners {
+			l.setLeader(leader)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			leader.Run(ctx)
+		}()
+	}
+
+	if config.SharedListener {
+		// Single listener loop polling all vaults at once
+		shared := NewSharedEventListener(e.listenerRPC, config, e.listeners)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Logger.Info("Starting shared event listener", "vault_count", len(e.listeners))
+			if err := shared.Start(ctx); err != nil && err != context.Canceled {
+				Logger.Error("Listener error", "error", err)
+				listenerErr <- err
+			}
+		}()
+	} else {
+		// Start all listeners in goroutines
+		for i, listener := range e.listeners {
+			wg.Add(1)
+			vaultName := config.SectorVaults[i].Name
+
+			go func(l *EventListener, name string) {
+				defer wg.Done()
+				Logger.Info("Starting event listener", "vault_name", name)
+				if err := l.Start(ctx); err != nil && err != context.Canceled {
+					Logger.Error("Listener error", "vault_name", name, "error", err)
+					listenerErr <- err
+				}
+			}(listener, vaultName)
+		}
+	}
+
+	// Start low-balance monitor
+	monitor := NewBalanceMonitor(config, e.account, e.fulfillers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := monitor.Start(ctx); err != nil && err != context.Canceled {
+			Logger.Error("Balance monitor error", "error", err)
+		}
+	}()
+
+	// Start periodic gas cost summary
+	gasReporter := NewGasReporter(config, e.fulfillers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := gasReporter.Start(ctx); err != nil && err != context.Canceled {
+			Logger.Error("Gas reporter error", "error", err)
+		}
+	}()
+
+	// Start periodic re-sync of vault target weights
+	weightSyncer := NewWeightSyncer(config, e.fulfillers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := weightSyncer.Start(ctx); err != nil && err != context.Canceled {
+			Logger.Error("Weight syncer error", "error", err)
+		}
+	}()
+
+	// Start periodic nonce reconciliation
+	nonceReconciler := NewNonceReconciler(config, e.account)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := nonceReconciler.Start(ctx); err != nil && err != context.Canceled {
+			Logger.Error("Nonce reconciler error", "error", err)
+		}
+	}()
+
+	// Start HTTP API if enabled
+	if config.APIPort > 0 {
+		apiServer := NewAPIServer(config, e.fulfillers, e.client)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := apiServer.Start(ctx); err != nil && err != context.Canceled {
+				Logger.Error("API server error", "error", err)
+				listenerErr <- err
+			}
+		}()
+	}
+
+	// Wait for shutdown or listener error
+	select {
+	case <-ctx.Done():
+		Logger.Info("Shutdown requested, initiating graceful shutdown",
+			"shutdown_timeout", config.ShutdownTimeout,
+		)
+
+		// Create shutdown context with timeout
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer shutdownCancel()
+
+		// Wait for in-flight fulfillments with timeout
+		shutdownComplete := make(chan struct{})
+		go func() {
+			Logger.Info("Waiting for in-flight fulfillments to complete")
+			for _, f := range e.fulfillers {
+				f.Wait()
+			}
+			close(shutdownComplete)
+		}()
+
+		select {
+		case <-shutdownComplete:
+			Logger.Info("All in-flight fulfillments completed")
+		case <-shutdownCtx.Done():
+			Logger.Warn("Shutdown timeout reached, forcing exit",
+				"timeout", config.ShutdownTimeout,
+			)
+		}
+
+		// Flush notifications queued by the fulfillments above, within the remaining budget
+		if e.notifier != nil {
+			flushed, dropped := e.notifier.Drain(shutdownCtx)
+			Logger.Info("Notification queue drained",
+				"flushed", flushed,
+				"dropped", dropped,
+			)
+		}
+
+		// Wait for all listeners to stop
+		wg.Wait()
+		Logger.Info("Fulfillment engine stopped gracefully")
+		return nil
+
+	case err := <-listenerErr:
+		cancel()
+		Logger.Error("Listener error, shutting down", "error", err)
+		// Wait for all listeners to stop before returning
+		wg.Wait()
+		return err
+	}
+}
+
+// Shutdown stops a running Run, waits for it to return, and closes the fulfillers, the WAL
+// and the RPC connections. The engine cannot be run again afterwards.
+func (e *Engine) Shutdown() {
+	e.mu.Lock()
+	if e.shutdown {
+		e.mu.Unlock()
+		return
+	}
+	e.shutdown = true
+	if e.stop != nil {
+		e.stop()
+	}
+	e.mu.Unlock()
+
+	e.running.Wait()
+	e.close()
+}
+
+// close releases what New opened
+func (e *Engine) close() {
+	for _, f := range e.fulfillers {
+		f.Close()
+	}
+	if e.wal != nil {
+		e.wal.Close()
+	}
+	if e.wsClient != nil {
+		e.wsClient.Close()
+	}
+	if e.client != nil {
+		e.client.Close()
+	}
+}
+
+// Reload re-reads the environment and .env file and applies the settings that are safe to
+// change while running
+func (e *Engine) Reload() {
+	reloadConfig(e.config)
+}
+
+// DriftReport writes the composition drift table for every vault to w
+func (e *Engine) DriftReport(w io.Writer) error {
+	return runDriftReport(e.fulfillers, w)
+}
+
+// Simulate writes the underlying amounts a deposit of quoteAmount (quote token base units) into
+// the named vault would be fulfilled with, computed from live prices without sending anything
+func (e *Engine) Simulate(w io.Writer, vaultName, quoteAmount string) error {
+	return runSimulate(e.fulfillers, vaultName, quoteAmount, w)
+}
+
+// Reconcile compares the WAL with the on-chain request state and writes the report to w.
+// mismatch is true if they disagree.
+func (e *Engine) Reconcile(w io.Writer) (mismatch bool, err error) {
+	return runReconcile(e.config, e.fulfillers, w)
+}
@@ -0,0 +1,17 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEngineRunAfterShutdown(t *testing.T) {
+	e := &Engine{config: &Config{}}
+	e.Shutdown()
+	e.Shutdown() // a second call is a no-op
+
+	if err := e.Run(context.Background()); !errors.Is(err, errEngineShutdown) {
+		t.Fatalf("Run after Shutdown = %v, want errEngineShutdown", err)
+	}
+}
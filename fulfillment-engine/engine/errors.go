@@ -0,0 +1,25 @@
+package engine
+
+import "errors"
+
+// Failure categories returned (wrapped) by the fulfiller. Match them with errors.Is.
+var (
+	// ErrInsufficientBalance means the fulfiller wallet cannot cover the tokens a request needs
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// ErrInsufficientInventory means the vault holds too little of an underlying token to pay out a withdrawal
+	ErrInsufficientInventory = errors.New("insufficient vault inventory")
+	// ErrInvalidPrice means the oracle returned a zero or negative price (unset or stale feed)
+	ErrInvalidPrice = errors.New("invalid oracle price")
+	// ErrPriceDeviation means the oracle price differs from the off-chain price check by more than allowed
+	ErrPriceDeviation = errors.New("oracle price deviates from price check")
+	// ErrDepositOvercommit means a deposit's computed underlying amounts are worth far more than the deposit
+	ErrDepositOvercommit = errors.New("deposit value exceeds safety cap")
+	// ErrTxReverted means the fulfillment transaction was mined but reverted
+	ErrTxReverted = errors.New("transaction reverted")
+	// ErrTxTimeout means the transaction was not mined within txWaitTimeout
+	ErrTxTimeout = errors.New("transaction not mined within timeout")
+	// ErrGasPriceTooHigh means the suggested gas price is above MAX_GAS_PRICE_GWEI; the request is retried later
+	ErrGasPriceTooHigh = errors.New("gas price above maximum")
+	// ErrNonceConflict means the node rejected the transaction nonce; the nonce tracker has been reset
+	ErrNonceConflict = errors.New("nonce conflict")
+)
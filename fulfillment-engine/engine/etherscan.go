@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Log backends (LOG_BACKEND)
+const (
+	logBackendRPC       = "rpc"       // eth_getLogs on the RPC endpoints
+	logBackendEtherscan = "etherscan" // Etherscan-style getLogs HTTP API
+)
+
+const (
+	// etherscanTimeout bounds each getLogs request
+	etherscanTimeout = 30 * time.Second
+	// etherscanPageSize is the most records the getLogs API returns per page
+	etherscanPageSize = 1000
+)
+
+// etherscanLogClient serves FilterLogs from an Etherscan-style getLogs API, for RPC providers
+// that reject eth_getLogs over large ranges. Blocks within lagBlocks of the head are still
+// queried over the RPC, since the explorer indexes new blocks with a delay. Headers and log
+// subscriptions go to the RPC.
+type etherscanLogClient struct {
+	listenerClient
+	client    *http.Client
+	url       string
+	apiKey    string
+	chainID   string
+	lagBlocks uint64
+
+	mu       sync.Mutex
+	interval time.Duration // minimum time between API requests, to stay under the key's rate limit
+	next     time.Time     // earliest time of the next request
+}
+
+func newEtherscanLogClient(rpc listenerClient, endpoint, apiKey, chainID string, lagBlocks uint64, maxRPS int) *etherscanLogClient {
+	c := &etherscanLogClient{
+		listenerClient: rpc,
+		client:         &http.Client{Timeout: etherscanTimeout},
+		url:            endpoint,
+		apiKey:         apiKey,
+		chainID:        chainID,
+		lagBlocks:      lagBlocks,
+	}
+	if maxRPS > 0 {
+		c.interval = time.Second / time.Duration(maxRPS)
+	}
+	return c
+}
+
+// pace waits until the next API request may be sent
+func (c *etherscanLogClient) pace(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	at := c.next
+	if at.Before(now) {
+		at = now
+	}
+	c.next = at.Add(c.interval)
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(at)):
+		return nil
+	}
+}
+
+// FilterLogs returns the logs matching query, ordered by block and log index like eth_getLogs.
+// Only the first topic position may be set; each of its values is fetched separately.
+func (c *etherscanLogClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if query.BlockHash != nil || query.FromBlock == nil || query.ToBlock == nil {
+		return nil, fmt.Errorf("getLogs API needs an explicit block range")
+	}
+	for _, topics := range query.Topics[min(1, len(query.Topics)):] {
+		if len(topics) > 0 {
+			return nil, fmt.Errorf("getLogs API backend only filters on the first topic")
+		}
+	}
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+
+	// Leave the blocks the explorer may not have indexed yet to the RPC
+	var logs []types.Log
+	if c.lagBlocks > 0 {
+		head, err := c.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get head for getLogs split: %v", err)
+		}
+		indexed := uint64(0)
+		if head.Number.Uint64() > c.lagBlocks {
+			indexed = head.Number.Uint64() - c.lagBlocks
+		}
+		if to > indexed {
+			recent := query
+			recent.FromBlock = new(big.Int).SetUint64(max(from, indexed+1))
+			recentLogs, err := c.listenerClient.FilterLogs(ctx, recent)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, recentLogs...)
+			if from > indexed {
+				return logs, nil
+			}
+			to = indexed
+		}
+	}
+
+	var topic0 []common.Hash
+	if len(query.Topics) > 0 {
+		topic0 = query.Topics[0]
+	}
+	for _, address := range query.Addresses {
+		if len(topic0) == 0 {
+			fetched, err := c.getLogs(ctx, address, nil, from, to)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, fetched...)
+			continue
+		}
+		for _, topic := range topic0 {
+			fetched, err := c.getLogs(ctx, address, &topic, from, to)
+			if err != nil {
+				return nil, err
+			}
+			logs = append(logs, fetched...)
+		}
+	}
+
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	return logs, nil
+}
+
+// etherscanResponse is the getLogs response envelope. Result is a list of logs on success and
+// an error string otherwise.
+type etherscanResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type etherscanLog struct {
+	Address          common.Address `json:"address"`
+	Topics           []common.Hash  `json:"topics"`
+	Data             string         `json:"data"`
+	BlockNumber      string         `json:"blockNumber"`
+	BlockHash        common.Hash    `json:"blockHash"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex string         `json:"transactionIndex"`
+	LogIndex         string         `json:"logIndex"`
+}
+
+// getLogs fetches every page of logs emitted by address in [from, to], optionally filtered on topic0
+func (c *etherscanLogClient) getLogs(ctx context.Context, address common.Address, topic0 *common.Hash, from, to uint64) ([]types.Log, error) {
+	var logs []types.Log
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("chainid", c.chainID)
+		params.Set("module", "logs")
+		params.Set("action", "getLogs")
+		params.Set("address", address.Hex())
+		params.Set("fromBlock", strconv.FormatUint(from, 10))
+		params.Set("toBlock", strconv.FormatUint(to, 10))
+		if topic0 != nil {
+			params.Set("topic0", topic0.Hex())
+		}
+		params.Set("page", strconv.Itoa(page))
+		params.Set("offset", strconv.Itoa(etherscanPageSize))
+		if c.apiKey != "" {
+			params.Set("apikey", c.apiKey)
+		}
+
+		fetched, err := c.getLogsPage(ctx, c.url+"?"+params.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("getLogs blocks %d-%d page %d: %v", from, to, page, err)
+		}
+		logs = append(logs, fetched...)
+		if len(fetched) < etherscanPageSize {
+			return logs, nil
+		}
+	}
+}
+
+func (c *etherscanLogClient) getLogsPage(ctx context.Context, endpoint string) ([]types.Log, error) {
+	if err := c.pace(ctx); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Strip the URL from the error so API keys never end up in logs
+		if urlErr, ok := err.(*url.Error); ok {
+			return nil, fmt.Errorf("get: %w", urlErr.Err)
+		}
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var body etherscanResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %v", err)
+	}
+	if body.Status != "1" {
+		// An empty range is reported as an error status
+		if strings.EqualFold(body.Message, "No records found") {
+			return nil, nil
+		}
+		var detail string
+		if json.Unmarshal(body.Result, &detail) != nil {
+			detail = string(body.Result)
+		}
+		return nil, fmt.Errorf("API error: %s: %s", body.Message, detail)
+	}
+
+	var results []etherscanLog
+	if err := json.Unmarshal(body.Result, &results); err != nil {
+		return nil, fmt.Errorf("decode logs: %v", err)
+	}
+	logs := make([]types.Log, 0, len(results))
+	for _, r := range results {
+		vLog, err := r.toLog()
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, vLog)
+	}
+	return logs, nil
+}
+
+// toLog converts an API log, whose numbers are hex strings ("0x" for zero), to a types.Log
+func (r etherscanLog) toLog() (types.Log, error) {
+	var numbers [3]uint64
+	for i, field := range []string{r.BlockNumber, r.TransactionIndex, r.LogIndex} {
+		digits := strings.TrimPrefix(field, "0x")
+		if digits == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(digits, 16, 64)
+		if err != nil {
+			return types.Log{}, fmt.Errorf("invalid log number %q: %v", field, err)
+		}
+		numbers[i] = n
+	}
+	data, err := hexutil.Decode(r.Data)
+	if err != nil {
+		return types.Log{}, fmt.Errorf("invalid log data: %v", err)
+	}
+	return types.Log{
+		Address:     r.Address,
+		Topics:      r.Topics,
+		Data:        data,
+		BlockNumber: numbers[0],
+		TxHash:      r.TransactionHash,
+		TxIndex:     uint(numbers[1]),
+		BlockHash:   r.BlockHash,
+		Index:       uint(numbers[2]),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestEtherscanLogClientFilterLogs(t *testing.T) {
+	vault := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	deposit := common.HexToHash(depositRequestedSignature)
+	withdrawal := common.HexToHash(withdrawalRequestedSignature)
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("chainid") != "84532" || q.Get("apikey") != "key" || !strings.EqualFold(q.Get("address"), vault.Hex()) {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		ranges = append(ranges, q.Get("fromBlock")+"-"+q.Get("toBlock"))
+
+		switch common.HexToHash(q.Get("topic0")) {
+		case deposit:
+			fmt.Fprintf(w, `{"status":"1","message":"OK","result":[{"address":"%s","topics":["%s"],"data":"0x01",
+				"blockNumber":"0x3c","blockHash":"0x%064x","transactionHash":"0x%064x","transactionIndex":"0x","logIndex":"0x"}]}`,
+				vault.Hex(), deposit.Hex(), 1, 2)
+		case withdrawal:
+			fmt.Fprintf(w, `{"status":"1","message":"OK","result":[{"address":"%s","topics":["%s"],"data":"0x",
+				"blockNumber":"0x37","blockHash":"0x%064x","transactionHash":"0x%064x","transactionIndex":"0x1","logIndex":"0x3"}]}`,
+				vault.Hex(), withdrawal.Hex(), 3, 4)
+		default:
+			fmt.Fprint(w, `{"status":"0","message":"No records found","result":[]}`)
+		}
+	}))
+	defer server.Close()
+
+	rpc := &fakeListenerClient{head: 100, chain: []types.Log{{Address: vault, BlockNumber: 90}}}
+	c := newEtherscanLogClient(rpc, server.URL, "key", "84532", 20, 0)
+
+	query := requestEventsQuery(50, 100, []common.Address{vault}, fulfillModeBoth)
+	logs, err := c.FilterLogs(context.Background(), query)
+	if err != nil {
+		t.Fatalf("FilterLogs: %v", err)
+	}
+
+	var blocks []uint64
+	for _, vLog := range logs {
+		blocks = append(blocks, vLog.BlockNumber)
+	}
+	if fmt.Sprint(blocks) != "[55 60 90]" {
+		t.Errorf("log blocks = %v, want [55 60 90] in order", blocks)
+	}
+	if logs[0].Index != 3 || logs[0].TxIndex != 1 || len(logs[1].Data) != 1 {
+		t.Errorf("decoded logs %+v, %+v", logs[0], logs[1])
+	}
+	if fmt.Sprint(ranges) != "[50-80 50-80]" {
+		t.Errorf("API ranges = %v, want both topics for 50-80", ranges)
+	}
+	if fmt.Sprint(rpc.queries) != "[[81 100]]" {
+		t.Errorf("RPC ranges = %v, want the unindexed 81-100", rpc.queries)
+	}
+
+	// Ranges entirely within the lag never reach the API
+	ranges = nil
+	if _, err := c.FilterLogs(context.Background(), requestEventsQuery(95, 100, []common.Address{vault}, fulfillModeBoth)); err != nil {
+		t.Fatalf("FilterLogs recent: %v", err)
+	}
+	if len(ranges) != 0 {
+		t.Errorf("API queried for recent blocks: %v", ranges)
+	}
+}
+
+func TestEtherscanLogClientAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`)
+	}))
+	defer server.Close()
+
+	c := newEtherscanLogClient(&fakeListenerClient{}, server.URL, "", "1", 0, 0)
+	_, err := c.FilterLogs(context.Background(), ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(10),
+		Addresses: []common.Address{{}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Max rate limit reached") {
+		t.Errorf("got %v, want the API error", err)
+	}
+}
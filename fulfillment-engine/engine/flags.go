@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"flag"
+	"io"
+	"os"
+)
+
+// flagEnv maps each command-line flag to the environment variable it overrides
+var flagEnv = map[string]string{
+	"rpc":           "RPC_URLS",
+	"vaults":        "SECTOR_VAULTS",
+	"poll-interval": "POLL_INTERVAL",
+	"log-level":     "LOG_LEVEL",
+	"dry-run":       "DRY_RUN",
+}
+
+// flagOverrides holds the values of the flags given on the command line, keyed by environment
+// variable. LoadConfig reads these before the environment, so they also survive a SIGHUP reload.
+var flagOverrides = map[string]string{}
+
+// ParseFlags parses the command-line flags into flagOverrides and returns the remaining
+// arguments, such as a subcommand. Flags must come before the subcommand.
+func ParseFlags(args []string, output io.Writer) ([]string, error) {
+	fs := flag.NewFlagSet("tone-fulfillment-engine", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.String("rpc", "", "comma-separated RPC endpoints in failover order (overrides RPC_URLS and RPC_URL)")
+	fs.String("vaults", "", "comma-separated vault addresses (overrides SECTOR_VAULTS and SECTOR_VAULT_<NAME>)")
+	fs.Uint("poll-interval", 0, "polling interval in seconds (overrides POLL_INTERVAL)")
+	fs.String("log-level", "", "DEBUG, INFO, WARN or ERROR (overrides LOG_LEVEL)")
+	fs.Bool("dry-run", false, "compute and log fulfillments without sending transactions (overrides DRY_RUN)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Only flags that were actually given override the environment
+	overrides := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		overrides[flagEnv[f.Name]] = f.Value.String()
+	})
+	flagOverrides = overrides
+	return fs.Args(), nil
+}
+
+// configEnv returns the command-line override of an environment variable, or its value
+func configEnv(key string) string {
+	if val, ok := flagOverrides[key]; ok {
+		return val
+	}
+	return os.Getenv(key)
+}
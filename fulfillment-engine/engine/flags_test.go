@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	t.Cleanup(func() { flagOverrides = map[string]string{} })
+
+	args, err := ParseFlags([]string{"-rpc", "https://a,https://b", "-poll-interval", "5", "-dry-run", "reconcile"}, io.Discard)
+	if err != nil {
+		t.Fatalf("ParseFlags: %v", err)
+	}
+	if want := []string{"reconcile"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	want := map[string]string{"RPC_URLS": "https://a,https://b", "POLL_INTERVAL": "5", "DRY_RUN": "true"}
+	if !reflect.DeepEqual(flagOverrides, want) {
+		t.Errorf("overrides = %v, want %v", flagOverrides, want)
+	}
+
+	t.Setenv("LOG_LEVEL", "WARN")
+	t.Setenv("POLL_INTERVAL", "30")
+	if got := configEnv("LOG_LEVEL"); got != "WARN" {
+		t.Errorf("LOG_LEVEL = %q, want the environment value WARN", got)
+	}
+	if got := configEnv("POLL_INTERVAL"); got != "5" {
+		t.Errorf("POLL_INTERVAL = %q, want the flag value 5", got)
+	}
+
+	if _, err := ParseFlags([]string{"-poll-interval", "soon"}, io.Discard); err == nil {
+		t.Error("ParseFlags accepted a non-numeric -poll-interval")
+	}
+}
@@ -0,0 +1,2424 @@
+package engine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// Transaction wait timeout in seconds
+	txWaitTimeout = 60
+	// Upper bound on all RPC calls made while initializing a fulfiller
+	fulfillerInitTimeout = 2 * time.Minute
+	// Largest token/oracle decimals accepted at startup; anything above points to a broken contract
+	maxDecimals = 36
+)
+
+// ERC20 approval modes (APPROVAL_MODE)
+const (
+	approvalModeInfinite = "infinite" // approve max uint256 once per token
+	approvalModeExact    = "exact"    // approve exactly what each fulfillment needs
+	approvalModeFixed    = "fixed"    // approve APPROVAL_CAP whenever the allowance runs short
+)
+
+var (
+	maxUint256, _ = new(big.Int).SetString("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", 16)
+	// An allowance at or above this (10^70) counts as an existing infinite approval
+	minInfiniteAllowance, _ = new(big.Int).SetString("1000000000000000000000000000000000000000000000000000000000000000000000", 10)
+)
+
+// Post-transaction state sync delay (a var so tests can disable it)
+var txSyncDelay = 2 * time.Second
+
+// EthClient is the subset of *ethclient.Client used by the Fulfiller.
+// It exists so the fulfiller can be exercised against a mock in tests.
+type EthClient interface {
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	Close()
+}
+
+var _ EthClient = (*ethclient.Client)(nil)
+
+type fulfillerAccount struct {
+	mu        sync.Mutex
+	nonce     *uint64    // Next nonce to hand out (fetched from the network if nil)
+	released  []uint64   // Nonces below nonce given back unsent, handed out again first (ascending)
+	nonceInit sync.Mutex // Serializes reservations so concurrent first sends fetch the nonce once
+
+	fromAddress common.Address
+	privateKey  *ecdsa.PrivateKey
+	client      EthClient
+	callTimeout time.Duration // Per-call RPC timeout (no bound if zero)
+	chainID     *big.Int      // Chain id transactions are signed for (fetched on first use if nil)
+
+	nonceStore *nonceStore // Persisted next nonce (disabled if nil)
+	reconciled bool        // Persisted nonce already reconciled against the network
+	savedNonce uint64      // Highest next nonce persisted so far
+
+	gapRecovery bool // Fill nonce gaps after repeated transaction timeouts
+	txTimeouts  int  // Consecutive transactions not mined within txWaitTimeout
+
+	gasPriceCache time.Duration // How long a suggested gas price is reused (always fetched if zero)
+	gasPrice      *big.Int      // Last suggested gas price
+	gasPriceAt    time.Time     // When gasPrice was fetched
+	maxGasPrice   *big.Int      // Transactions are refused while the suggested price is above this (no limit if nil)
+}
+
+type Fulfiller struct {
+	client            EthClient
+	config            *Config
+	vaultConfig       VaultConfig              // Specific vault this fulfiller manages
+	account           *fulfillerAccount        // account to use for fullfillments
+	wg                sync.WaitGroup           // Track in-flight fulfillments
+	mu                sync.Mutex               // protectes the approvedTokens, tokenDecimals map
+	underlyingTokens  []common.Address         // Cached underlying tokens, guarded by mu (refreshed if the vault's list changes)
+	underlyingWeights []*big.Int               // Cached underlying weights, guarded by mu
+	totalWeight       *big.Int                 // Sum of underlyingWeights, guarded by mu
+	approvedTokens    map[approvalKey]bool     // Track which (token, spender) pairs have max approval (infinite approval mode only)
+	oracleAddress     common.Address           // Oracle contract address
+	oracleDecimals    uint8                    // Oracle price decimals
+	quoteTokenAddress common.Address           // Quote token (e.g., USDC) address
+	quoteDecimals     uint8                    // Quote token decimals
+	tokenDecimals     map[common.Address]uint8 // Underlying token decimals
+	notifier          *NotificationQueue       // Fulfillment event notifications (nil if disabled)
+	holds             *requestHolds            // Requests over the auto-fulfillment limits awaiting approval
+	gas               *gasLedger               // Gas spent on this vault's transactions
+	latency           *latencyRecorder         // Request-to-confirmation times of this vault's fulfillments
+	prices            *priceGauge              // Last oracle price read per token
+	deadLetters       *deadLetterStore         // Persistent record of failed fulfillments (nil if disabled)
+	wal               *fulfillmentWAL          // Write-ahead log of fulfillment attempts (nil if disabled)
+
+	priceCheck  PriceSource                  // Off-chain prices the oracle is cross-checked against (nil if disabled)
+	priceAlerts map[common.Address]time.Time // Last deviation alert per token, guarded by mu
+
+	pendingApprovals []pendingApproval // Approvals sent without waiting (APPROVAL_CONFIRMATIONS=0), guarded by mu
+	processedBlock   atomic.Uint64     // Last block the vault's listener has fully processed (0 until started)
+
+	vaultPause *vaultPauseState // On-chain pause of the vault (nil if not checked)
+}
+
+func NewFulfiller(config *Config, vaultConfig VaultConfig, account *fulfillerAccount, notifier *NotificationQueue, deadLetters *deadLetterStore, wal *fulfillmentWAL) (*Fulfiller, error) {
+	fulfiller := &Fulfiller{
+		account:        account,
+		notifier:       notifier,
+		deadLetters:    deadLetters,
+		wal:            wal,
+		client:         account.client,
+		config:         config,
+		vaultConfig:    vaultConfig,
+		approvedTokens: make(map[approvalKey]bool),
+		tokenDecimals:  make(map[common.Address]uint8),
+		holds:          newRequestHolds(),
+		gas:            newGasLedger(),
+		latency:        newLatencyRecorder(),
+		prices:         newPriceGauge(),
+	}
+
+	// Bound initialization so a hung RPC node cannot block startup forever
+	ctx, cancel := context.WithTimeout(context.Background(), fulfillerInitTimeout)
+	defer cancel()
+
+	// Fetch oracle address from vault (unless configured)
+	verify := config.VerifyConfigOnchain
+	oracleAddr, err := startupValue(vaultConfig.OracleAddress, verify, "oracle address", func() (common.Address, error) {
+		return fulfiller.getOracleAddress(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oracle address: %v", err)
+	}
+	fulfiller.oracleAddress = oracleAddr
+
+	// Fetch quote token address from vault (unless configured)
+	quoteTokenAddr, err := startupValue(vaultConfig.QuoteToken, verify, "quote token", func() (common.Address, error) {
+		return fulfiller.getQuoteTokenAddress(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote token address: %v", err)
+	}
+	fulfiller.quoteTokenAddress = quoteTokenAddr
+
+	// Fetch quote token decimals (unless configured)
+	quoteDecimals, err := startupValue(vaultConfig.QuoteDecimals, verify, "quote token decimals", func() (uint8, error) {
+		return fulfiller.getTokenDecimals(ctx, quoteTokenAddr)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote token decimals: %v", err)
+	}
+	if err := validateDecimals(quoteDecimals); err != nil {
+		return nil, fmt.Errorf("quote token %s: %v", quoteTokenAddr.Hex(), err)
+	}
+	fulfiller.quoteDecimals = quoteDecimals
+
+	// Fetch underlying tokens and weights from vault
+	if err := fulfiller.loadUnderlyingTokens(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load underlying tokens: %v", err)
+	}
+
+	// Fetch oracle decimals (after the tokens, since per-token feeds are keyed by token)
+	oracleDecimals, err := fulfiller.loadOracleDecimals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oracle decimals: %v", err)
+	}
+	if err := validateDecimals(oracleDecimals); err != nil {
+		return nil, fmt.Errorf("oracle %s: %v", oracleAddr.Hex(), err)
+	}
+	fulfiller.oracleDecimals = oracleDecimals
+	if config.PriceCheckURL != "" {
+		fulfiller.priceCheck = newRESTPriceSource(config.PriceCheckURL, config.PriceCheckField, oracleDecimals)
+	}
+
+	// Fetch decimals for all underlying tokens
+	for _, token := range fulfiller.underlyingTokens {
+		decimals, err := fulfiller.getTokenDecimals(ctx, token)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get decimals for token %s: %v", token.Hex(), err)
+		}
+		if err := validateDecimals(decimals); err != nil {
+			return nil, fmt.Errorf("underlying token %s: %v", token.Hex(), err)
+		}
+		fulfiller.tokenDecimals[token] = decimals
+
+		Logger.Info("Underlying token decimals",
+			"vault_name", vaultConfig.Name,
+			"token", token.Hex(),
+			"decimals", decimals,
+		)
+	}
+
+	// Catch a payout mode the vault does not implement before the first withdrawal reverts
+	if err := fulfiller.checkWithdrawalPayout(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.VaultPauseCheck {
+		if err := fulfiller.probeVaultPause(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	Logger.Info("Fulfiller initialized for vault",
+		"vault_name", vaultConfig.Name,
+		"vault_address", vaultConfig.Address.Hex(),
+		"fulfiller_address", account.fromAddress.Hex(),
+		"oracle_address", oracleAddr.Hex(),
+		"oracle_variant", config.Oracle.Name,
+		"oracle_decimals", oracleDecimals,
+		"quote_token", quoteTokenAddr.Hex(),
+		"quote_decimals", quoteDecimals,
+		"underlying_tokens", len(fulfiller.underlyingTokens),
+		"withdrawal_payout_mode", config.WithdrawalPayoutMode,
+		"withdrawal_rounding", config.WithdrawalRounding,
+	)
+
+	return fulfiller, nil
+}
+
+// startupValue returns a configured deployment value, or reads it from the chain if unset.
+// With verify, a configured value is read as well and must match the chain.
+func startupValue[T comparable](configured *T, verify bool, name string, read func() (T, error)) (T, error) {
+	if configured == nil {
+		return read()
+	}
+	if !verify {
+		return *configured, nil
+	}
+	onchain, err := read()
+	if err != nil {
+		return onchain, fmt.Errorf("verify configured %s: %v", name, err)
+	}
+	if onchain != *configured {
+		return onchain, fmt.Errorf("configured %s %v does not match %v on-chain", name, *configured, onchain)
+	}
+	return onchain, nil
+}
+
+// loadOracleDecimals returns the configured oracle decimals or reads them from the oracle. An
+// oracle without a working decimals function gets ORACLE_DECIMALS_FALLBACK, with a warning.
+func (f *Fulfiller) loadOracleDecimals(ctx context.Context) (uint8, error) {
+	configured := f.vaultConfig.OracleDecimals
+	decimals, err := startupValue(configured, f.config.VerifyConfigOnchain, "oracle decimals", func() (uint8, error) {
+		return f.getOracleDecimals(ctx)
+	})
+	if configured != nil || !errors.Is(err, errCallUnsupported) || f.config.OracleDecimalsFallback == 0 {
+		return decimals, err
+	}
+	Logger.Warn("Oracle decimals call not supported, using ORACLE_DECIMALS_FALLBACK - set ORACLE_DECIMALS to confirm the value",
+		"vault_name", f.vaultConfig.Name,
+		"oracle", f.oracleAddress.Hex(),
+		"oracle_decimals", f.config.OracleDecimalsFallback,
+		"error", err,
+	)
+	return f.config.OracleDecimalsFallback, nil
+}
+
+// validateDecimals rejects decimals outside 1..maxDecimals, which would corrupt amount normalization
+func validateDecimals(decimals uint8) error {
+	if decimals == 0 || decimals > maxDecimals {
+		return fmt.Errorf("implausible decimals %d (expected 1-%d)", decimals, maxDecimals)
+	}
+	return nil
+}
+
+// FulfillDeposit sends the underlying tokens for a deposit. requestedAt is the on-chain request
+// time from the DepositRequested event (zero if unknown) and is only used for latency reporting.
+func (f *Fulfiller) FulfillDeposit(ctx context.Context, depositId *big.Int, quoteAmount *big.Int, requestedAt time.Time) (txHash common.Hash, err error) {
+	// Track this in-flight operation
+	f.wg.Add(1)
+	defer f.wg.Done()
+
+	// Tag every log line of this fulfillment so concurrent fulfillments can be told apart
+	logger := Logger.With("correlation_id", newCorrelationID())
+
+	// Check if context is already cancelled before starting
+	select {
+	case <-ctx.Done():
+		logger.Info("Deposit fulfillment cancelled before start",
+			"deposit_id", depositId.String(),
+			"reason", ctx.Err(),
+		)
+		return common.Hash{}, ctx.Err()
+	default:
+	}
+
+	// A paused engine sends nothing; the listener keeps the request until fulfillment resumes
+	if fulfillmentPause.paused() {
+		return common.Hash{}, errPaused
+	}
+	// Neither does a paused vault, where the fulfillment would revert
+	if err := f.checkVaultPaused(ctx); err != nil {
+		return common.Hash{}, err
+	}
+
+	// Reject zero-amount deposits before doing any work
+	if quoteAmount.Sign() <= 0 {
+		logger.Warn("Skipping deposit with zero quote amount",
+			"vault_name", f.vaultConfig.Name,
+			"deposit_id", depositId.String(),
+		)
+		return common.Hash{}, fmt.Errorf("%w: deposit %s has zero quote amount", errZeroAmount, depositId.String())
+	}
+
+	defer func() {
+		if !reportable(err) {
+			return
+		}
+		f.notify("deposit", depositId, quoteAmount, txHash, err)
+		f.deadLetter("deposit", depositId, txHash, err)
+	}()
+
+	// Hold oversized deposits for operator review
+	maxDepositValue, _ := f.config.requestLimits()
+	if err := f.holds.check("deposit", depositId, quoteAmount, maxDepositValue); err != nil {
+		logger.Warn("Deposit exceeds auto-fulfillment limit, holding for manual approval",
+			"vault_name", f.vaultConfig.Name,
+			"deposit_id", depositId.String(),
+			"quote_amount", quoteAmount.String(),
+			"limit", maxDepositValue.String(),
+		)
+		return common.Hash{}, err
+	}
+
+	f.walAppend(gasKindDeposit, depositId, walRecord{Stage: walStarted, Amount: quoteAmount.String()})
+	defer func() {
+		f.walOutcome(gasKindDeposit, depositId, txHash, err)
+	}()
+
+	// Snapshot the vault composition so a concurrent refresh cannot change it mid-calculation
+	u := f.underlying()
+
+	underlyingAmounts, tokenPrices, err := f.depositAmounts(ctx, logger.With("deposit_id", depositId.String()), u, quoteAmount)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// The amounts were computed to match the deposit at the engine's prices and decimals. Valued
+	// independently by the vault's oracle, anything far beyond the deposit means a bad price
+	// (override or feed), wrong decimals or a bug.
+	if f.config.MaxDepositOvercommitBps > 0 {
+		targetValue := normalizeDecimals(quoteAmount, f.quoteDecimals, f.oracleDecimals)
+		providedValue, err := f.oracleValue(ctx, u.tokens, underlyingAmounts)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to value deposit amounts with the oracle: %v", err)
+		}
+		if exceedsBps(providedValue, targetValue, f.config.MaxDepositOvercommitBps) {
+			logger.Error("Deposit underlying value exceeds safety cap, aborting",
+				"vault_name", f.vaultConfig.Name,
+				"deposit_id", depositId.String(),
+				"provided_value", providedValue.String(),
+				"target_value", targetValue.String(),
+				"max_overcommit_bps", f.config.MaxDepositOvercommitBps,
+				"amounts", bigStrings(underlyingAmounts),
+				"prices", bigStrings(tokenPrices),
+			)
+			return common.Hash{}, fmt.Errorf("%w: value %s for deposit worth %s (max %d bps over)",
+				ErrDepositOvercommit, providedValue.String(), targetValue.String(), f.config.MaxDepositOvercommitBps)
+		}
+	}
+
+	for i, token := range u.tokens {
+		logger.Debug("Calculated underlying token amount",
+			"deposit_id", depositId.String(),
+			"token_index", i,
+			"token", token.Hex(),
+			"token_decimals", u.decimals[i],
+			"weight", u.weights[i].String(),
+			"price", tokenPrices[i].String(),
+			"amount", underlyingAmounts[i].String(),
+		)
+	}
+	f.walAppend(gasKindDeposit, depositId, walRecord{
+		Stage:   walComputed,
+		Amounts: bigStrings(underlyingAmounts),
+		Prices:  bigStrings(tokenPrices),
+	})
+
+	// Check fulfiller holds enough of each underlying token before spending gas on approvals
+	var shortfalls []string
+	for i, token := range u.tokens {
+		balance, err := f.getTokenBalance(ctx, token, f.account.fromAddress)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to get balance for token %s: %v", token.Hex(), err)
+		}
+
+		if balance.Cmp(underlyingAmounts[i]) < 0 {
+			shortfall := new(big.Int).Sub(underlyingAmounts[i], balance)
+			logger.Error("Insufficient underlying token balance for deposit",
+				"vault_name", f.vaultConfig.Name,
+				"deposit_id", depositId.String(),
+				"token", token.Hex(),
+				"required", underlyingAmounts[i].String(),
+				"available", balance.String(),
+				"shortfall", shortfall.String(),
+			)
+			shortfalls = append(shortfalls, fmt.Sprintf("insufficient token %s: have %s, need %s, short %s",
+				token.Hex(), balance.String(), underlyingAmounts[i].String(), shortfall.String()))
+		}
+	}
+	if len(shortfalls) > 0 {
+		return common.Hash{}, fmt.Errorf("%w: %s", ErrInsufficientBalance, strings.Join(shortfalls, "; "))
+	}
+
+	if f.config.DryRun {
+		logger.Info("Dry run, not sending deposit fulfillment",
+			"vault_name", f.vaultConfig.Name,
+			"deposit_id", depositId.String(),
+			"quote_amount", quoteAmount.String(),
+			"amounts", bigStrings(underlyingAmounts),
+		)
+		return common.Hash{}, errDryRun
+	}
+
+	// Ensure all tokens have max approval (only approves once per token)
+	defer f.settleApprovals(ctx, logger)
+	for i, token := range u.tokens {
+		if err := f.ensureTokenApproval(ctx, logger, token, f.vaultConfig.Address, underlyingAmounts[i]); err != nil {
+			return common.Hash{}, fmt.Errorf("failed to ensure approval for token %s: %w", token.Hex(), err)
+		}
+	}
+	// Another engine instance may have fulfilled the deposit while we priced and approved it
+	deposit, err := f.GetPendingDeposit(ctx, depositId)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to re-check deposit status: %v", err)
+	}
+	if deposit.Fulfilled || deposit.User == (common.Address{}) {
+		logger.Info("Deposit settled before fulfillment was sent, skipping",
+			"vault_name", f.vaultConfig.Name,
+			"deposit_id", depositId.String(),
+		)
+		return common.Hash{}, errAlreadySettled
+	}
+
+	// A transaction from an earlier attempt may still be on its way
+	txHash, done, err := f.awaitPriorSubmission(ctx, logger, gasKindDeposit, depositId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !done {
+		txHash, err = f.callFulfillDeposit(ctx, logger, depositId, underlyingAmounts)
+		if err != nil {
+			if errors.Is(err, ErrTxReverted) {
+				f.refreshAfterRevert(ctx, logger)
+			}
+			return txHash, fmt.Errorf("failed to call fulfillDeposit: %w", err)
+		}
+	}
+
+	f.latency.observe(gasKindDeposit, requestedAt, time.Now())
+	logger.Info("Deposit fulfilled successfully",
+		"deposit_id", depositId.String(),
+		"quote_amount", quoteAmount.String(),
+		"tx_hash", txHash.Hex(),
+		"latency", requestLatency(requestedAt),
+	)
+	return txHash, nil
+}
+
+// FulfillWithdrawal sends the quote tokens for a withdrawal; requestedAt is as in FulfillDeposit
+func (f *Fulfiller) FulfillWithdrawal(ctx context.Context, withdrawalId *big.Int, sharesAmount *big.Int, requestedAt time.Time) (txHash common.Hash, err error) {
+	// Track this in-flight operation
+	f.wg.Add(1)
+	defer f.wg.Done()
+
+	// Tag every log line of this fulfillment so concurrent fulfillments can be told apart
+	logger := Logger.With("correlation_id", newCorrelationID())
+
+	// Check if context is already cancelled before starting
+	select {
+	case <-ctx.Done():
+		logger.Info("Withdrawal fulfillment cancelled before start",
+			"withdrawal_id", withdrawalId.String(),
+			"reason", ctx.Err(),
+		)
+		return common.Hash{}, ctx.Err()
+	default:
+	}
+
+	if fulfillmentPause.paused() {
+		return common.Hash{}, errPaused
+	}
+	if err := f.checkVaultPaused(ctx); err != nil {
+		return common.Hash{}, err
+	}
+
+	// Reject zero-share withdrawals before doing any work
+	if sharesAmount.Sign() <= 0 {
+		logger.Warn("Skipping withdrawal with zero shares amount",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+		)
+		return common.Hash{}, fmt.Errorf("%w: withdrawal %s has zero shares amount", errZeroAmount, withdrawalId.String())
+	}
+
+	defer func() {
+		if !reportable(err) {
+			return
+		}
+		f.notify("withdrawal", withdrawalId, sharesAmount, txHash, err)
+		f.deadLetter("withdrawal", withdrawalId, txHash, err)
+	}()
+
+	// Hold oversized withdrawals for operator review
+	_, maxWithdrawalShares := f.config.requestLimits()
+	if err := f.holds.check("withdrawal", withdrawalId, sharesAmount, maxWithdrawalShares); err != nil {
+		logger.Warn("Withdrawal exceeds auto-fulfillment limit, holding for manual approval",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+			"shares_amount", sharesAmount.String(),
+			"limit", maxWithdrawalShares.String(),
+		)
+		return common.Hash{}, err
+	}
+
+	logger.Info("Starting withdrawal fulfillment",
+		"vault_name", f.vaultConfig.Name,
+		"withdrawal_id", withdrawalId.String(),
+		"shares_amount", sharesAmount.String(),
+	)
+
+	f.walAppend(gasKindWithdrawal, withdrawalId, walRecord{Stage: walStarted, Amount: sharesAmount.String()})
+	defer func() {
+		f.walOutcome(gasKindWithdrawal, withdrawalId, txHash, err)
+	}()
+
+	// Get expected USDC value from vault contract
+	expectedUSDC, err := f.calculateWithdrawalValue(ctx, sharesAmount)
+	if err != nil {
+		logger.Error("Failed to calculate withdrawal value",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+			"error", err,
+		)
+		return common.Hash{}, fmt.Errorf("failed to calculate withdrawal value: %v", err)
+	}
+
+	logger.Info("Calculated expected USDC for withdrawal",
+		"vault_name", f.vaultConfig.Name,
+		"withdrawal_id", withdrawalId.String(),
+		"expected_usdc", expectedUSDC.String(),
+	)
+
+	if expectedUSDC.Sign() <= 0 {
+		logger.Warn("Skipping withdrawal with zero expected USDC value",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+		)
+		return common.Hash{}, fmt.Errorf("%w: withdrawal %s has zero expected USDC value", errZeroAmount, withdrawalId.String())
+	}
+
+	// Check if fulfiller has enough USDC balance
+	usdcBalance, err := f.getTokenBalance(ctx, f.quoteTokenAddress, f.account.fromAddress)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get USDC balance: %v", err)
+	}
+
+	if usdcBalance.Cmp(expectedUSDC) < 0 {
+		logger.Error("Insufficient USDC balance for withdrawal",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+			"required", expectedUSDC.String(),
+			"available", usdcBalance.String(),
+		)
+		return common.Hash{}, fmt.Errorf("%w: USDC have %s, need %s", ErrInsufficientBalance, usdcBalance.String(), expectedUSDC.String())
+	}
+
+	// Snapshot the vault composition so a concurrent refresh cannot change it between computing
+	// the amounts and checking the vault's inventory of each token
+	u := f.underlying()
+
+	// In quote payout mode the vault keeps the underlying tokens, so only the quote amount is sent
+	var underlyingAmounts, tokenPrices []*big.Int
+	if f.config.WithdrawalPayoutMode != withdrawalPayoutQuote {
+		underlyingAmounts, tokenPrices, err = f.withdrawalAmounts(ctx, logger, u, withdrawalId, expectedUSDC)
+		if err != nil {
+			return common.Hash{}, err
+		}
+	}
+	f.walAppend(gasKindWithdrawal, withdrawalId, walRecord{
+		Stage:      walComputed,
+		QuoteValue: expectedUSDC.String(),
+		Amounts:    bigStrings(underlyingAmounts),
+		Prices:     bigStrings(tokenPrices),
+	})
+
+	// The fulfiller only provides the USDC; the vault transfers the underlying tokens to it,
+	// so fulfillWithdrawal reverts if the vault itself holds too little of any of them
+	if err := f.checkVaultInventory(ctx, logger, u, withdrawalId, underlyingAmounts); err != nil {
+		return common.Hash{}, err
+	}
+
+	if f.config.DryRun {
+		logger.Info("Dry run, not sending withdrawal fulfillment",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+			"usdc_amount", expectedUSDC.String(),
+			"amounts", bigStrings(underlyingAmounts),
+		)
+		return common.Hash{}, errDryRun
+	}
+
+	// Ensure USDC has max approval to vault
+	defer f.settleApprovals(ctx, logger)
+	if err := f.ensureTokenApproval(ctx, logger, f.quoteTokenAddress, f.vaultConfig.Address, expectedUSDC); err != nil {
+		if !errors.Is(err, ErrGasPriceTooHigh) {
+			logger.Error("Failed to ensure USDC approval",
+				"vault_name", f.vaultConfig.Name,
+				"withdrawal_id", withdrawalId.String(),
+				"error", err,
+			)
+		}
+		return common.Hash{}, fmt.Errorf("failed to ensure USDC approval: %w", err)
+	}
+
+	logger.Info("Fulfilling withdrawal with USDC",
+		"vault_name", f.vaultConfig.Name,
+		"withdrawal_id", withdrawalId.String(),
+		"usdc_amount", expectedUSDC.String(),
+	)
+
+	// A transaction from an earlier attempt may still be on its way
+	txHash, done, err := f.awaitPriorSubmission(ctx, logger, gasKindWithdrawal, withdrawalId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if !done {
+		// Call fulfillWithdrawal on the vault
+		txHash, err = f.callFulfillWithdrawal(ctx, logger, withdrawalId, underlyingAmounts)
+		if err != nil {
+			if errors.Is(err, errAlreadySettled) {
+				return txHash, err
+			}
+			if errors.Is(err, ErrTxReverted) {
+				f.refreshAfterRevert(ctx, logger)
+			}
+			logger.Error("Failed to fulfill withdrawal",
+				"vault_name", f.vaultConfig.Name,
+				"withdrawal_id", withdrawalId.String(),
+				"error", err,
+			)
+			return txHash, fmt.Errorf("failed to call fulfillWithdrawal: %w", err)
+		}
+	}
+
+	f.latency.observe(gasKindWithdrawal, requestedAt, time.Now())
+	logger.Info("Withdrawal fulfilled successfully",
+		"vault_name", f.vaultConfig.Name,
+		"withdrawal_id", withdrawalId.String(),
+		"shares_amount", sharesAmount.String(),
+		"usdc_transferred", expectedUSDC.String(),
+		"tx_hash", txHash.Hex(),
+		"latency", requestLatency(requestedAt),
+	)
+	return txHash, nil
+}
+
+// checkVaultInventory checks the vault holds each underlying amount a withdrawal would transfer
+// to the fulfiller, and returns ErrInsufficientInventory listing every token it is short of.
+// underlyingAmounts are indexed like u.tokens.
+func (f *Fulfiller) checkVaultInventory(ctx context.Context, logger *slog.Logger, u underlyingSet, withdrawalId *big.Int, underlyingAmounts []*big.Int) error {
+	var shortfalls []string
+	for i, amount := range underlyingAmounts {
+		if amount.Sign() <= 0 {
+			continue // the vault skips zero amounts
+		}
+		token := u.tokens[i]
+		balance, err := f.getTokenBalance(ctx, token, f.vaultConfig.Address)
+		if err != nil {
+			return fmt.Errorf("failed to get vault balance for token %s: %v", token.Hex(), err)
+		}
+
+		if balance.Cmp(amount) < 0 {
+			shortfall := new(big.Int).Sub(amount, balance)
+			logger.Error("Insufficient vault balance of underlying token for withdrawal",
+				"vault_name", f.vaultConfig.Name,
+				"withdrawal_id", withdrawalId.String(),
+				"token", token.Hex(),
+				"required", amount.String(),
+				"available", balance.String(),
+				"shortfall", shortfall.String(),
+			)
+			shortfalls = append(shortfalls, fmt.Sprintf("vault token %s: have %s, need %s, short %s",
+				token.Hex(), balance.String(), amount.String(), shortfall.String()))
+		}
+	}
+	if len(shortfalls) > 0 {
+		return fmt.Errorf("%w: %s", ErrInsufficientInventory, strings.Join(shortfalls, "; "))
+	}
+	return nil
+}
+
+// rebalancingWeights reads the vault balances and returns the value allocation of a deposit
+// worth depositValue (oracle units) in rebalancing mode. It falls back to the target weights
+// when the deposit is too small to allocate.
+func (f *Fulfiller) rebalancingWeights(ctx context.Context, u underlyingSet, prices []*big.Int, depositValue *big.Int) ([]*big.Int, error) {
+	if depositValue.Sign() <= 0 {
+		return u.weights, nil
+	}
+	balancesByToken, err := f.getVaultBalances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vault balances: %v", err)
+	}
+	balances := make([]*big.Int, len(u.tokens))
+	for i, token := range u.tokens {
+		balance, ok := balancesByToken[token]
+		if !ok {
+			balance = big.NewInt(0)
+		}
+		balances[i] = balance
+	}
+
+	allocation, err := rebalancingAllocation(depositValue, u.weights, balances, prices, u.decimals)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range allocation {
+		if value.Sign() > 0 {
+			return allocation, nil
+		}
+	}
+	return u.weights, nil
+}
+
+// depositAmounts computes the underlying tokens the fulfiller provides for a deposit of
+// quoteAmount, split by target weight or, in rebalancing mode, toward the target composition.
+// It also returns the token prices the amounts were computed with. It only reads the chain, so
+// the simulate command uses it too.
+func (f *Fulfiller) depositAmounts(ctx context.Context, logger *slog.Logger, u underlyingSet, quoteAmount *big.Int) ([]*big.Int, []*big.Int, error) {
+	// Fetch token prices from oracle
+	tokenPrices := make([]*big.Int, len(u.tokens))
+	for i, token := range u.tokens {
+		price, err := f.fulfillmentPrice(ctx, logger, token)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get price for token %s: %w", token.Hex(), err)
+		}
+		tokenPrices[i] = price
+
+		logger.Debug("Fetched token price",
+			"token_index", i,
+			"token", token.Hex(),
+			"price", price.String(),
+		)
+	}
+
+	// In rebalancing mode, deposits are split to move the vault toward its target weights
+	weights := u.weights
+	if f.config.DepositAllocationMode == depositAllocationRebalancing {
+		var err error
+		weights, err = f.rebalancingWeights(ctx, u, tokenPrices, normalizeDecimals(quoteAmount, f.quoteDecimals, f.oracleDecimals))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to compute rebalancing allocation: %v", err)
+		}
+		logger.Debug("Rebalancing deposit allocation", "allocation", bigStrings(weights))
+	}
+
+	// Calculate underlying amounts based on weights AND prices (with dynamic decimals)
+	underlyingAmounts, err := computeDepositAmounts(
+		quoteAmount,
+		weights,
+		tokenPrices,
+		u.decimals,
+		f.quoteDecimals,
+		f.oracleDecimals,
+		contractToleranceBps,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute underlying amounts: %v", err)
+	}
+	return underlyingAmounts, tokenPrices, nil
+}
+
+// withdrawalAmounts computes the underlying tokens the vault sends the fulfiller for a withdrawal
+// worth expectedUSDC, split by target weight and kept within the vault's acceptance band.
+// It also returns the token prices the amounts were computed with.
+func (f *Fulfiller) withdrawalAmounts(ctx context.Context, logger *slog.Logger, u underlyingSet, withdrawalId *big.Int, expectedUSDC *big.Int) ([]*big.Int, []*big.Int, error) {
+	// The vault compares the underlying value with the withdrawal value normalized to oracle
+	// decimals, so allocate in oracle units as FulfillDeposit does
+	targetValue := normalizeDecimals(expectedUSDC, f.quoteDecimals, f.oracleDecimals)
+	if targetValue.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("withdrawal value %s is below one oracle unit", expectedUSDC.String())
+	}
+
+	// Calculate underlying amounts to send back based on vault composition
+	// We need to send proportional amounts of each underlying token
+	underlyingAmounts := make([]*big.Int, len(u.tokens))
+
+	// Fetch token prices from oracle
+	tokenPrices := make([]*big.Int, len(u.tokens))
+	for i, token := range u.tokens {
+		price, err := f.fulfillmentPrice(ctx, logger, token)
+		if err != nil {
+			logger.Error("Failed to get token price for withdrawal",
+				"withdrawal_id", withdrawalId.String(),
+				"token_index", i,
+				"token", token.Hex(),
+				"error", err,
+			)
+			return nil, nil, fmt.Errorf("failed to get price for token %s: %w", token.Hex(), err)
+		}
+		tokenPrices[i] = price
+	}
+
+	// Check the cached total weight
+	if u.totalWeight.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("total weight is zero - vault target weights are misconfigured")
+	}
+
+	// For each underlying token, calculate the amount based on weight and prices
+	totalProvidedValue := big.NewInt(0)
+
+	for i, weight := range u.weights {
+		token := u.tokens[i]
+		tokenDec := u.decimals[i]
+
+		// Step 1: Calculate value allocation (in oracle decimals)
+		valueAllocation := new(big.Int).Div(new(big.Int).Mul(targetValue, weight), u.totalWeight)
+
+		// Step 2: Calculate token amount needed to provide the value allocation
+		// oracle.getValue(token, amount) = (amount * price) / 10^tokenDecimals
+		// amount = (valueAllocation * 10^tokenDecimals) / price, rounded per WITHDRAWAL_ROUNDING.
+		// The default floor-with-topup rounds down; a shortfall of the total is topped up below.
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDec)), nil)
+		numerator := new(big.Int).Mul(valueAllocation, tokenDecMultiplier)
+		amount := roundedDiv(numerator, tokenPrices[i], f.config.WithdrawalRounding)
+		underlyingAmounts[i] = amount
+
+		// Calculate actual value this amount provides
+		actualValue := new(big.Int).Div(new(big.Int).Mul(amount, tokenPrices[i]), tokenDecMultiplier)
+		totalProvidedValue = new(big.Int).Add(totalProvidedValue, actualValue)
+
+		logger.Debug("Calculated underlying token amount for withdrawal",
+			"withdrawal_id", withdrawalId.String(),
+			"token_index", i,
+			"token", token.Hex(),
+			"token_decimals", tokenDec,
+			"weight", weight.String(),
+			"price", tokenPrices[i].String(),
+			"value_allocation", valueAllocation.String(),
+			"amount", amount.String(),
+			"actual_value", actualValue.String(),
+		)
+	}
+
+	// Check if we need to add more value to meet the tolerance
+	// The contract checks: difference <= (normalizedExpectedUSDC / 1000) + 1
+	tolerance := new(big.Int).Div(targetValue, big.NewInt(1000))
+	tolerance = new(big.Int).Add(tolerance, big.NewInt(1))
+
+	var difference *big.Int
+	if totalProvidedValue.Cmp(targetValue) >= 0 {
+		difference = new(big.Int).Sub(totalProvidedValue, targetValue)
+	} else {
+		difference = new(big.Int).Sub(targetValue, totalProvidedValue)
+	}
+
+	logger.Debug("Withdrawal value check",
+		"withdrawal_id", withdrawalId.String(),
+		"expected_usdc", expectedUSDC.String(),
+		"target_value", targetValue.String(),
+		"total_provided_value", totalProvidedValue.String(),
+		"difference", difference.String(),
+		"tolerance", tolerance.String(),
+	)
+
+	// If we're providing less than required, increase amounts to meet the target (capped at 0.01 USD).
+	// Nearest rounding accepts a shortfall the vault tolerates.
+	short := totalProvidedValue.Cmp(targetValue) < 0
+	if f.config.WithdrawalRounding == withdrawalRoundingNearest {
+		short = short && difference.Cmp(tolerance) > 0
+	}
+	if short {
+		// We're under the expected value. Find the token with the largest weight (usually most liquid)
+		maxWeightIdx := 0
+		maxWeight := u.weights[0]
+		for i, w := range u.weights {
+			if w.Cmp(maxWeight) > 0 {
+				maxWeight = w
+				maxWeightIdx = i
+			}
+		}
+
+		// Calculate how much more value we need, but cap at 0.01 USD
+		currentShortfall := new(big.Int).Sub(targetValue, totalProvidedValue)
+		maxIncreaseValue := normalizeDecimals(big.NewInt(1), 2, f.oracleDecimals) // 0.01 in oracle decimals
+
+		if currentShortfall.Cmp(maxIncreaseValue) > 0 {
+			currentShortfall = maxIncreaseValue
+		}
+
+		tokenDecMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(u.decimals[maxWeightIdx])), nil)
+		price := tokenPrices[maxWeightIdx]
+
+		// Calculate ceiling((shortfall * 10^tokenDecimals) / price) as the amount to increase
+		increaseNumerator := new(big.Int).Mul(currentShortfall, tokenDecMultiplier)
+		increaseAmount := new(big.Int).Div(
+			new(big.Int).Add(increaseNumerator, new(big.Int).Sub(price, big.NewInt(1))),
+			price,
+		)
+
+		// Always add at least 1 token to ensure value improvement
+		if increaseAmount.Sign() <= 0 {
+			increaseAmount = big.NewInt(1)
+		}
+
+		underlyingAmounts[maxWeightIdx] = new(big.Int).Add(underlyingAmounts[maxWeightIdx], increaseAmount)
+
+		// Recalculate actual value provided after increase
+		newActualValue := new(big.Int).Div(new(big.Int).Mul(increaseAmount, price), tokenDecMultiplier)
+		newTotalValue := new(big.Int).Add(totalProvidedValue, newActualValue)
+		totalProvidedValue = newTotalValue
+
+		logger.Debug("Increased token amount to meet withdrawal value",
+			"withdrawal_id", withdrawalId.String(),
+			"token_index", maxWeightIdx,
+			"token", u.tokens[maxWeightIdx].Hex(),
+			"shortfall", currentShortfall.String(),
+			"increase_tokens", increaseAmount.String(),
+			"increase_value", newActualValue.String(),
+			"new_total_value", newTotalValue.String(),
+			"target_value", targetValue.String(),
+		)
+	}
+
+	// Rounding up can overshoot the vault's acceptance band; trim the excess from the lowest-weight tokens
+	trimmedAmounts, trimmedValue := trimExcessValue(
+		underlyingAmounts,
+		u.weights,
+		tokenPrices,
+		u.decimals,
+		targetValue,
+		f.config.WithdrawalToleranceBps,
+	)
+	if trimmedValue.Cmp(totalProvidedValue) != 0 {
+		logger.Info("Trimmed excess withdrawal value",
+			"vault_name", f.vaultConfig.Name,
+			"withdrawal_id", withdrawalId.String(),
+			"pre_trim_value", totalProvidedValue.String(),
+			"post_trim_value", trimmedValue.String(),
+			"target_value", targetValue.String(),
+			"tolerance_bps", f.config.WithdrawalToleranceBps,
+		)
+		underlyingAmounts = trimmedAmounts
+	}
+
+	return underlyingAmounts, tokenPrices, nil
+}
+
+// requestLatency is the time from the on-chain request to now, or 0 if the request time is unknown
+func requestLatency(requestedAt time.Time) time.Duration {
+	if requestedAt.IsZero() {
+		return 0
+	}
+	return time.Since(requestedAt).Round(time.Second)
+}
+
+// reportable reports whether a fulfillment result is notified and dead-lettered. Requests settled
+// by another engine instance, held for approval, not sent (yet) or with nothing to send are not.
+func reportable(err error) bool {
+	return !errors.Is(err, errAlreadySettled) &&
+		!errors.Is(err, ErrRequestHeld) &&
+		!errors.Is(err, errDryRun) &&
+		!errors.Is(err, ErrGasPriceTooHigh) &&
+		!errors.Is(err, errZeroAmount)
+}
+
+// notify queues a fulfillment event for the configured notifiers (non-blocking)
+func (f *Fulfiller) notify(kind string, requestId *big.Int, amount *big.Int, txHash common.Hash, err error) {
+	event := FulfillmentEvent{
+		Kind:      kind,
+		VaultName: f.vaultConfig.Name,
+		RequestID: requestId.String(),
+		Amount:    amount.String(),
+		Err:       err,
+	}
+	if txHash != (common.Hash{}) {
+		event.TxHash = txHash.Hex()
+	}
+	f.notifier.Enqueue(event)
+}
+
+// deadLetter records a failed fulfillment in the dead-letter store and clears the entry once
+// the request is fulfilled. Held requests and fulfillments interrupted by shutdown are not failures.
+func (f *Fulfiller) deadLetter(kind string, requestId *big.Int, txHash common.Hash, err error) {
+	if f.deadLetters == nil || errors.Is(err, ErrRequestHeld) || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err == nil {
+		if _, err := f.deadLetters.Remove(f.vaultConfig.Name, kind, requestId.String()); err != nil {
+			Logger.Warn("Failed to update dead-letter store", "vault_name", f.vaultConfig.Name, "error", err)
+		}
+		return
+	}
+
+	entry := DeadLetter{
+		VaultName: f.vaultConfig.Name,
+		Type:      kind,
+		ID:        requestId.String(),
+		Reason:    err.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+	if txHash != (common.Hash{}) {
+		entry.TxHash = txHash.Hex()
+	}
+	if err := f.deadLetters.Add(entry); err != nil {
+		Logger.Warn("Failed to write dead-letter store", "vault_name", f.vaultConfig.Name, "error", err)
+		return
+	}
+	Logger.Warn("Fulfillment dead-lettered",
+		"vault_name", f.vaultConfig.Name,
+		"type", kind,
+		"id", entry.ID,
+		"reason", entry.Reason,
+	)
+}
+
+// walAppend records a stage of a fulfillment attempt in the WAL
+func (f *Fulfiller) walAppend(kind string, requestId *big.Int, rec walRecord) {
+	if f.wal == nil {
+		return
+	}
+	rec.Time = time.Now().UTC()
+	rec.VaultName = f.vaultConfig.Name
+	rec.Type = kind
+	rec.ID = requestId.String()
+	if err := f.wal.Append(rec); err != nil {
+		Logger.Warn("Failed to write fulfillment WAL", "vault_name", f.vaultConfig.Name, "stage", rec.Stage, "error", err)
+	}
+}
+
+// walSubmitted records a broadcast fulfillment transaction
+func (f *Fulfiller) walSubmitted(kind string, requestId *big.Int, tx *types.Transaction) {
+	nonce := tx.Nonce()
+	f.walAppend(kind, requestId, walRecord{Stage: walSubmitted, TxHash: tx.Hash().Hex(), Nonce: &nonce})
+}
+
+// walOutcome records how a fulfillment attempt ended. A timeout or shutdown leaves a submitted
+// transaction unconfirmed rather than failed, since it may still be mined.
+func (f *Fulfiller) walOutcome(kind string, requestId *big.Int, txHash common.Hash, err error) {
+	rec := walRecord{Stage: walConfirmed}
+	switch {
+	case err == nil:
+	case errors.Is(err, errAlreadySettled):
+		rec.Stage = walSettled
+	case errors.Is(err, ErrTxTimeout) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		rec.Stage = walUnconfirmed
+		rec.Error = err.Error()
+	default:
+		rec.Stage = walFailed
+		rec.Error = err.Error()
+	}
+	if txHash != (common.Hash{}) {
+		rec.TxHash = txHash.Hex()
+	}
+	f.walAppend(kind, requestId, rec)
+}
+
+// awaitPriorSubmission waits for a fulfillment transaction the WAL shows as submitted but not
+// confirmed, such as one sent just before a restart. done is true once it is mined successfully;
+// if it reverted or is not mined within txWaitTimeout, a new transaction should be sent. A stale
+// one mined later makes that new transaction revert as already settled.
+func (f *Fulfiller) awaitPriorSubmission(ctx context.Context, logger *slog.Logger, kind string, requestId *big.Int) (txHash common.Hash, done bool, err error) {
+	prior, ok := f.wal.Submitted(f.vaultConfig.Name, kind, requestId.String())
+	if !ok {
+		return common.Hash{}, false, nil
+	}
+	hash := common.HexToHash(prior.TxHash)
+	logger.Info("Waiting for previously submitted fulfillment transaction",
+		"vault_name", f.vaultConfig.Name,
+		"type", kind,
+		"id", requestId.String(),
+		"tx_hash", prior.TxHash,
+		"submitted_at", prior.Time,
+	)
+
+	for i := 0; i < txWaitTimeout; i++ {
+		receipt, err := withCallTimeout(ctx, f.config.RPCCallTimeout, "TransactionReceipt", func(ctx context.Context) (*types.Receipt, error) {
+			return f.client.TransactionReceipt(ctx, hash)
+		})
+		if err == nil && receipt != nil {
+			if receipt.Status == types.ReceiptStatusSuccessful {
+				logger.Info("Previously submitted fulfillment transaction confirmed", "type", kind, "id", requestId.String(), "tx_hash", prior.TxHash)
+				return hash, true, nil
+			}
+			logger.Warn("Previously submitted fulfillment transaction reverted, sending a new one", "type", kind, "id", requestId.String(), "tx_hash", prior.TxHash)
+			return common.Hash{}, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return common.Hash{}, false, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	logger.Warn("Previously submitted fulfillment transaction not mined, sending a new one",
+		"type", kind,
+		"id", requestId.String(),
+		"tx_hash", prior.TxHash,
+		"timeout_seconds", txWaitTimeout,
+	)
+	return common.Hash{}, false, nil
+}
+
+func (f *Fulfiller) callFulfillWithdrawal(ctx context.Context, logger *slog.Logger, withdrawalId *big.Int, amounts []*big.Int) (common.Hash, error) {
+	data, err := f.packFulfillWithdrawal(withdrawalId, amounts)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("pack call: %w", err)
+	}
+
+	tx, err := f.account.sendTransaction(ctx, f.vaultConfig.Address, big.NewInt(0), data)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("send: %w", err)
+	}
+	f.walSubmitted(gasKindWithdrawal, withdrawalId, tx)
+
+	logger.Info("Fulfill withdrawal transaction sent",
+		"withdrawal_id", withdrawalId.String(),
+		"tx_hash", tx.Hash().Hex(),
+	)
+
+	// Wait for transaction to be mined
+	minedHash, err := f.waitForTransaction(ctx, logger, tx, gasKindWithdrawal)
+	if errors.Is(err, ErrTxReverted) {
+		if reason, settled := f.settledRevert(ctx, data); settled {
+			logger.Info("Fulfill withdrawal reverted because the withdrawal was already settled",
+				"withdrawal_id", withdrawalId.String(),
+				"tx_hash", minedHash.Hex(),
+				"reason", reason,
+			)
+			return minedHash, fmt.Errorf("%w: %v (%s)", errAlreadySettled, err, reason)
+		}
+	}
+	if err != nil {
+		logger.Error("Fulfill withdrawal transaction failed",
+			"withdrawal_id", withdrawalId.String(),
+			"tx_hash", minedHash.Hex(),
+			"error", err,
+		)
+		return minedHash, err
+	}
+
+	logger.Debug("Fulfill withdrawal transaction confirmed",
+		"withdrawal_id", withdrawalId.String(),
+		"tx_hash", minedHash.Hex(),
+	)
+	return minedHash, nil
+}
+
+// calculateWithdrawalValue calls the vault's calculateWithdrawalValue function
+func (f *Fulfiller) calculateWithdrawalValue(ctx context.Context, sharesAmount *big.Int) (*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %v", err)
+	}
+
+	data, err := parsedABI.Pack("calculateWithdrawalValue", sharesAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack data: %v", err)
+	}
+
+	result, err := f.callContract(ctx, "calculateWithdrawalValue", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract: %v", err)
+	}
+
+	var expectedUSDC *big.Int
+	if err := parsedABI.UnpackIntoInterface(&expectedUSDC, "calculateWithdrawalValue", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %v", err)
+	}
+
+	return expectedUSDC, nil
+}
+
+// getTokenBalance gets the balance of a token for a given address
+func (f *Fulfiller) getTokenBalance(ctx context.Context, token common.Address, owner common.Address) (*big.Int, error) {
+	parsedABI, err := ParseERC20ABI()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %v", err)
+	}
+
+	data, err := parsedABI.Pack("balanceOf", owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack data: %v", err)
+	}
+
+	result, err := f.callContract(ctx, "balanceOf", token, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call contract: %v", err)
+	}
+
+	var balance *big.Int
+	if err := parsedABI.UnpackIntoInterface(&balance, "balanceOf", result); err != nil {
+		return nil, fmt.Errorf("failed to unpack result: %v", err)
+	}
+
+	return balance, nil
+}
+
+// approvalKey identifies an ERC20 allowance granted by the fulfiller
+type approvalKey struct {
+	token   common.Address
+	spender common.Address
+}
+
+// ensureTokenApproval ensures spender may pull at least needed of token, approving according to
+// the configured APPROVAL_MODE. In infinite mode a max approval is sent once per (token, spender) and cached;
+// exact and fixed allowances are consumed by fulfillments, so the on-chain allowance is checked every time.
+func (f *Fulfiller) ensureTokenApproval(ctx context.Context, logger *slog.Logger, token, spender common.Address, needed *big.Int) error {
+	mode := f.config.ApprovalMode
+	if mode == "" {
+		mode = approvalModeInfinite
+	}
+	key := approvalKey{token: token, spender: spender}
+
+	// Check if already approved in memory
+	if mode == approvalModeInfinite {
+		f.mu.Lock()
+		if f.approvedTokens[key] {
+			f.mu.Unlock()
+			return nil
+		}
+		f.mu.Unlock()
+	}
+
+	var amount *big.Int
+	switch mode {
+	case approvalModeExact:
+		amount = needed
+	case approvalModeFixed:
+		if needed.Cmp(f.config.ApprovalCap) > 0 {
+			return fmt.Errorf("token %s needs allowance %s above APPROVAL_CAP %s", token.Hex(), needed.String(), f.config.ApprovalCap.String())
+		}
+		amount = f.config.ApprovalCap
+	default:
+		amount = new(big.Int).Set(maxUint256)
+	}
+
+	// Check on-chain allowance
+	allowance, err := f.getAllowance(ctx, token, spender)
+	if err != nil {
+		logger.Warn("Failed to check allowance, will attempt approval anyway",
+			"token", token.Hex(),
+			"spender", spender.Hex(),
+			"error", err,
+		)
+	} else {
+		// Infinite mode only re-approves once a max allowance has been drawn down substantially
+		sufficient := allowance.Cmp(needed) >= 0
+		if mode == approvalModeInfinite {
+			sufficient = allowance.Cmp(minInfiniteAllowance) >= 0
+		}
+
+		if sufficient {
+			logger.Debug("Token already has sufficient allowance, skipping approval",
+				"token", token.Hex(),
+				"allowance", allowance.String(),
+				"approval_mode", mode,
+			)
+			if mode == approvalModeInfinite {
+				f.mu.Lock()
+				f.approvedTokens[key] = true
+				f.mu.Unlock()
+			}
+			return nil
+		}
+
+		logger.Debug("Current allowance insufficient, approving",
+			"token", token.Hex(),
+			"current_allowance", allowance.String(),
+			"approval_mode", mode,
+		)
+	}
+
+	// USDT-style tokens revert when changing a non-zero allowance, so reset it to zero first.
+	// An unknown allowance (failed lookup) is treated as non-zero.
+	nonZero := allowance == nil || allowance.Sign() > 0
+	if nonZero && f.config.ApproveResetTokens[token] {
+		logger.Info("Resetting allowance to zero before approval",
+			"token", token.Hex(),
+			"reason", "configured",
+		)
+		if err := f.sendApproval(ctx, logger, token, spender, big.NewInt(0), mode); err != nil {
+			return fmt.Errorf("reset allowance: %w", err)
+		}
+		nonZero = false
+	}
+
+	err = f.sendApproval(ctx, logger, token, spender, amount, mode)
+	if err != nil && nonZero && errors.Is(err, ErrTxReverted) {
+		// Fall back to reset-then-approve for tokens not listed in APPROVE_RESET_TOKENS
+		logger.Warn("Approval reverted with a non-zero allowance, resetting to zero and retrying",
+			"token", token.Hex(),
+			"reason", "approve_reverted",
+		)
+		if err := f.sendApproval(ctx, logger, token, spender, big.NewInt(0), mode); err != nil {
+			return fmt.Errorf("reset allowance: %w", err)
+		}
+		err = f.sendApproval(ctx, logger, token, spender, amount, mode)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Mark as approved
+	if mode == approvalModeInfinite {
+		f.mu.Lock()
+		f.approvedTokens[key] = true
+		f.mu.Unlock()
+	}
+	return nil
+}
+
+// sendApproval sends approve(spender, amount) for token and waits for it to be mined,
+// unless APPROVAL_CONFIRMATIONS=0 in which case the receipt is left to settleApprovals
+func (f *Fulfiller) sendApproval(ctx context.Context, logger *slog.Logger, token, spender common.Address, amount *big.Int, mode string) error {
+	parsedABI, err := ParseERC20ABI()
+	if err != nil {
+		return fmt.Errorf("parse ERC20 abi: %w", err)
+	}
+	data, err := parsedABI.Pack("approve", spender, amount)
+	if err != nil {
+		return fmt.Errorf("pack 'approve': %w", err)
+	}
+
+	tx, err := f.account.sendTransaction(ctx, token, big.NewInt(0), data)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Approval transaction sent",
+		"token", token.Hex(),
+		"spender", spender.Hex(),
+		"approval_mode", mode,
+		"amount", amount.String(),
+		"tx_hash", tx.Hash().Hex(),
+	)
+
+	if f.config.ApprovalNoWait {
+		// The fulfillment is sent behind it with a higher nonce; settleApprovals checks the receipt afterwards
+		f.mu.Lock()
+		f.pendingApprovals = append(f.pendingApprovals, pendingApproval{key: approvalKey{token: token, spender: spender}, tx: tx})
+		f.mu.Unlock()
+		return nil
+	}
+
+	// Wait for transaction to be mined
+	minedHash, err := f.waitForTransaction(ctx, logger, tx, gasKindApproval)
+	if err != nil {
+		logger.Error("Token approval transaction failed",
+			"token", token.Hex(),
+			"tx_hash", minedHash.Hex(),
+			"error", err,
+		)
+		return err
+	}
+
+	logger.Debug("Approval confirmed",
+		"token", token.Hex(),
+		"approval_mode", mode,
+		"amount", amount.String(),
+		"tx_hash", minedHash.Hex(),
+	)
+	return nil
+}
+
+// pendingApproval is an approval sent without waiting for its receipt
+type pendingApproval struct {
+	key approvalKey
+	tx  *types.Transaction
+}
+
+// settleApprovals waits for approvals sent without confirmation so their gas is recorded and a
+// reverted approval is not left cached as granted. Called once the fulfillment that relied on them is done.
+func (f *Fulfiller) settleApprovals(ctx context.Context, logger *slog.Logger) {
+	f.mu.Lock()
+	pending := f.pendingApprovals
+	f.pendingApprovals = nil
+	f.mu.Unlock()
+
+	for _, approval := range pending {
+		if minedHash, err := f.waitForTransaction(ctx, logger, approval.tx, gasKindApproval); err != nil {
+			logger.Error("Unconfirmed token approval failed",
+				"token", approval.key.token.Hex(),
+				"spender", approval.key.spender.Hex(),
+				"tx_hash", minedHash.Hex(),
+				"error", err,
+			)
+			f.mu.Lock()
+			delete(f.approvedTokens, approval.key)
+			f.mu.Unlock()
+		}
+	}
+}
+
+// getAllowance checks the on-chain allowance the fulfiller has granted spender for a token
+func (f *Fulfiller) getAllowance(ctx context.Context, token, spender common.Address) (*big.Int, error) {
+	parsedABI, err := ParseERC20ABI()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parsedABI.Pack("allowance", f.account.fromAddress, spender)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "allowance", token, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var allowance *big.Int
+	err = parsedABI.UnpackIntoInterface(&allowance, "allowance", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return allowance, nil
+}
+
+func (f *Fulfiller) callFulfillDeposit(ctx context.Context, logger *slog.Logger, depositId *big.Int, amounts []*big.Int) (common.Hash, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+
+	data, err := parsedABI.Pack("fulfillDeposit", depositId, amounts)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	tx, err := f.account.sendTransaction(ctx, f.vaultConfig.Address, big.NewInt(0), data)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	f.walSubmitted(gasKindDeposit, depositId, tx)
+
+	logger.Info("Fulfill deposit transaction sent",
+		"deposit_id", depositId.String(),
+		"tx_hash", tx.Hash().Hex(),
+	)
+
+	// Wait for transaction to be mined
+	minedHash, err := f.waitForTransaction(ctx, logger, tx, gasKindDeposit)
+	if errors.Is(err, ErrTxReverted) {
+		if reason, settled := f.settledRevert(ctx, data); settled {
+			logger.Info("Fulfill deposit reverted because the deposit was already settled",
+				"deposit_id", depositId.String(),
+				"tx_hash", minedHash.Hex(),
+				"reason", reason,
+			)
+			return minedHash, fmt.Errorf("%w: %v (%s)", errAlreadySettled, err, reason)
+		}
+	}
+	if err != nil {
+		logger.Debug("Fulfill deposit transaction failed",
+			"deposit_id", depositId.String(),
+			"tx_hash", minedHash.Hex(),
+			"error", err,
+		)
+		return minedHash, err
+	}
+
+	logger.Debug("Fulfill deposit transaction confirmed",
+		"deposit_id", depositId.String(),
+		"tx_hash", minedHash.Hex(),
+	)
+	return minedHash, nil
+}
+
+// getChainID returns the cached chain id, fetching it once if main did not set it
+func (f *fulfillerAccount) getChainID(ctx context.Context) (*big.Int, error) {
+	f.mu.Lock()
+	chainID := f.chainID
+	f.mu.Unlock()
+	if chainID != nil {
+		return chainID, nil
+	}
+
+	chainID, err := withCallTimeout(ctx, f.callTimeout, "NetworkID", f.client.NetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("get network ID: %w", err)
+	}
+	f.mu.Lock()
+	f.chainID = chainID
+	f.mu.Unlock()
+	return chainID, nil
+}
+
+// suggestGasPrice returns the node's suggested gas price, reusing the last one for up to gasPriceCache
+func (f *fulfillerAccount) suggestGasPrice(ctx context.Context) (*big.Int, error) {
+	f.mu.Lock()
+	if f.gasPrice != nil && time.Since(f.gasPriceAt) < f.gasPriceCache {
+		gasPrice := f.gasPrice
+		f.mu.Unlock()
+		return gasPrice, nil
+	}
+	f.mu.Unlock()
+
+	gasPrice, err := withCallTimeout(ctx, f.callTimeout, "SuggestGasPrice", f.client.SuggestGasPrice)
+	if err != nil {
+		return nil, fmt.Errorf("get gas price: %w", err)
+	}
+	f.mu.Lock()
+	f.gasPrice = gasPrice
+	f.gasPriceAt = time.Now()
+	f.mu.Unlock()
+	return gasPrice, nil
+}
+
+func (f *fulfillerAccount) sendTransaction(ctx context.Context, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	gasPrice, err := f.suggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Rather than overpay during a gas spike, leave the request to be retried once gas drops
+	if f.maxGasPrice != nil && gasPrice.Cmp(f.maxGasPrice) > 0 {
+		Logger.Warn("Gas price above maximum, deferring transaction",
+			"to", to.Hex(),
+			"gas_price_gwei", formatGwei(gasPrice),
+			"max_gas_price_gwei", formatGwei(f.maxGasPrice),
+		)
+		return nil, fmt.Errorf("%w: %s gwei > %s gwei", ErrGasPriceTooHigh, formatGwei(gasPrice), formatGwei(f.maxGasPrice))
+	}
+
+	nonce, err := f.reserveNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := f.signAndSend(ctx, nonce, gasPrice, to, value, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist the highest nonce in use; sends reserved concurrently may complete out of order
+	f.mu.Lock()
+	next := nonce + 1
+	if f.nonceStore != nil && next > f.savedNonce {
+		if err := f.nonceStore.Save(f.fromAddress, next); err != nil {
+			Logger.Warn("Failed to persist nonce", "nonce", next, "error", err)
+		} else {
+			f.savedNonce = next
+		}
+	}
+	f.mu.Unlock()
+
+	Logger.Debug("Transaction sent",
+		"tx_hash", signedTx.Hash().Hex(),
+		"to", to.Hex(),
+		"nonce", nonce,
+		"gas_price", signedTx.GasPrice().String(),
+	)
+
+	return signedTx, nil
+}
+
+// reserveNonce hands out the next nonce. Concurrent callers get unique, increasing nonces, and
+// only the first of them fetches the starting nonce from the network.
+func (f *fulfillerAccount) reserveNonce(ctx context.Context) (uint64, error) {
+	f.nonceInit.Lock()
+	defer f.nonceInit.Unlock()
+
+	f.mu.Lock()
+	if f.nonce == nil {
+		// First transaction - fetch nonce from network
+		f.mu.Unlock() // Unlock while making network call; nonceInit keeps other senders waiting
+		fetchedNonce, err := withCallTimeout(ctx, f.callTimeout, "PendingNonceAt", func(ctx context.Context) (uint64, error) {
+			return f.client.PendingNonceAt(ctx, f.fromAddress)
+		})
+		if err != nil {
+			Logger.Error("Failed to fetch nonce", "error", err)
+			return 0, err
+		}
+		f.mu.Lock() // Re-lock to update nonce
+		initial := f.reconcileNonce(fetchedNonce)
+		f.nonce = &initial
+		f.released = nil
+		f.savedNonce = 0 // The fetched nonce supersedes the persisted one, even if lower
+		Logger.Debug("Fetched initial nonce", "nonce", initial)
+	}
+	// Fill gaps left by released nonces before advancing, or later transactions never mine
+	if len(f.released) > 0 {
+		nonce := f.released[0]
+		f.released = f.released[1:]
+		f.mu.Unlock()
+		return nonce, nil
+	}
+	nonce := *f.nonce
+	next := nonce + 1
+	f.nonce = &next
+	f.mu.Unlock()
+	return nonce, nil
+}
+
+// releaseNonce gives back a reserved nonce whose transaction was not sent, so it is handed out
+// again. If later nonces are in flight it is kept in the released list; refetching the pending
+// nonce instead would return it again and then reuse the nonces after it.
+func (f *fulfillerAccount) releaseNonce(nonce uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.nonce == nil || nonce >= *f.nonce {
+		return // the tracker was reset since, the next send refetches it from the network
+	}
+	if *f.nonce != nonce+1 {
+		f.released = append(f.released, nonce)
+		sort.Slice(f.released, func(i, j int) bool { return f.released[i] < f.released[j] })
+		return
+	}
+
+	// The latest reservation: step back, over any released nonces just below it too
+	f.nonce = &nonce
+	for n := len(f.released); n > 0 && f.released[n-1] == *f.nonce-1; n-- {
+		prev := *f.nonce - 1
+		f.nonce = &prev
+		f.released = f.released[:n-1]
+	}
+}
+
+// signAndSend signs and broadcasts a transaction at a reserved nonce, releasing the nonce on failure
+func (f *fulfillerAccount) signAndSend(ctx context.Context, nonce uint64, gasPrice *big.Int, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	chainID, err := f.getChainID(ctx)
+	if err != nil {
+		f.releaseNonce(nonce)
+		return nil, err
+	}
+
+	tx := types.NewTransaction(nonce, to, value, 8000000, gasPrice, data)
+
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), f.privateKey)
+	if err != nil {
+		f.releaseNonce(nonce)
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	err = f.client.SendTransaction(ctx, signedTx)
+	if err != nil {
+		// Check if it's a nonce error - reset nonce tracker to resync
+		if strings.Contains(err.Error(), "nonce too low") || strings.Contains(err.Error(), "replacement transaction underpriced") {
+			Logger.Warn("Nonce error detected, resetting nonce tracker",
+				"error", err,
+				"nonce", nonce,
+			)
+			f.mu.Lock()
+			f.nonce = nil    // Reset to force fresh fetch on next transaction
+			f.gasPrice = nil // An underpriced send must not reuse the cached price
+			f.released = nil
+			f.mu.Unlock()
+			return nil, fmt.Errorf("%w: %v", ErrNonceConflict, err)
+		}
+		f.releaseNonce(nonce)
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// reconcileNonce returns the nonce to use after a fresh network fetch. On the first fetch after
+// startup the persisted nonce wins if it is higher, since the node may not (yet) see transactions
+// sent just before a restart. Later fetches (after a nonce error) trust the network.
+// Must be called with f.mu held.
+func (f *fulfillerAccount) reconcileNonce(pending uint64) uint64 {
+	if f.nonceStore == nil || f.reconciled {
+		return pending
+	}
+	f.reconciled = true
+
+	stored, ok, err := f.nonceStore.Load(f.fromAddress)
+	if err != nil {
+		Logger.Warn("Failed to load persisted nonce, using network nonce", "error", err)
+		return pending
+	}
+	if ok && stored > pending {
+		Logger.Info("Using persisted nonce ahead of network pending nonce",
+			"persisted_nonce", stored,
+			"pending_nonce", pending,
+		)
+		return stored
+	}
+	return pending
+}
+
+// waitForTransaction waits for tx to be mined and attributes its gas cost to kind. With MAX_REPLACEMENTS
+// set, a transaction pending for MIN_MEMPOOL_SECONDS is replaced with a higher gas price; the hash of
+// whichever version was mined (or the last one sent) is returned. If ctx is cancelled, as on
+// shutdown, it stops waiting and returns ctx.Err(); the transaction may still be mined.
+func (f *Fulfiller) waitForTransaction(ctx context.Context, logger *slog.Logger, tx *types.Transaction, kind string) (common.Hash, error) {
+	sent := []*types.Transaction{tx} // every version at this nonce; any of them may be mined
+	lastSent := time.Now()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	// Wait for transaction to be mined (with simple polling)
+	for i := 0; i < txWaitTimeout; i++ {
+		for _, candidate := range sent {
+			receipt, err := withCallTimeout(ctx, f.config.RPCCallTimeout, "TransactionReceipt", func(ctx context.Context) (*types.Receipt, error) {
+				return f.client.TransactionReceipt(ctx, candidate.Hash())
+			})
+			if err != nil || receipt == nil {
+				continue
+			}
+			f.account.recordTxMined()
+
+			// Reverted transactions still pay for the gas they used
+			cost := txGasCost(receipt, candidate)
+			f.gas.record(kind, receipt.GasUsed, cost)
+
+			if receipt.Status == 0 {
+				logger.Error("Transaction reverted",
+					"tx_hash", candidate.Hash().Hex(),
+					"block", receipt.BlockNumber.Uint64(),
+					"kind", kind,
+					"gas_cost_wei", cost.String(),
+				)
+				return candidate.Hash(), fmt.Errorf("%w: %s", ErrTxReverted, candidate.Hash().Hex())
+			}
+			// Transaction successful - add small delay to ensure node state updates
+			logger.Debug("Transaction mined successfully",
+				"tx_hash", candidate.Hash().Hex(),
+				"block", receipt.BlockNumber.Uint64(),
+				"gas_used", receipt.GasUsed,
+				"kind", kind,
+				"gas_cost_wei", cost.String(),
+			)
+			select {
+			case <-ctx.Done(): // mined; shutdown need not wait for the node to catch up
+			case <-time.After(txSyncDelay):
+			}
+			return candidate.Hash(), nil
+		}
+
+		// Still pending: replace it with a higher gas price once it has waited long enough
+		if len(sent) <= f.config.MaxReplacements && time.Since(lastSent) >= f.config.MinMempoolTime {
+			stuck := sent[len(sent)-1]
+			replacement, err := f.account.replaceTransaction(ctx, stuck)
+			switch {
+			case errors.Is(err, ErrGasPriceTooHigh):
+				// Logged by replaceTransaction; keep waiting for the stuck transaction
+			case err != nil:
+				logger.Warn("Failed to replace stuck transaction",
+					"tx_hash", stuck.Hash().Hex(),
+					"error", err,
+				)
+			default:
+				sent = append(sent, replacement)
+				logger.Info("Replaced stuck transaction with a higher gas price",
+					"tx_hash", stuck.Hash().Hex(),
+					"replacement_tx_hash", replacement.Hash().Hex(),
+					"replacement", len(sent)-1,
+					"max_replacements", f.config.MaxReplacements,
+					"gas_price", replacement.GasPrice().String(),
+				)
+			}
+			lastSent = time.Now()
+		}
+
+		// Transaction not yet mined, wait and retry
+		select {
+		case <-ctx.Done():
+			last := sent[len(sent)-1]
+			logger.Warn("Stopped waiting for transaction",
+				"tx_hash", last.Hash().Hex(),
+				"kind", kind,
+				"reason", ctx.Err(),
+			)
+			return last.Hash(), ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	last := sent[len(sent)-1]
+	logger.Error("Transaction timeout",
+		"tx_hash", last.Hash().Hex(),
+		"timeout_seconds", txWaitTimeout,
+		"replacements", len(sent)-1,
+	)
+	f.account.recordTxTimeout(ctx)
+	return last.Hash(), fmt.Errorf("%w: %s", ErrTxTimeout, last.Hash().Hex())
+}
+
+func (f *Fulfiller) GetNextDepositId(ctx context.Context) (*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+
+	data, err := parsedABI.Pack("nextDepositId")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "nextDepositId", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextDepositId *big.Int
+	err = parsedABI.UnpackIntoInterface(&nextDepositId, "nextDepositId", result)
+	if err != nil {
+		return nil, fmt.Errorf("unpack: %w", err)
+	}
+
+	return nextDepositId, nil
+}
+
+func (f *Fulfiller) GetPendingDeposit(ctx context.Context, depositId *big.Int) (*PendingDeposit, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+
+	data, err := parsedABI.Pack("pendingDeposits", depositId)
+	if err != nil {
+		return nil, fmt.Errorf("pack pendingDeposits: %w", err)
+	}
+
+	result, err := f.callContract(ctx, "pendingDeposits", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var deposit PendingDeposit
+	err = parsedABI.UnpackIntoInterface(&[]interface{}{
+		&deposit.User,
+		&deposit.QuoteAmount,
+		&deposit.Fulfilled,
+		&deposit.Timestamp,
+	}, "pendingDeposits", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deposit, nil
+}
+
+// Wait waits for all in-flight fulfillments to complete
+func (f *Fulfiller) Wait() {
+	f.wg.Wait()
+}
+
+func (f *Fulfiller) Close() {
+	f.client.Close()
+}
+
+// setProcessedBlock records the last block the vault's listener has fully processed, for GET /status
+func (f *Fulfiller) setProcessedBlock(block uint64) {
+	f.processedBlock.Store(block)
+}
+
+// loadUnderlyingTokens fetches underlying tokens and weights from the vault
+func (f *Fulfiller) loadUnderlyingTokens(ctx context.Context) error {
+	tokens, weights, err := f.fetchUnderlyingTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	f.setUnderlying(tokens, weights)
+
+	Logger.Debug("Loaded underlying tokens from vault",
+		"token_count", len(tokens),
+	)
+	for i, token := range tokens {
+		Logger.Debug("Underlying token",
+			"index", i,
+			"address", token.Hex(),
+			"weight", weights[i].String(),
+		)
+	}
+
+	return nil
+}
+
+// setUnderlying replaces the cached tokens, target weights and their total.
+// Decimals for every token must already be in tokenDecimals once fulfillments run.
+func (f *Fulfiller) setUnderlying(tokens []common.Address, weights []*big.Int) {
+	totalWeight := big.NewInt(0)
+	for _, weight := range weights {
+		totalWeight = new(big.Int).Add(totalWeight, weight)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.underlyingTokens = tokens
+	f.underlyingWeights = weights
+	f.totalWeight = totalWeight
+}
+
+// underlyingSet is a consistent snapshot of the vault's underlying tokens
+type underlyingSet struct {
+	tokens      []common.Address
+	weights     []*big.Int
+	decimals    []uint8
+	totalWeight *big.Int
+}
+
+// underlying returns a snapshot of the cached tokens, weights and decimals
+func (f *Fulfiller) underlying() underlyingSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	decimals := make([]uint8, len(f.underlyingTokens))
+	for i, token := range f.underlyingTokens {
+		decimals[i] = f.tokenDecimals[token]
+	}
+	return underlyingSet{
+		tokens:      f.underlyingTokens,
+		weights:     f.underlyingWeights,
+		decimals:    decimals,
+		totalWeight: f.totalWeight,
+	}
+}
+
+// fetchUnderlyingTokens reads the underlying tokens and their target weights from the vault
+func (f *Fulfiller) fetchUnderlyingTokens(ctx context.Context) ([]common.Address, []*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fetch tokens by index until we get an error (end of array)
+	var tokens []common.Address
+	var weights []*big.Int
+
+	for i := uint64(0); ; i++ {
+		// Try to fetch token at index i
+		tokenData, err := parsedABI.Pack("underlyingTokens", new(big.Int).SetUint64(i))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tokenResult, err := f.callContract(ctx, "underlyingTokens", f.vaultConfig.Address, tokenData)
+		if err != nil {
+			// A stalled node must not be mistaken for the end of the array
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, nil, err
+			}
+			// End of array reached
+			break
+		}
+
+		var token common.Address
+		err = parsedABI.UnpackIntoInterface(&token, "underlyingTokens", tokenResult)
+		if err != nil {
+			break
+		}
+
+		// Check for zero address (end of valid tokens)
+		if token == (common.Address{}) {
+			break
+		}
+
+		tokens = append(tokens, token)
+
+		// Fetch weight for this token
+		weightData, err := parsedABI.Pack("targetWeights", token)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to pack targetWeights for token %s: %v", token.Hex(), err)
+		}
+
+		weightResult, err := f.callContract(ctx, "targetWeights", f.vaultConfig.Address, weightData)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to call targetWeights for token %s: %v", token.Hex(), err)
+		}
+
+		var weight *big.Int
+		err = parsedABI.UnpackIntoInterface(&weight, "targetWeights", weightResult)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to unpack targetWeights for token %s: %v", token.Hex(), err)
+		}
+
+		weights = append(weights, weight)
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("no underlying tokens found in vault")
+	}
+
+	return tokens, weights, nil
+}
+
+// callContract performs a read-only call against `to`, bounded by the configured RPC call timeout
+func (f *Fulfiller) callContract(ctx context.Context, method string, to common.Address, data []byte) ([]byte, error) {
+	return withCallTimeout(ctx, f.config.RPCCallTimeout, method, func(ctx context.Context) ([]byte, error) {
+		return f.client.CallContract(ctx, ethereum.CallMsg{
+			To:   &to,
+			Data: data,
+		}, nil)
+	})
+}
+
+// getOracleAddress fetches the oracle address from the vault
+func (f *Fulfiller) getOracleAddress(ctx context.Context) (common.Address, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	data, err := parsedABI.Pack("oracle")
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	result, err := f.callContract(ctx, "oracle", f.vaultConfig.Address, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var oracleAddr common.Address
+	err = parsedABI.UnpackIntoInterface(&oracleAddr, "oracle", result)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return oracleAddr, nil
+}
+
+// getTokenPrice fetches the price of a token from the oracle (returns price with oracle decimals).
+// Zero and negative prices are rejected with ErrInvalidPrice.
+func (f *Fulfiller) getTokenPrice(ctx context.Context, token common.Address) (*big.Int, error) {
+	if price, ok := f.config.PriceOverrides[token]; ok {
+		Logger.Warn("PRICE OVERRIDE ACTIVE - not using the oracle price",
+			"vault_name", f.vaultConfig.Name,
+			"token", token.Hex(),
+			"price", price.String(),
+		)
+		return new(big.Int).Set(price), nil
+	}
+	price, err := oraclePriceSource{f: f}.Price(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	f.prices.observe(token, price)
+	return price, nil
+}
+
+// oracleValue sums the vault oracle's getValue over the non-zero amounts, as fulfillDeposit does.
+// The oracle reads token decimals and prices itself, so this values the amounts independently of
+// the engine's cached decimals, price overrides and per-token feeds.
+func (f *Fulfiller) oracleValue(ctx context.Context, tokens []common.Address, amounts []*big.Int) (*big.Int, error) {
+	parsedABI, err := ParseOracleABI()
+	if err != nil {
+		return nil, err
+	}
+
+	total := big.NewInt(0)
+	for i, amount := range amounts {
+		if amount.Sign() <= 0 {
+			continue
+		}
+		data, err := parsedABI.Pack("getValue", tokens[i], amount)
+		if err != nil {
+			return nil, err
+		}
+		result, err := f.callContract(ctx, "getValue", f.oracleAddress, data)
+		if err != nil {
+			return nil, fmt.Errorf("getValue for token %s: %v", tokens[i].Hex(), err)
+		}
+		var value *big.Int
+		if err := parsedABI.UnpackIntoInterface(&value, "getValue", result); err != nil {
+			return nil, err
+		}
+		total.Add(total, value)
+	}
+	return total, nil
+}
+
+// fulfillmentPrice is getTokenPrice for pricing a fulfillment: with PRICE_CHECK_URL set, the
+// oracle price is cross-checked against the off-chain price and rejected with ErrPriceDeviation
+// if they differ by more than MAX_PRICE_DEVIATION_BPS. An unavailable price check also fails.
+func (f *Fulfiller) fulfillmentPrice(ctx context.Context, logger *slog.Logger, token common.Address) (*big.Int, error) {
+	price, err := f.getTokenPrice(ctx, token)
+	if err != nil || f.priceCheck == nil {
+		return price, err
+	}
+	if _, ok := f.config.PriceOverrides[token]; ok {
+		return price, nil // staging price, nothing to cross-check
+	}
+
+	reference, err := f.priceCheck.Price(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("price check for token %s failed: %v", token.Hex(), err)
+	}
+	deviation := priceDeviationBps(price, reference)
+	if deviation <= f.config.MaxPriceDeviationBps {
+		return price, nil
+	}
+
+	logger.Error("Oracle price deviates from price check, aborting fulfillment",
+		"vault_name", f.vaultConfig.Name,
+		"token", token.Hex(),
+		"oracle_price", price.String(),
+		"check_price", reference.String(),
+		"deviation_bps", deviation,
+		"max_deviation_bps", f.config.MaxPriceDeviationBps,
+	)
+	if f.claimPriceAlert(token, time.Now()) {
+		sendPriceAlert(f.config.AlertWebhookURL, PriceDeviationAlert{
+			Text: fmt.Sprintf("Price deviation: oracle price %s of token %s is %d bps from the price check %s, fulfillments for vault %s aborted",
+				price.String(), token.Hex(), deviation, reference.String(), f.vaultConfig.Name),
+			VaultName:    f.vaultConfig.Name,
+			Vault:        f.vaultConfig.Address.Hex(),
+			Token:        token.Hex(),
+			OraclePrice:  price.String(),
+			CheckPrice:   reference.String(),
+			DeviationBps: deviation,
+		})
+	}
+	return nil, fmt.Errorf("%w: token %s oracle %s, check %s (%d bps, max %d)",
+		ErrPriceDeviation, token.Hex(), price.String(), reference.String(), deviation, f.config.MaxPriceDeviationBps)
+}
+
+// claimPriceAlert reports whether a deviation alert for token is due, and records it as sent
+func (f *Fulfiller) claimPriceAlert(token common.Address, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if last, ok := f.priceAlerts[token]; ok && now.Sub(last) < priceAlertCooldown {
+		return false
+	}
+	if f.priceAlerts == nil {
+		f.priceAlerts = make(map[common.Address]time.Time)
+	}
+	f.priceAlerts[token] = now
+	return true
+}
+
+// getOracleDecimals fetches the decimals from the oracle
+// With per-token feeds, every feed must report the same decimals.
+func (f *Fulfiller) getOracleDecimals(ctx context.Context) (uint8, error) {
+	oracle := f.config.Oracle
+	parsedABI, err := oracle.ParseABI()
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := parsedABI.Pack(oracle.DecimalsFn)
+	if err != nil {
+		return 0, err
+	}
+
+	sources := []common.Address{f.oracleAddress}
+	if oracle.PerTokenFeed {
+		sources = sources[:0]
+		for _, token := range f.underlyingTokens {
+			source, err := oracle.priceSource(f.oracleAddress, f.config.OracleFeeds, token)
+			if err != nil {
+				return 0, err
+			}
+			sources = append(sources, source)
+		}
+	}
+
+	var decimals uint8
+	for i, source := range sources {
+		result, err := f.callContract(ctx, oracle.DecimalsFn, source, data)
+		if err != nil {
+			if isCallRevert(err) {
+				return 0, fmt.Errorf("%w: %s() on %s reverted: %v", errCallUnsupported, oracle.DecimalsFn, source.Hex(), err)
+			}
+			return 0, err
+		}
+		if len(result) == 0 {
+			return 0, fmt.Errorf("%w: %s() on %s returned no data", errCallUnsupported, oracle.DecimalsFn, source.Hex())
+		}
+
+		var sourceDecimals uint8
+		err = parsedABI.UnpackIntoInterface(&sourceDecimals, oracle.DecimalsFn, result)
+		if err != nil {
+			return 0, err
+		}
+
+		if i > 0 && sourceDecimals != decimals {
+			return 0, fmt.Errorf("oracle feed %s has %d decimals, expected %d", source.Hex(), sourceDecimals, decimals)
+		}
+		decimals = sourceDecimals
+	}
+
+	return decimals, nil
+}
+
+// getQuoteTokenAddress fetches the quote token address from the vault
+func (f *Fulfiller) getQuoteTokenAddress(ctx context.Context) (common.Address, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	data, err := parsedABI.Pack("QUOTE_TOKEN")
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	result, err := f.callContract(ctx, "QUOTE_TOKEN", f.vaultConfig.Address, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var quoteToken common.Address
+	err = parsedABI.UnpackIntoInterface(&quoteToken, "QUOTE_TOKEN", result)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return quoteToken, nil
+}
+
+// getTokenDecimals fetches the decimals for an ERC20 token
+func (f *Fulfiller) getTokenDecimals(ctx context.Context, token common.Address) (uint8, error) {
+	parsedABI, err := ParseERC20ABI()
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := parsedABI.Pack("decimals")
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := f.callContract(ctx, "decimals", token, data)
+	if err != nil {
+		return 0, err
+	}
+
+	var decimals uint8
+	err = parsedABI.UnpackIntoInterface(&decimals, "decimals", result)
+	if err != nil {
+		return 0, err
+	}
+
+	return decimals, nil
+}
+
+// getVaultBalances fetches the current balances of all underlying tokens in the vault, keyed by token
+func (f *Fulfiller) getVaultBalances(ctx context.Context) (map[common.Address]*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parsedABI.Pack("getVaultBalances")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "getVaultBalances", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Tokens   []common.Address
+		Balances []*big.Int
+	}
+
+	err = parsedABI.UnpackIntoInterface(&output, "getVaultBalances", result)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output.Tokens) != len(output.Balances) {
+		return nil, fmt.Errorf("getVaultBalances returned %d tokens but %d balances", len(output.Tokens), len(output.Balances))
+	}
+
+	balances := make(map[common.Address]*big.Int, len(output.Tokens))
+	for i, token := range output.Tokens {
+		balances[token] = output.Balances[i]
+	}
+	return balances, nil
+}
+
+// getTotalValue fetches the vault's total NAV (in oracle decimals)
+func (f *Fulfiller) getTotalValue(ctx context.Context) (*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parsedABI.Pack("getTotalValue")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "getTotalValue", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalValue *big.Int
+	err = parsedABI.UnpackIntoInterface(&totalValue, "getTotalValue", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return totalValue, nil
+}
+
+// getSectorTokenAddress fetches the vault's share token address
+func (f *Fulfiller) getSectorTokenAddress(ctx context.Context) (common.Address, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	data, err := parsedABI.Pack("SECTOR_TOKEN")
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	result, err := f.callContract(ctx, "SECTOR_TOKEN", f.vaultConfig.Address, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var sectorTokenAddr common.Address
+	err = parsedABI.UnpackIntoInterface(&sectorTokenAddr, "SECTOR_TOKEN", result)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return sectorTokenAddr, nil
+}
+
+// getSectorTokenTotalSupply fetches the total supply of sector tokens
+func (f *Fulfiller) getSectorTokenTotalSupply(ctx context.Context) (*big.Int, error) {
+	sectorTokenAddr, err := f.getSectorTokenAddress(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedERC20ABI, err := ParseERC20ABI()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := parsedERC20ABI.Pack("totalSupply")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "totalSupply", sectorTokenAddr, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalSupply *big.Int
+	err = parsedERC20ABI.UnpackIntoInterface(&totalSupply, "totalSupply", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return totalSupply, nil
+}
+
+func (f *Fulfiller) GetNextWithdrawalId(ctx context.Context) (*big.Int, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+
+	data, err := parsedABI.Pack("nextWithdrawalId")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "nextWithdrawalId", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var nextWithdrawalId *big.Int
+	err = parsedABI.UnpackIntoInterface(&nextWithdrawalId, "nextWithdrawalId", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return nextWithdrawalId, nil
+}
+
+func (f *Fulfiller) GetPendingWithdrawal(ctx context.Context, withdrawalId *big.Int) (*PendingWithdrawal, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+
+	data, err := parsedABI.Pack("pendingWithdrawals", withdrawalId)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, "pendingWithdrawals", f.vaultConfig.Address, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawal PendingWithdrawal
+	err = parsedABI.UnpackIntoInterface(&[]interface{}{
+		&withdrawal.User,
+		&withdrawal.SharesAmount,
+		&withdrawal.Fulfilled,
+		&withdrawal.Timestamp,
+	}, "pendingWithdrawals", result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &withdrawal, nil
+}
@@ -0,0 +1,1293 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	txSyncDelay = 0
+}
+
+// mockEthClient answers the contract calls made by the Fulfiller from in-memory state
+// and records every transaction sent.
+type mockEthClient struct {
+	mu sync.Mutex
+
+	prices          map[common.Address]*big.Int // oracle getPrice
+	balances        map[common.Address]*big.Int // ERC20 balanceOf (fulfiller); defaults to a huge balance
+	withdrawalValue *big.Int                    // vault calculateWithdrawalValue
+	pendingNonce    uint64                      // PendingNonceAt
+	confirmedNonce  uint64                      // NonceAt
+	allowances      map[common.Address]*big.Int // ERC20 allowance by token; defaults to an infinite approval
+	resetRequired   map[common.Address]bool     // USDT-style tokens: approve reverts unless the allowance is zero
+
+	reverted map[common.Hash]bool // transactions whose receipt reports a revert
+	settled  map[uint64]bool      // deposit ids pendingDeposits reports as already fulfilled
+
+	underlying    []common.Address            // vault underlyingTokens
+	targetWeights map[common.Address]*big.Int // vault targetWeights
+	decimals      map[common.Address]uint8    // ERC20 decimals
+	noDecimals    map[common.Address]bool     // contracts whose decimals() reverts
+	revertFulfill bool                        // fulfill transactions revert
+	minGasPrice   *big.Int                    // transactions priced below this stay pending
+	gasPriceCalls int                         // SuggestGasPrice calls
+
+	replayRevert []byte // revert data of fulfill calls replayed with eth_call
+	vaultPaused  *bool  // vault paused(); reverts if nil, as for a vault without it
+
+	sent []*types.Transaction
+}
+
+// mockRevertError is a reverted eth_call carrying revert data, like rpc.DataError from a node
+type mockRevertError struct{ data []byte }
+
+func (e mockRevertError) Error() string          { return "execution reverted" }
+func (e mockRevertError) ErrorData() interface{} { return hexutil.Encode(e.data) }
+
+func newMockEthClient() *mockEthClient {
+	return &mockEthClient{
+		prices:     make(map[common.Address]*big.Int),
+		balances:   make(map[common.Address]*big.Int),
+		allowances: make(map[common.Address]*big.Int),
+
+		resetRequired: make(map[common.Address]bool),
+		reverted:      make(map[common.Hash]bool),
+		settled:       make(map[uint64]bool),
+		targetWeights: make(map[common.Address]*big.Int),
+		decimals:      make(map[common.Address]uint8),
+		noDecimals:    make(map[common.Address]bool),
+	}
+}
+
+func mustParse(t testing.TB, parse func() (abi.ABI, error)) abi.ABI {
+	t.Helper()
+	parsed, err := parse()
+	if err != nil {
+		t.Fatalf("parse ABI: %v", err)
+	}
+	return parsed
+}
+
+func (m *mockEthClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oracleABI, _ := ParseOracleABI()
+	erc20ABI, _ := ParseERC20ABI()
+	vaultABI, _ := ParseSectorVaultABI()
+	chainlinkABI, _ := oracleVariants["chainlink"].ParseABI()
+
+	if len(msg.Data) < 4 {
+		return nil, fmt.Errorf("short call data")
+	}
+	selector := msg.Data[:4]
+
+	if method, err := oracleABI.MethodById(selector); err == nil && method.Name == "getPrice" {
+		args, err := method.Inputs.Unpack(msg.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		price, ok := m.prices[args[0].(common.Address)]
+		if !ok {
+			return nil, fmt.Errorf("no price for %s", args[0].(common.Address).Hex())
+		}
+		return method.Outputs.Pack(price)
+	}
+
+	// getValue uses the token's on-chain decimals, like the vault's oracle
+	if method, err := oracleABI.MethodById(selector); err == nil && method.Name == "getValue" {
+		args, err := method.Inputs.Unpack(msg.Data[4:])
+		if err != nil {
+			return nil, err
+		}
+		token, amount := args[0].(common.Address), args[1].(*big.Int)
+		price, ok := m.prices[token]
+		if !ok {
+			return nil, fmt.Errorf("no price for %s", token.Hex())
+		}
+		decimals, ok := m.decimals[token]
+		if !ok {
+			return nil, fmt.Errorf("no decimals for %s", token.Hex())
+		}
+		multiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+		return method.Outputs.Pack(new(big.Int).Div(new(big.Int).Mul(amount, price), multiplier))
+	}
+
+	// Aggregator-style feeds: latestAnswer() on the feed address, priced from prices[feed]
+	if method, err := chainlinkABI.MethodById(selector); err == nil && method.Name == "latestAnswer" {
+		price, ok := m.prices[*msg.To]
+		if !ok {
+			return nil, fmt.Errorf("no price for feed %s", msg.To.Hex())
+		}
+		return method.Outputs.Pack(price)
+	}
+
+	if method, err := vaultABI.MethodById(selector); err == nil {
+		switch method.Name {
+		case "fulfillDeposit", "fulfillWithdrawal":
+			if m.replayRevert != nil {
+				return nil, mockRevertError{data: m.replayRevert}
+			}
+		case "calculateWithdrawalValue":
+			return method.Outputs.Pack(m.withdrawalValue)
+		case "paused":
+			if m.vaultPaused == nil {
+				return nil, fmt.Errorf("execution reverted")
+			}
+			return method.Outputs.Pack(*m.vaultPaused)
+		case "underlyingTokens":
+			args, err := method.Inputs.Unpack(msg.Data[4:])
+			if err != nil {
+				return nil, err
+			}
+			i := args[0].(*big.Int).Uint64()
+			if i >= uint64(len(m.underlying)) {
+				return nil, fmt.Errorf("execution reverted")
+			}
+			return method.Outputs.Pack(m.underlying[i])
+		case "targetWeights":
+			args, err := method.Inputs.Unpack(msg.Data[4:])
+			if err != nil {
+				return nil, err
+			}
+			return method.Outputs.Pack(m.targetWeights[args[0].(common.Address)])
+		case "pendingDeposits":
+			args, err := method.Inputs.Unpack(msg.Data[4:])
+			if err != nil {
+				return nil, err
+			}
+			if m.settled[args[0].(*big.Int).Uint64()] {
+				return method.Outputs.Pack(common.Address{}, big.NewInt(0), false, big.NewInt(0))
+			}
+			return method.Outputs.Pack(common.HexToAddress("0xdead"), big.NewInt(1), false, big.NewInt(1))
+		}
+	}
+
+	if method, err := erc20ABI.MethodById(selector); err == nil {
+		switch method.Name {
+		case "balanceOf":
+			balance, ok := m.balances[*msg.To]
+			if !ok {
+				balance = new(big.Int).Lsh(big.NewInt(1), 200)
+			}
+			return method.Outputs.Pack(balance)
+		case "decimals":
+			if m.noDecimals[*msg.To] {
+				return nil, mockRevertError{}
+			}
+			decimals, ok := m.decimals[*msg.To]
+			if !ok {
+				return nil, fmt.Errorf("no decimals for %s", msg.To.Hex())
+			}
+			return method.Outputs.Pack(decimals)
+		case "allowance":
+			allowance, ok := m.allowances[*msg.To]
+			if !ok {
+				allowance = new(big.Int).Lsh(big.NewInt(1), 255)
+			}
+			return method.Outputs.Pack(allowance)
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected call to %s", msg.To.Hex())
+}
+
+func (m *mockEthClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pendingNonce, nil
+}
+
+func (m *mockEthClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.confirmedNonce, nil
+}
+
+func (m *mockEthClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gasPriceCalls++
+	return big.NewInt(1), nil
+}
+
+func (m *mockEthClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(84532), nil
+}
+
+func (m *mockEthClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, tx)
+
+	// Track approvals so allowance reads reflect them
+	erc20ABI, _ := ParseERC20ABI()
+	if method, err := erc20ABI.MethodById(tx.Data()); err == nil && method.Name == "approve" {
+		args, err := method.Inputs.Unpack(tx.Data()[4:])
+		if err != nil {
+			return err
+		}
+		amount := args[1].(*big.Int)
+		current, ok := m.allowances[*tx.To()]
+		if m.resetRequired[*tx.To()] && ok && current.Sign() > 0 && amount.Sign() > 0 {
+			m.reverted[tx.Hash()] = true
+			return nil
+		}
+		m.allowances[*tx.To()] = amount
+	} else if m.revertFulfill {
+		m.reverted[tx.Hash()] = true
+	}
+	return nil
+}
+
+func (m *mockEthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.minGasPrice != nil {
+		for _, tx := range m.sent {
+			if tx.Hash() == txHash && tx.GasPrice().Cmp(m.minGasPrice) < 0 {
+				return nil, ethereum.NotFound
+			}
+		}
+	}
+	if m.reverted[txHash] {
+		return &types.Receipt{Status: types.ReceiptStatusFailed, BlockNumber: big.NewInt(1)}, nil
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}, nil
+}
+
+func (m *mockEthClient) Close() {}
+
+// lastFulfillAmounts decodes the underlyingAmounts argument of the last fulfill transaction sent
+func (m *mockEthClient) lastFulfillAmounts(t *testing.T, method string) []*big.Int {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.sent) == 0 {
+		t.Fatalf("no transaction sent")
+	}
+	data := m.sent[len(m.sent)-1].Data()
+
+	vaultABI := mustParse(t, ParseSectorVaultABI)
+	args, err := vaultABI.Methods[method].Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("unpack %s: %v", method, err)
+	}
+	return args[1].([]*big.Int)
+}
+
+type testToken struct {
+	decimals uint8
+	weight   int64
+	price    string // oracle price of one whole token, in oracle decimals
+}
+
+func newTestFulfiller(t *testing.T, client *mockEthClient, quoteDecimals, oracleDecimals uint8, tokens []testToken) *Fulfiller {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	f := &Fulfiller{
+		client:      client,
+		config:      &Config{Oracle: defaultOracleVariant},
+		vaultConfig: VaultConfig{Name: "Test", Address: common.HexToAddress("0x00000000000000000000000000000000000000aa")},
+		account: &fulfillerAccount{
+			fromAddress: crypto.PubkeyToAddress(key.PublicKey),
+			privateKey:  key,
+			client:      client,
+		},
+		approvedTokens:    make(map[approvalKey]bool),
+		tokenDecimals:     make(map[common.Address]uint8),
+		oracleAddress:     common.HexToAddress("0x00000000000000000000000000000000000000bb"),
+		oracleDecimals:    oracleDecimals,
+		quoteTokenAddress: common.HexToAddress("0x00000000000000000000000000000000000000cc"),
+		quoteDecimals:     quoteDecimals,
+		gas:               newGasLedger(),
+		latency:           newLatencyRecorder(),
+		prices:            newPriceGauge(),
+	}
+
+	var tokenAddrs []common.Address
+	var weights []*big.Int
+	for i, tok := range tokens {
+		addr := common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+		price, ok := new(big.Int).SetString(tok.price, 10)
+		if !ok {
+			t.Fatalf("bad price %q", tok.price)
+		}
+		client.prices[addr] = price
+		tokenAddrs = append(tokenAddrs, addr)
+		weights = append(weights, big.NewInt(tok.weight))
+		f.tokenDecimals[addr] = tok.decimals
+		client.decimals[addr] = tok.decimals
+		client.underlying = append(client.underlying, addr)
+		client.targetWeights[addr] = big.NewInt(tok.weight)
+	}
+	f.setUnderlying(tokenAddrs, weights)
+
+	return f
+}
+
+// providedValue mirrors oracle.getValue summed over all tokens: sum(amount * price / 10^decimals)
+func providedValue(f *Fulfiller, client *mockEthClient, amounts []*big.Int) *big.Int {
+	total := big.NewInt(0)
+	for i, token := range f.underlyingTokens {
+		multiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(f.tokenDecimals[token])), nil)
+		value := new(big.Int).Div(new(big.Int).Mul(amounts[i], client.prices[token]), multiplier)
+		total.Add(total, value)
+	}
+	return total
+}
+
+// withinContractTolerance reports whether value matches target within the vault's (target/1000)+1 tolerance
+func withinContractTolerance(value, target *big.Int) bool {
+	tolerance := new(big.Int).Add(new(big.Int).Div(target, big.NewInt(1000)), big.NewInt(1))
+	diff := new(big.Int).Abs(new(big.Int).Sub(value, target))
+	return diff.Cmp(tolerance) <= 0
+}
+
+func TestFulfillDepositAmounts(t *testing.T) {
+	tests := []struct {
+		name           string
+		quoteDecimals  uint8
+		oracleDecimals uint8
+		quoteAmount    string
+		tokens         []testToken
+		want           []string // expected underlying amounts, nil to only check tolerance
+	}{
+		{
+			name:           "equal decimals single token",
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			quoteAmount:    "10000000", // 10 USDC
+			tokens:         []testToken{{decimals: 18, weight: 10000, price: "2000000"}},
+			want:           []string{"5000000000000000000"},
+		},
+		{
+			name:           "equal decimals two tokens even split",
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			quoteAmount:    "100000000", // 100 USDC
+			tokens: []testToken{
+				{decimals: 18, weight: 5000, price: "1000000"},
+				{decimals: 8, weight: 5000, price: "50000000000"},
+			},
+			want: []string{"50000000000000000000", "100000"},
+		},
+		{
+			name:           "oracle decimals above quote decimals",
+			quoteDecimals:  6,
+			oracleDecimals: 18,
+			quoteAmount:    "1000000", // 1 USDC
+			tokens: []testToken{
+				{decimals: 18, weight: 7000, price: "3000000000000000000"},
+				{decimals: 6, weight: 3000, price: "1000000000000000000"},
+			},
+		},
+		{
+			name:           "oracle decimals below quote decimals",
+			quoteDecimals:  18,
+			oracleDecimals: 6,
+			quoteAmount:    "25000000000000000000", // 25 quote tokens
+			tokens: []testToken{
+				{decimals: 18, weight: 2500, price: "1234567"},
+				{decimals: 18, weight: 2500, price: "7654321"},
+				{decimals: 6, weight: 5000, price: "999999"},
+			},
+		},
+		{
+			name:           "uneven weights and awkward prices need top-up",
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			quoteAmount:    "1000001",
+			tokens: []testToken{
+				{decimals: 6, weight: 3333, price: "333333"},
+				{decimals: 6, weight: 3333, price: "777777"},
+				{decimals: 8, weight: 3334, price: "999999"},
+			},
+		},
+		{
+			name:           "tiny deposit",
+			quoteDecimals:  6,
+			oracleDecimals: 6,
+			quoteAmount:    "7",
+			tokens: []testToken{
+				{decimals: 18, weight: 6000, price: "1500000"},
+				{decimals: 18, weight: 4000, price: "2500000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockEthClient()
+			f := newTestFulfiller(t, client, tt.quoteDecimals, tt.oracleDecimals, tt.tokens)
+
+			quoteAmount, _ := new(big.Int).SetString(tt.quoteAmount, 10)
+			if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), quoteAmount, time.Time{}); err != nil {
+				t.Fatalf("FulfillDeposit: %v", err)
+			}
+
+			amounts := client.lastFulfillAmounts(t, "fulfillDeposit")
+			if len(amounts) != len(tt.tokens) {
+				t.Fatalf("got %d amounts, want %d", len(amounts), len(tt.tokens))
+			}
+
+			for i, want := range tt.want {
+				if amounts[i].String() != want {
+					t.Errorf("amount[%d] = %s, want %s", i, amounts[i], want)
+				}
+			}
+
+			target := normalizeDecimals(quoteAmount, tt.quoteDecimals, tt.oracleDecimals)
+			value := providedValue(f, client, amounts)
+			if value.Cmp(target) < 0 {
+				t.Errorf("provided value %s below target %s", value, target)
+			}
+			if !withinContractTolerance(value, target) {
+				t.Errorf("provided value %s outside contract tolerance of target %s", value, target)
+			}
+		})
+	}
+}
+
+func TestFulfillDepositInsufficientBalance(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{decimals: 18, weight: 10000, price: "1000000"}})
+	client.balances[f.underlyingTokens[0]] = big.NewInt(1)
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("got error %v, want ErrInsufficientBalance", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions, want 0", len(client.sent))
+	}
+}
+
+func TestFulfillWithdrawalAmounts(t *testing.T) {
+	tests := []struct {
+		name            string
+		quoteDecimals   uint8
+		oracleDecimals  uint8
+		rounding        string
+		withdrawalValue string // in quote decimals
+		tokens          []testToken
+		want            []string
+	}{
+		{
+			name:            "single token",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			withdrawalValue: "10000000",
+			tokens:          []testToken{{decimals: 18, weight: 10000, price: "2000000"}},
+			want:            []string{"5000000000000000000"},
+		},
+		{
+			name:            "two tokens with top-up",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			withdrawalValue: "1000001",
+			tokens: []testToken{
+				{decimals: 6, weight: 5000, price: "333333"},
+				{decimals: 8, weight: 5000, price: "777777"},
+			},
+		},
+		{
+			name:            "oracle decimals above quote decimals",
+			quoteDecimals:   6,
+			oracleDecimals:  18,
+			withdrawalValue: "10000000", // 10 USDC
+			tokens:          []testToken{{decimals: 18, weight: 10000, price: "2000000000000000000"}},
+			want:            []string{"5000000000000000000"},
+		},
+		{
+			name:            "oracle decimals below quote decimals",
+			quoteDecimals:   18,
+			oracleDecimals:  6,
+			withdrawalValue: "25000000000000000000", // 25 quote tokens
+			tokens: []testToken{
+				{decimals: 18, weight: 5000, price: "1234567"},
+				{decimals: 6, weight: 5000, price: "999999"},
+			},
+		},
+		// 10 USDC of a $3 token is 3.333... tokens: 9999999 in value when floored
+		{
+			name:            "floor with top-up rounding",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			rounding:        withdrawalRoundingFloorTopUp,
+			withdrawalValue: "10000000",
+			tokens:          []testToken{{decimals: 18, weight: 10000, price: "3000000"}},
+			want:            []string{"3333333666666666667"}, // topped up by ceil(1 / 3e-12)
+		},
+		{
+			name:            "ceil rounding",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			rounding:        withdrawalRoundingCeil,
+			withdrawalValue: "10000000",
+			tokens:          []testToken{{decimals: 18, weight: 10000, price: "3000000"}},
+			want:            []string{"3333333333333333334"},
+		},
+		{
+			name:            "nearest rounding",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			rounding:        withdrawalRoundingNearest,
+			withdrawalValue: "10000000",
+			tokens:          []testToken{{decimals: 18, weight: 10000, price: "3000000"}},
+			want:            []string{"3333333333333333333"}, // 1 unit short, within tolerance
+		},
+		{
+			name:            "nearest rounding with two tokens",
+			quoteDecimals:   6,
+			oracleDecimals:  6,
+			rounding:        withdrawalRoundingNearest,
+			withdrawalValue: "1000001",
+			tokens: []testToken{
+				{decimals: 6, weight: 5000, price: "333333"},
+				{decimals: 8, weight: 5000, price: "777777"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockEthClient()
+			f := newTestFulfiller(t, client, tt.quoteDecimals, tt.oracleDecimals, tt.tokens)
+			f.config.WithdrawalRounding = tt.rounding
+			client.withdrawalValue, _ = new(big.Int).SetString(tt.withdrawalValue, 10)
+
+			if _, err := f.FulfillWithdrawal(context.Background(), big.NewInt(1), big.NewInt(1), time.Time{}); err != nil {
+				t.Fatalf("FulfillWithdrawal: %v", err)
+			}
+
+			amounts := client.lastFulfillAmounts(t, "fulfillWithdrawal")
+			for i, want := range tt.want {
+				if amounts[i].String() != want {
+					t.Errorf("amount[%d] = %s, want %s", i, amounts[i], want)
+				}
+			}
+
+			// The vault compares the underlying value with the withdrawal value in oracle decimals
+			target := normalizeDecimals(client.withdrawalValue, tt.quoteDecimals, tt.oracleDecimals)
+			value := providedValue(f, client, amounts)
+			if !withinContractTolerance(value, target) {
+				t.Errorf("provided value %s outside contract tolerance of target %s", value, target)
+			}
+		})
+	}
+}
+
+func TestFulfillWithdrawalInsufficientInventory(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{
+		{decimals: 18, weight: 5000, price: "1000000"},
+		{decimals: 18, weight: 5000, price: "1000000"},
+	})
+	client.withdrawalValue = big.NewInt(10000000)
+	// The mock reports balances per token, so this is also the vault's balance
+	client.balances[f.underlyingTokens[1]] = big.NewInt(1)
+
+	_, err := f.FulfillWithdrawal(context.Background(), big.NewInt(1), big.NewInt(1), time.Time{})
+	if !errors.Is(err, ErrInsufficientInventory) {
+		t.Fatalf("got error %v, want ErrInsufficientInventory", err)
+	}
+	if !strings.Contains(err.Error(), f.underlyingTokens[1].Hex()) || strings.Contains(err.Error(), f.underlyingTokens[0].Hex()) {
+		t.Errorf("error %q should name only the short token %s", err, f.underlyingTokens[1].Hex())
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions, want 0", len(client.sent))
+	}
+}
+
+func TestCheckVaultInventoryUsesSnapshot(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{
+		{decimals: 18, weight: 5000, price: "1000000"},
+		{decimals: 18, weight: 5000, price: "1000000"},
+	})
+	tokens := f.underlyingTokens
+	client.balances[tokens[1]] = big.NewInt(1)
+
+	u := f.underlying()
+	amounts, _, err := f.withdrawalAmounts(context.Background(), Logger, u, big.NewInt(1), big.NewInt(10000000))
+	if err != nil {
+		t.Fatalf("withdrawalAmounts: %v", err)
+	}
+
+	// A weight resync replaces the token list before the inventory check
+	f.setUnderlying([]common.Address{common.HexToAddress("0xdddd")}, []*big.Int{big.NewInt(10000)})
+
+	err = f.checkVaultInventory(context.Background(), Logger, u, big.NewInt(1), amounts)
+	if !errors.Is(err, ErrInsufficientInventory) {
+		t.Fatalf("got error %v, want ErrInsufficientInventory", err)
+	}
+	if !strings.Contains(err.Error(), tokens[1].Hex()) {
+		t.Errorf("error %q should name the snapshot's short token %s", err, tokens[1].Hex())
+	}
+}
+
+func TestFulfillWithdrawalQuotePayout(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{decimals: 18, weight: 10000, price: "2000000"}})
+	f.config.WithdrawalPayoutMode = withdrawalPayoutQuote
+	client.withdrawalValue = big.NewInt(10000000)
+
+	if _, err := f.FulfillWithdrawal(context.Background(), big.NewInt(7), big.NewInt(1), time.Time{}); err != nil {
+		t.Fatalf("FulfillWithdrawal: %v", err)
+	}
+
+	vaultABI := mustParse(t, ParseSectorVaultABI)
+	data := client.sent[len(client.sent)-1].Data()
+	method, err := vaultABI.MethodById(data)
+	if err != nil || method.Sig != "fulfillWithdrawal(uint256)" {
+		t.Fatalf("sent %v (err %v), want fulfillWithdrawal(uint256)", method, err)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil || args[0].(*big.Int).Int64() != 7 {
+		t.Errorf("withdrawal id = %v (err %v), want 7", args, err)
+	}
+}
+
+func TestCheckWithdrawalPayout(t *testing.T) {
+	client := newMockEthClient()
+	// Only the underlying variant exists: it reverts with data, the quote overload is an unexpected call
+	client.replayRevert = crypto.Keccak256([]byte("WithdrawalNotFound()"))[:4]
+	f := newTestFulfiller(t, client, 6, 6, nil)
+
+	f.config.WithdrawalPayoutMode = withdrawalPayoutUnderlying
+	if err := f.checkWithdrawalPayout(context.Background()); err != nil {
+		t.Errorf("underlying mode: %v", err)
+	}
+	f.config.WithdrawalPayoutMode = withdrawalPayoutQuote
+	if err := f.checkWithdrawalPayout(context.Background()); err == nil {
+		t.Error("quote mode: expected an error for a vault without the quote payout")
+	}
+}
+
+func TestEnsureTokenApprovalModes(t *testing.T) {
+	token := common.HexToAddress("0x1000")
+	erc20ABI := mustParse(t, ParseERC20ABI)
+
+	// approvals decodes the amounts of the approve transactions sent so far
+	approvals := func(client *mockEthClient) []string {
+		var amounts []string
+		for _, tx := range client.sent {
+			args, err := erc20ABI.Methods["approve"].Inputs.Unpack(tx.Data()[4:])
+			if err != nil {
+				t.Fatalf("unpack approve: %v", err)
+			}
+			amounts = append(amounts, args[1].(*big.Int).String())
+		}
+		return amounts
+	}
+
+	tests := []struct {
+		name      string
+		mode      string
+		cap       int64
+		allowance int64
+		reset     bool // token listed in APPROVE_RESET_TOKENS
+		usdtStyle bool // token reverts approvals over a non-zero allowance
+		needed    []int64
+		want      []string
+		wantErr   bool
+	}{
+		{name: "infinite approves once", mode: approvalModeInfinite, needed: []int64{10, 20}, want: []string{maxUint256.String()}},
+		{name: "exact approves every time", mode: approvalModeExact, needed: []int64{10, 20}, want: []string{"10", "20"}},
+		{name: "exact skips sufficient allowance", mode: approvalModeExact, allowance: 50, needed: []int64{10}},
+		{name: "fixed approves the cap", mode: approvalModeFixed, cap: 1000, needed: []int64{10}, want: []string{"1000"}},
+		{name: "fixed rejects needs above the cap", mode: approvalModeFixed, cap: 1000, needed: []int64{2000}, wantErr: true},
+		{name: "configured reset token", mode: approvalModeExact, allowance: 5, reset: true, usdtStyle: true, needed: []int64{10}, want: []string{"0", "10"}},
+		{name: "reset after approve revert", mode: approvalModeExact, allowance: 5, usdtStyle: true, needed: []int64{10}, want: []string{"10", "0", "10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockEthClient()
+			client.allowances[token] = big.NewInt(tt.allowance)
+			client.resetRequired[token] = tt.usdtStyle
+			f := newTestFulfiller(t, client, 6, 6, nil)
+			f.config.ApprovalMode = tt.mode
+			f.config.ApprovalCap = big.NewInt(tt.cap)
+			f.config.ApproveResetTokens = map[common.Address]bool{token: tt.reset}
+
+			for _, needed := range tt.needed {
+				err := f.ensureTokenApproval(context.Background(), Logger, token, f.vaultConfig.Address, big.NewInt(needed))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ensureTokenApproval(%d) error = %v, wantErr %v", needed, err, tt.wantErr)
+				}
+			}
+			if got := approvals(client); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("approved %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalCacheKeyedBySpender(t *testing.T) {
+	token := common.HexToAddress("0xaaaa")
+	router := common.HexToAddress("0xbbbb")
+
+	client := newMockEthClient()
+	client.allowances[token] = big.NewInt(0)
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.config.ApprovalMode = approvalModeInfinite
+	f.approvedTokens[approvalKey{token: token, spender: f.vaultConfig.Address}] = true
+
+	if err := f.ensureTokenApproval(context.Background(), Logger, token, router, big.NewInt(10)); err != nil {
+		t.Fatalf("ensureTokenApproval: %v", err)
+	}
+	if len(client.sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1 approval for the router", len(client.sent))
+	}
+	erc20ABI := mustParse(t, ParseERC20ABI)
+	args, err := erc20ABI.Methods["approve"].Inputs.Unpack(client.sent[0].Data()[4:])
+	if err != nil {
+		t.Fatalf("unpack approve: %v", err)
+	}
+	if spender := args[0].(common.Address); spender != router {
+		t.Errorf("approved spender %s, want %s", spender.Hex(), router.Hex())
+	}
+	if !f.approvedTokens[approvalKey{token: token, spender: router}] {
+		t.Error("router approval not cached")
+	}
+}
+
+func TestApprovalNoWaitSettlesAfterFulfillment(t *testing.T) {
+	token := common.HexToAddress("0xaaaa")
+
+	client := newMockEthClient()
+	client.allowances[token] = big.NewInt(5)
+	client.resetRequired[token] = true // the approval will revert
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.config.ApprovalMode = approvalModeInfinite
+	f.config.ApprovalNoWait = true
+	key := approvalKey{token: token, spender: f.vaultConfig.Address}
+
+	if err := f.ensureTokenApproval(context.Background(), Logger, token, key.spender, big.NewInt(10)); err != nil {
+		t.Fatalf("ensureTokenApproval: %v", err)
+	}
+	if len(f.pendingApprovals) != 1 {
+		t.Fatalf("%d pending approvals, want 1", len(f.pendingApprovals))
+	}
+	if !f.approvedTokens[key] {
+		t.Fatal("approval not optimistically cached")
+	}
+
+	f.settleApprovals(context.Background(), Logger)
+	if len(f.pendingApprovals) != 0 {
+		t.Errorf("%d pending approvals after settling, want 0", len(f.pendingApprovals))
+	}
+	if f.approvedTokens[key] {
+		t.Error("reverted approval still cached")
+	}
+	if report := f.gas.report(f.vaultConfig.Name); report.ByKind[gasKindApproval].Transactions != 1 {
+		t.Errorf("recorded %d approval transactions, want 1", report.ByKind[gasKindApproval].Transactions)
+	}
+}
+
+func TestResyncWeights(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{
+		{price: "1000000", decimals: 6, weight: 6000},
+		{price: "1000000", decimals: 6, weight: 4000},
+	})
+
+	client.targetWeights[f.underlyingTokens[1]] = big.NewInt(2000)
+	if err := f.resyncWeights(context.Background()); err != nil {
+		t.Fatalf("resyncWeights: %v", err)
+	}
+	u := f.underlying()
+	if u.weights[1].Int64() != 2000 || u.totalWeight.Int64() != 8000 {
+		t.Errorf("weights %v total %s, want [6000 2000] total 8000", u.weights, u.totalWeight)
+	}
+
+}
+
+func TestFulfillDepositRefreshesTokensAfterRevert(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{
+		{price: "1000000", decimals: 6, weight: 6000},
+		{price: "1000000", decimals: 6, weight: 4000},
+	})
+
+	// Governance adds a third token; the stale two-token fulfillment reverts
+	added := common.HexToAddress("0xcccc")
+	client.underlying = append(client.underlying, added)
+	client.targetWeights[added] = big.NewInt(2000)
+	client.decimals[added] = 18
+	client.revertFulfill = true
+
+	_, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1_000000), time.Time{})
+	if !errors.Is(err, ErrTxReverted) {
+		t.Fatalf("FulfillDeposit error = %v, want ErrTxReverted", err)
+	}
+
+	u := f.underlying()
+	if len(u.tokens) != 3 || u.tokens[2] != added {
+		t.Fatalf("cached tokens %v, want the vault's three tokens", u.tokens)
+	}
+	if u.decimals[2] != 18 || u.totalWeight.Int64() != 12000 {
+		t.Errorf("decimals %v total weight %s, want 18 for the new token and 12000", u.decimals, u.totalWeight)
+	}
+}
+
+func TestFulfillDepositRevertedAfterSettlement(t *testing.T) {
+	tests := []struct {
+		name         string
+		replayRevert []byte
+		wantErr      error
+	}{
+		{name: "settled", replayRevert: crypto.Keccak256([]byte("DepositNotFound()"))[:4], wantErr: errAlreadySettled},
+		{name: "other revert", replayRevert: crypto.Keccak256([]byte("FulfillmentValueMismatch()"))[:4], wantErr: ErrTxReverted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockEthClient()
+			f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 6, weight: 10000}})
+			for _, entry := range defaultSettledReverts {
+				matcher, err := parseRevertMatcher(entry)
+				if err != nil {
+					t.Fatalf("parseRevertMatcher(%q): %v", entry, err)
+				}
+				f.config.SettledReverts = append(f.config.SettledReverts, matcher)
+			}
+			client.revertFulfill = true
+			client.replayRevert = tt.replayRevert
+
+			_, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1_000000), time.Time{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FulfillDeposit error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRevertMatcher(t *testing.T) {
+	selector := crypto.Keccak256([]byte("DepositNotFound()"))[:4]
+	for _, entry := range []string{"DepositNotFound()", hexutil.Encode(selector)} {
+		m, err := parseRevertMatcher(entry)
+		if err != nil || !m.matches(selector, "") {
+			t.Errorf("%q does not match its selector (err %v)", entry, err)
+		}
+	}
+	if m, _ := parseRevertMatcher("already fulfilled"); !m.matches(nil, "execution reverted: Deposit already fulfilled") {
+		t.Error("substring matcher does not match the reason")
+	}
+	if _, err := parseRevertMatcher("0x1234"); err == nil {
+		t.Error("expected an error for a short selector")
+	}
+}
+
+func TestWaitForTransactionReplacesStuck(t *testing.T) {
+	client := newMockEthClient()
+	client.minGasPrice = big.NewInt(2) // SuggestGasPrice is 1, so only a bumped replacement is mined
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.config.MaxReplacements = 1
+
+	tx, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatalf("sendTransaction: %v", err)
+	}
+	minedHash, err := f.waitForTransaction(context.Background(), Logger, tx, gasKindDeposit)
+	if err != nil {
+		t.Fatalf("waitForTransaction: %v", err)
+	}
+
+	if len(client.sent) != 2 {
+		t.Fatalf("sent %d transactions, want the original and 1 replacement", len(client.sent))
+	}
+	replacement := client.sent[1]
+	if replacement.Nonce() != tx.Nonce() || replacement.GasPrice().Cmp(tx.GasPrice()) <= 0 {
+		t.Errorf("replacement nonce %d gas price %s, want nonce %d above gas price %s",
+			replacement.Nonce(), replacement.GasPrice(), tx.Nonce(), tx.GasPrice())
+	}
+	if minedHash != replacement.Hash() {
+		t.Errorf("mined hash %s, want the replacement %s", minedHash.Hex(), replacement.Hash().Hex())
+	}
+}
+
+func TestReplaceTransactionAboveMaxGasPrice(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.account.maxGasPrice = big.NewInt(1) // SuggestGasPrice is 1, a bumped replacement 2
+
+	tx, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatalf("sendTransaction: %v", err)
+	}
+	if _, err := f.account.replaceTransaction(context.Background(), tx); !errors.Is(err, ErrGasPriceTooHigh) {
+		t.Fatalf("replaceTransaction error = %v, want ErrGasPriceTooHigh", err)
+	}
+	if len(client.sent) != 1 {
+		t.Errorf("sent %d transactions, want only the original", len(client.sent))
+	}
+}
+
+func TestWaitForTransactionCancelled(t *testing.T) {
+	client := newMockEthClient()
+	client.minGasPrice = big.NewInt(2) // never mined
+	f := newTestFulfiller(t, client, 6, 6, nil)
+
+	tx, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatalf("sendTransaction: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	hash, err := f.waitForTransaction(ctx, Logger, tx, gasKindDeposit)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("waitForTransaction: %v, want the context error", err)
+	}
+	if hash != tx.Hash() {
+		t.Errorf("returned hash %s, want %s", hash.Hex(), tx.Hash().Hex())
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("returned after %s, want promptly after cancellation", elapsed)
+	}
+}
+
+func TestSendTransactionCachesGasPrice(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.account.gasPriceCache = time.Minute
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil); err != nil {
+			t.Fatalf("sendTransaction: %v", err)
+		}
+	}
+	if client.gasPriceCalls != 1 {
+		t.Errorf("SuggestGasPrice called %d times, want 1", client.gasPriceCalls)
+	}
+}
+
+func TestSendTransactionMaxGasPrice(t *testing.T) {
+	client := newMockEthClient()
+	client.pendingNonce = 7
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.account.maxGasPrice = big.NewInt(0) // SuggestGasPrice is 1
+
+	_, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil)
+	if !errors.Is(err, ErrGasPriceTooHigh) {
+		t.Fatalf("got %v, want ErrGasPriceTooHigh", err)
+	}
+	if len(client.sent) != 0 {
+		t.Fatalf("sent %d transactions above the maximum gas price", len(client.sent))
+	}
+
+	f.account.maxGasPrice = big.NewInt(1)
+	tx, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatalf("sendTransaction at the maximum: %v", err)
+	}
+	if tx.Nonce() != 7 {
+		t.Errorf("nonce %d, want 7: a deferred send must not use up a nonce", tx.Nonce())
+	}
+}
+
+func TestSendTransactionConcurrentNonces(t *testing.T) {
+	client := newMockEthClient()
+	client.pendingNonce = 7
+	f := newTestFulfiller(t, client, 6, 6, nil)
+
+	const sends = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, sends)
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.account.sendTransaction(context.Background(), f.vaultConfig.Address, big.NewInt(0), nil); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("sendTransaction: %v", err)
+	}
+
+	// Every nonce from the network's pending nonce on is used exactly once
+	used := make(map[uint64]bool)
+	for _, tx := range client.sent {
+		if used[tx.Nonce()] {
+			t.Fatalf("nonce %d sent twice", tx.Nonce())
+		}
+		used[tx.Nonce()] = true
+	}
+	for nonce := uint64(7); nonce < 7+sends; nonce++ {
+		if !used[nonce] {
+			t.Errorf("nonce %d never sent", nonce)
+		}
+	}
+	if len(client.sent) != sends {
+		t.Errorf("sent %d transactions, want %d", len(client.sent), sends)
+	}
+}
+
+func TestReleaseNonce(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	ctx := context.Background()
+
+	first, _ := f.account.reserveNonce(ctx)
+	second, _ := f.account.reserveNonce(ctx)
+
+	// Releasing the latest reservation hands it out again
+	f.account.releaseNonce(second)
+	if nonce, _ := f.account.reserveNonce(ctx); nonce != second {
+		t.Errorf("reserved %d after releasing the latest nonce, want %d", nonce, second)
+	}
+
+	// Releasing an earlier one while later nonces are in flight hands it out again before advancing
+	f.account.releaseNonce(first)
+	if nonce, _ := f.account.reserveNonce(ctx); nonce != first {
+		t.Errorf("reserved %d after releasing an earlier nonce, want %d", nonce, first)
+	}
+	if nonce, _ := f.account.reserveNonce(ctx); nonce != second+1 {
+		t.Errorf("reserved %d after refilling the gap, want %d", nonce, second+1)
+	}
+
+	// Out-of-order releases: the earlier nonce is released first, then the latest steps back over both
+	third, _ := f.account.reserveNonce(ctx)
+	fourth, _ := f.account.reserveNonce(ctx)
+	f.account.releaseNonce(third)
+	f.account.releaseNonce(fourth)
+	for _, want := range []uint64{third, fourth} {
+		if nonce, _ := f.account.reserveNonce(ctx); nonce != want {
+			t.Errorf("reserved %d after releasing %d and %d, want %d", nonce, third, fourth, want)
+		}
+	}
+	if len(f.account.released) != 0 {
+		t.Errorf("released nonces %v left over, want none", f.account.released)
+	}
+}
+
+func TestStartupValue(t *testing.T) {
+	reads := 0
+	read := func() (uint8, error) {
+		reads++
+		return 18, nil
+	}
+	configured := func(v uint8) *uint8 { return &v }
+
+	if got, err := startupValue(nil, false, "decimals", read); got != 18 || err != nil || reads != 1 {
+		t.Errorf("unset: got %d, %v after %d reads; want the on-chain 18", got, err, reads)
+	}
+
+	reads = 0
+	if got, err := startupValue(configured(6), false, "decimals", read); got != 6 || err != nil || reads != 0 {
+		t.Errorf("configured: got %d, %v after %d reads; want 6 without a read", got, err, reads)
+	}
+
+	if _, err := startupValue(configured(6), true, "decimals", read); err == nil {
+		t.Error("verify accepted a configured value that differs from the chain")
+	}
+	if got, err := startupValue(configured(18), true, "decimals", read); got != 18 || err != nil {
+		t.Errorf("verify: got %d, %v; want the matching 18", got, err)
+	}
+}
+
+func TestLoadOracleDecimalsFallback(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 8, []testToken{{decimals: 18, weight: 10000, price: "1000000"}})
+	client.noDecimals[f.oracleAddress] = true
+
+	f.config.OracleDecimalsFallback = 8
+	if got, err := f.loadOracleDecimals(context.Background()); got != 8 || err != nil {
+		t.Errorf("reverting decimals(): got %d, %v; want the fallback 8", got, err)
+	}
+
+	configured := uint8(6)
+	f.vaultConfig.OracleDecimals = &configured
+	if got, err := f.loadOracleDecimals(context.Background()); got != 6 || err != nil {
+		t.Errorf("configured: got %d, %v; want the configured 6", got, err)
+	}
+
+	f.vaultConfig.OracleDecimals = nil
+	f.config.OracleDecimalsFallback = 0
+	if _, err := f.loadOracleDecimals(context.Background()); !errors.Is(err, errCallUnsupported) {
+		t.Errorf("fallback disabled: got %v, want errCallUnsupported", err)
+	}
+}
+
+func TestValidateDecimals(t *testing.T) {
+	for _, decimals := range []uint8{1, 6, 8, 18, 36} {
+		if err := validateDecimals(decimals); err != nil {
+			t.Errorf("validateDecimals(%d) = %v, want nil", decimals, err)
+		}
+	}
+	for _, decimals := range []uint8{0, 37, 255} {
+		if err := validateDecimals(decimals); err == nil {
+			t.Errorf("validateDecimals(%d) = nil, want error", decimals)
+		}
+	}
+}
+
+func TestGetTokenPriceRejectsNonPositive(t *testing.T) {
+	token := common.HexToAddress("0x1000")
+	feed := common.HexToAddress("0x2000")
+
+	tests := []struct {
+		name    string
+		variant OracleVariant
+		price   int64
+		wantErr bool
+	}{
+		{name: "custom positive", variant: defaultOracleVariant, price: 1_000000},
+		{name: "custom zero", variant: defaultOracleVariant, price: 0, wantErr: true},
+		{name: "chainlink positive", variant: oracleVariants["chainlink"], price: 2_50000000},
+		{name: "chainlink zero", variant: oracleVariants["chainlink"], price: 0, wantErr: true},
+		{name: "chainlink negative", variant: oracleVariants["chainlink"], price: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockEthClient()
+			client.prices[token] = big.NewInt(tt.price)
+			client.prices[feed] = big.NewInt(tt.price)
+			f := newTestFulfiller(t, client, 6, 6, nil)
+			f.config.Oracle = tt.variant
+			f.config.OracleFeeds = map[common.Address]common.Address{token: feed}
+
+			price, err := f.getTokenPrice(context.Background(), token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPrice) {
+					t.Fatalf("getTokenPrice() = %v, %v; want ErrInvalidPrice", price, err)
+				}
+				return
+			}
+			if err != nil || price.Int64() != tt.price {
+				t.Fatalf("getTokenPrice() = %v, %v; want %d", price, err, tt.price)
+			}
+		})
+	}
+}
+
+func TestGetTokenPriceOverride(t *testing.T) {
+	token := common.HexToAddress("0x1000")
+	client := newMockEthClient()
+	client.prices[token] = big.NewInt(0) // the oracle would be rejected
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	f.config.PriceOverrides = map[common.Address]*big.Int{token: big.NewInt(3_000000)}
+
+	price, err := f.getTokenPrice(context.Background(), token)
+	if err != nil || price.Int64() != 3_000000 {
+		t.Fatalf("getTokenPrice() = %v, %v; want override 3000000", price, err)
+	}
+}
+
+func TestFulfillDepositSkipsSettledBeforeSend(t *testing.T) {
+	client := newMockEthClient()
+	client.settled[1] = true
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); !errors.Is(err, errAlreadySettled) {
+		t.Fatalf("FulfillDeposit error = %v, want errAlreadySettled", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions for a settled deposit, want none", len(client.sent))
+	}
+}
+
+func TestFulfillZeroAmountSkipped(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	ctx := context.Background()
+
+	if _, err := f.FulfillDeposit(ctx, big.NewInt(1), big.NewInt(0), time.Time{}); !errors.Is(err, errZeroAmount) {
+		t.Errorf("FulfillDeposit error = %v, want errZeroAmount", err)
+	}
+	if _, err := f.FulfillWithdrawal(ctx, big.NewInt(1), big.NewInt(0), time.Time{}); !errors.Is(err, errZeroAmount) {
+		t.Errorf("FulfillWithdrawal error = %v, want errZeroAmount", err)
+	}
+	client.withdrawalValue = big.NewInt(0)
+	_, err := f.FulfillWithdrawal(ctx, big.NewInt(2), big.NewInt(1), time.Time{})
+	if !errors.Is(err, errZeroAmount) {
+		t.Errorf("FulfillWithdrawal with zero expected USDC error = %v, want errZeroAmount", err)
+	}
+	if outcome := requestErrOutcome(err); outcome != outcomeIgnored {
+		t.Errorf("outcome = %v, want outcomeIgnored", outcome)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions for zero amounts, want none", len(client.sent))
+	}
+}
+
+func TestFulfillDepositHeldNotNotified(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	f.config.MaxDepositValue = big.NewInt(1000000)
+	f.holds = newRequestHolds()
+	notifier := &recordingNotifier{}
+	f.notifier = NewNotificationQueue([]Notifier{notifier}, 10)
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(2000000), time.Time{}); !errors.Is(err, ErrRequestHeld) {
+		t.Fatalf("FulfillDeposit error = %v, want ErrRequestHeld", err)
+	}
+	if flushed, _ := f.notifier.Drain(context.Background()); flushed != 0 {
+		t.Errorf("notified %d events for a held deposit, want none", flushed)
+	}
+}
+
+func TestFulfillDepositOvercommitCap(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	f.config.MaxDepositOvercommitBps = 200
+	ctx := context.Background()
+
+	// The oracle values the amounts at the deposit's value, so the deposit is fulfilled
+	if _, err := f.FulfillDeposit(ctx, big.NewInt(1), big.NewInt(1000000), time.Time{}); err != nil {
+		t.Fatalf("FulfillDeposit: %v", err)
+	}
+	sent := len(client.sent)
+
+	// The engine's cached decimals are wrong: the token really has 6, so the amounts it computes
+	// with 18 are worth 10^12 times the deposit to the oracle
+	client.decimals[f.underlyingTokens[0]] = 6
+	_, err := f.FulfillDeposit(ctx, big.NewInt(2), big.NewInt(1000000), time.Time{})
+	if !errors.Is(err, ErrDepositOvercommit) {
+		t.Fatalf("FulfillDeposit error = %v, want ErrDepositOvercommit", err)
+	}
+	if len(client.sent) != sent {
+		t.Errorf("sent %d transactions for an overcommitted deposit, want none", len(client.sent)-sent)
+	}
+}
+
+func TestFulfillDepositDryRun(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	f.config.DryRun = true
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); !errors.Is(err, errDryRun) {
+		t.Fatalf("FulfillDeposit error = %v, want errDryRun", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions in a dry run, want none", len(client.sent))
+	}
+}
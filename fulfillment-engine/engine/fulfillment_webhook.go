@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// Per-attempt timeout of a fulfillment webhook delivery
+	fulfillmentWebhookTimeout = 5 * time.Second
+	// Delivery attempts per event; the delay between them starts at fulfillmentWebhookRetryDelay and doubles
+	fulfillmentWebhookAttempts   = 3
+	fulfillmentWebhookRetryDelay = time.Second
+)
+
+// receiptReader is the part of EthClient the fulfillment webhook needs
+type receiptReader interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// FulfilledEvent is the payload posted to a vault's FULFILLMENT_WEBHOOK_URL
+type FulfilledEvent struct {
+	Type        string `json:"type"` // "deposit" or "withdrawal"
+	VaultName   string `json:"vault_name"`
+	Vault       string `json:"vault"`
+	ID          string `json:"id"`
+	User        string `json:"user"`
+	Amount      string `json:"amount"` // quote amount for deposits, shares amount for withdrawals
+	TxHash      string `json:"tx_hash"`
+	BlockNumber uint64 `json:"block_number"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// FulfillmentWebhookNotifier posts each confirmed fulfillment to its vault's webhook, a user-facing
+// event stream for frontends. Unlike the operator notifiers it skips failures and retries
+// deliveries. The user and block number are read from the fulfillment receipt, since the vault
+// deletes the request once it is fulfilled.
+type FulfillmentWebhookNotifier struct {
+	vaults     map[string]VaultConfig // by vault name, only vaults with a webhook
+	receipts   receiptReader
+	client     *http.Client
+	retryDelay time.Duration
+}
+
+// NewFulfillmentWebhookNotifier returns nil if no vault has a fulfillment webhook
+func NewFulfillmentWebhookNotifier(vaults []VaultConfig, receipts receiptReader) *FulfillmentWebhookNotifier {
+	n := &FulfillmentWebhookNotifier{
+		vaults:     make(map[string]VaultConfig),
+		receipts:   receipts,
+		client:     &http.Client{Timeout: fulfillmentWebhookTimeout},
+		retryDelay: fulfillmentWebhookRetryDelay,
+	}
+	for _, vault := range vaults {
+		if vault.FulfillmentWebhookURL != "" {
+			n.vaults[vault.Name] = vault
+		}
+	}
+	if len(n.vaults) == 0 {
+		return nil
+	}
+	return n
+}
+
+func (n *FulfillmentWebhookNotifier) Name() string { return "fulfillment_webhook" }
+
+func (n *FulfillmentWebhookNotifier) Notify(ctx context.Context, event FulfillmentEvent) error {
+	vault, ok := n.vaults[event.VaultName]
+	if !ok || event.Err != nil || event.TxHash == "" {
+		return nil
+	}
+
+	payload := FulfilledEvent{
+		Type:      event.Kind,
+		VaultName: event.VaultName,
+		Vault:     vault.Address.Hex(),
+		ID:        event.RequestID,
+		Amount:    event.Amount,
+		TxHash:    event.TxHash,
+		Timestamp: event.Time.Unix(),
+	}
+
+	delay := n.retryDelay
+	var err error
+	for attempt := 1; attempt <= fulfillmentWebhookAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		if err = n.deliver(ctx, vault, &payload); err == nil {
+			return nil
+		}
+		Logger.Debug("Fulfillment webhook delivery failed",
+			"vault_name", event.VaultName,
+			"kind", event.Kind,
+			"request_id", event.RequestID,
+			"attempt", attempt,
+			"error", err,
+		)
+	}
+	return fmt.Errorf("after %d attempts: %w", fulfillmentWebhookAttempts, err)
+}
+
+// deliver completes payload from the receipt if that has not been done yet, then posts it
+func (n *FulfillmentWebhookNotifier) deliver(ctx context.Context, vault VaultConfig, payload *FulfilledEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, fulfillmentWebhookTimeout)
+	defer cancel()
+
+	if payload.BlockNumber == 0 {
+		receipt, err := n.receipts.TransactionReceipt(ctx, common.HexToHash(payload.TxHash))
+		if err != nil {
+			return fmt.Errorf("get receipt: %v", err)
+		}
+		signature := depositFulfilledSignature
+		if payload.Type == gasKindWithdrawal {
+			signature = withdrawalFulfilledSignature
+		}
+		for _, vLog := range receipt.Logs {
+			// Topics: [0] = event signature, [1] = user (indexed), [2] = request id (indexed)
+			if vLog.Address == vault.Address && len(vLog.Topics) == 3 && vLog.Topics[0] == common.HexToHash(signature) {
+				payload.User = common.BytesToAddress(vLog.Topics[1].Bytes()).Hex()
+			}
+		}
+		payload.BlockNumber = receipt.BlockNumber.Uint64()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	return postJSON(ctx, n.client, vault.FulfillmentWebhookURL, body)
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+type fakeReceiptReader struct {
+	receipt *types.Receipt
+}
+
+func (r *fakeReceiptReader) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if r.receipt == nil {
+		return nil, errors.New("not found")
+	}
+	return r.receipt, nil
+}
+
+func TestFulfillmentWebhookNotifier(t *testing.T) {
+	vault := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	user := common.HexToAddress("0x2222222222222222222222222222222222222222")
+
+	var calls atomic.Int32
+	var got FulfilledEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first delivery fails, so the second attempt is the one recorded
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	receipts := &fakeReceiptReader{receipt: &types.Receipt{
+		Status:      types.ReceiptStatusSuccessful,
+		BlockNumber: big.NewInt(1234),
+		Logs: []*types.Log{{
+			Address: vault,
+			Topics: []common.Hash{
+				common.HexToHash(withdrawalFulfilledSignature),
+				common.BytesToHash(user.Bytes()),
+				common.BigToHash(big.NewInt(7)),
+			},
+		}},
+	}}
+	n := NewFulfillmentWebhookNotifier([]VaultConfig{
+		{Address: vault, Name: "Main", FulfillmentWebhookURL: server.URL},
+		{Name: "Other"},
+	}, receipts)
+	n.retryDelay = time.Millisecond
+
+	event := FulfillmentEvent{
+		Kind:      gasKindWithdrawal,
+		VaultName: "Main",
+		RequestID: "7",
+		Amount:    "5000000",
+		TxHash:    common.HexToHash("0xabc").Hex(),
+		Time:      time.Unix(1700000000, 0),
+	}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	want := FulfilledEvent{
+		Type:        "withdrawal",
+		VaultName:   "Main",
+		Vault:       vault.Hex(),
+		ID:          "7",
+		User:        user.Hex(),
+		Amount:      "5000000",
+		TxHash:      event.TxHash,
+		BlockNumber: 1234,
+		Timestamp:   1700000000,
+	}
+	if got != want {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+
+	// Failed fulfillments and vaults without a webhook are not posted
+	calls.Store(0)
+	failed := event
+	failed.Err = errors.New("reverted")
+	other := event
+	other.VaultName = "Other"
+	for _, e := range []FulfillmentEvent{failed, other} {
+		if err := n.Notify(context.Background(), e); err != nil {
+			t.Errorf("Notify: %v", err)
+		}
+	}
+	if calls.Load() != 0 {
+		t.Errorf("posted %d times, want 0", calls.Load())
+	}
+
+	if NewFulfillmentWebhookNotifier([]VaultConfig{{Name: "Other"}}, receipts) != nil {
+		t.Error("expected no notifier without a webhook URL")
+	}
+}
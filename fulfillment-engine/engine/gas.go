@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Transaction kinds gas is attributed to
+const (
+	gasKindDeposit    = "deposit"
+	gasKindWithdrawal = "withdrawal"
+	gasKindApproval   = "approval"
+)
+
+var gasKinds = []string{gasKindDeposit, gasKindWithdrawal, gasKindApproval}
+
+// GasUsage is the gas spent on one kind of transaction
+type GasUsage struct {
+	Transactions int    `json:"transactions"`
+	GasUsed      uint64 `json:"gas_used"`
+	CostWei      string `json:"cost_wei"`
+}
+
+// GasReport is the JSON representation of a vault's gas totals
+type GasReport struct {
+	VaultName string              `json:"vault_name"`
+	ByKind    map[string]GasUsage `json:"by_kind"`
+	TotalWei  string              `json:"total_cost_wei"`
+}
+
+var weiPerGwei = big.NewInt(1_000_000_000)
+
+// parseGwei parses a decimal gas price in gwei (e.g. "0.05") to wei, dropping fractions of a wei
+func parseGwei(gwei string) (*big.Int, error) {
+	r, ok := new(big.Rat).SetString(gwei)
+	if !ok {
+		return nil, fmt.Errorf("invalid gas price %q", gwei)
+	}
+	r.Mul(r, new(big.Rat).SetInt(weiPerGwei))
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
+
+// formatGwei formats a wei amount in gwei without trailing zeros, e.g. 1500000000 -> "1.5"
+func formatGwei(wei *big.Int) string {
+	s := new(big.Rat).SetFrac(wei, weiPerGwei).FloatString(9)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
+
+// txGasCost returns gasUsed * effectiveGasPrice for a mined transaction.
+// Receipts from nodes that omit effectiveGasPrice fall back to the legacy gas price.
+func txGasCost(receipt *types.Receipt, tx *types.Transaction) *big.Int {
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = tx.GasPrice()
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+}
+
+type gasTotal struct {
+	transactions int
+	gasUsed      uint64
+	cost         *big.Int
+}
+
+// gasLedger accumulates the gas cost of a vault's transactions by kind
+type gasLedger struct {
+	mu     sync.Mutex
+	totals map[string]*gasTotal
+}
+
+func newGasLedger() *gasLedger {
+	return &gasLedger{totals: make(map[string]*gasTotal)}
+}
+
+func (g *gasLedger) record(kind string, gasUsed uint64, cost *big.Int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	total, ok := g.totals[kind]
+	if !ok {
+		total = &gasTotal{cost: new(big.Int)}
+		g.totals[kind] = total
+	}
+	total.transactions++
+	total.gasUsed += gasUsed
+	total.cost.Add(total.cost, cost)
+}
+
+// report returns a snapshot of the totals
+func (g *gasLedger) report(vaultName string) GasReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	report := GasReport{VaultName: vaultName, ByKind: make(map[string]GasUsage, len(g.totals))}
+	sum := new(big.Int)
+	for kind, total := range g.totals {
+		report.ByKind[kind] = GasUsage{
+			Transactions: total.transactions,
+			GasUsed:      total.gasUsed,
+			CostWei:      total.cost.String(),
+		}
+		sum.Add(sum, total.cost)
+	}
+	report.TotalWei = sum.String()
+	return report
+}
+
+// GasReporter periodically logs each vault's accumulated gas cost
+type GasReporter struct {
+	interval   time.Duration
+	fulfillers []*Fulfiller
+}
+
+func NewGasReporter(config *Config, fulfillers []*Fulfiller) *GasReporter {
+	return &GasReporter{interval: config.GasReportInterval, fulfillers: fulfillers}
+}
+
+func (r *GasReporter) Start(ctx context.Context) error {
+	if r.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.log()
+		}
+	}
+}
+
+func (r *GasReporter) log() {
+	for _, f := range r.fulfillers {
+		report := f.gas.report(f.vaultConfig.Name)
+		attrs := []interface{}{"vault_name", report.VaultName}
+		for _, kind := range gasKinds {
+			usage := report.ByKind[kind]
+			if usage.CostWei == "" {
+				usage.CostWei = "0"
+			}
+			attrs = append(attrs, kind+"_txs", usage.Transactions, kind+"_cost_wei", usage.CostWei)
+		}
+		attrs = append(attrs, "total_cost_wei", report.TotalWei)
+		Logger.Info("Gas cost summary", attrs...)
+	}
+}
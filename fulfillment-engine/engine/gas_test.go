@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestTxGasCost(t *testing.T) {
+	legacy := types.NewTx(&types.LegacyTx{GasPrice: big.NewInt(7)})
+
+	receipt := &types.Receipt{GasUsed: 21000, EffectiveGasPrice: big.NewInt(3)}
+	if got := txGasCost(receipt, legacy); got.Cmp(big.NewInt(63000)) != 0 {
+		t.Errorf("cost with effective price = %s, want 63000", got)
+	}
+
+	receipt = &types.Receipt{GasUsed: 21000}
+	if got := txGasCost(receipt, legacy); got.Cmp(big.NewInt(147000)) != 0 {
+		t.Errorf("cost without effective price = %s, want 147000", got)
+	}
+}
+
+func TestParseFormatGwei(t *testing.T) {
+	tests := []struct {
+		gwei string
+		want string
+	}{
+		{gwei: "30", want: "30000000000"},
+		{gwei: "1.5", want: "1500000000"},
+		{gwei: "0.0000000015", want: "1"},
+	}
+	for _, tt := range tests {
+		got, err := parseGwei(tt.gwei)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("parseGwei(%q) = %v, %v; want %s", tt.gwei, got, err, tt.want)
+		}
+	}
+	if _, err := parseGwei("30gwei"); err == nil {
+		t.Error("expected an error for a non-numeric gas price")
+	}
+
+	for wei, want := range map[int64]string{30000000000: "30", 1500000000: "1.5", 1: "0.000000001"} {
+		if got := formatGwei(big.NewInt(wei)); got != want {
+			t.Errorf("formatGwei(%d) = %s, want %s", wei, got, want)
+		}
+	}
+}
+
+func TestGasLedgerReport(t *testing.T) {
+	g := newGasLedger()
+	g.record(gasKindDeposit, 100, big.NewInt(1000))
+	g.record(gasKindDeposit, 50, big.NewInt(500))
+	g.record(gasKindApproval, 10, big.NewInt(20))
+
+	report := g.report("Test")
+	if report.VaultName != "Test" || report.TotalWei != "1520" {
+		t.Errorf("report = %+v, want vault Test with total 1520", report)
+	}
+	want := GasUsage{Transactions: 2, GasUsed: 150, CostWei: "1500"}
+	if got := report.ByKind[gasKindDeposit]; got != want {
+		t.Errorf("deposit usage = %+v, want %+v", got, want)
+	}
+	if _, ok := report.ByKind[gasKindWithdrawal]; ok {
+		t.Error("unexpected withdrawal usage")
+	}
+}
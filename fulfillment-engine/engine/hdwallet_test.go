@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDeriveKey(t *testing.T) {
+	// The well-known development mnemonic used by Hardhat and Anvil
+	const mnemonic = "test test test test test test test test test test test junk"
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "m/44'/60'/0'/0/0", want: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"},
+		{path: "m/44'/60'/0'/0/1", want: "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"},
+	}
+	for _, tt := range tests {
+		path, err := accounts.ParseDerivationPath(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := deriveKey(mnemonic, path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if got := crypto.PubkeyToAddress(key.PublicKey).Hex(); got != tt.want {
+			t.Errorf("%s: address %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := deriveKey("test test test", accounts.DefaultBaseDerivationPath); err == nil {
+		t.Error("expected an error for a 3-word mnemonic")
+	}
+}
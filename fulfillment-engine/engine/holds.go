@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrRequestHeld is returned when a request exceeds an auto-fulfillment limit and awaits operator approval
+var ErrRequestHeld = errors.New("request held for manual approval")
+
+// HeldRequest is a request that exceeded MAX_DEPOSIT_VALUE or MAX_WITHDRAWAL_SHARES
+type HeldRequest struct {
+	Type   string    `json:"type"` // "deposit" or "withdrawal"
+	ID     string    `json:"id"`
+	Amount string    `json:"amount"`
+	Limit  string    `json:"limit"`
+	HeldAt time.Time `json:"held_at"`
+}
+
+type holdKey struct {
+	kind string
+	id   string
+}
+
+// requestHolds tracks requests held for review and one-shot operator approvals that release them
+type requestHolds struct {
+	mu       sync.Mutex
+	held     map[holdKey]HeldRequest
+	approved map[holdKey]bool
+}
+
+func newRequestHolds() *requestHolds {
+	return &requestHolds{
+		held:     make(map[holdKey]HeldRequest),
+		approved: make(map[holdKey]bool),
+	}
+}
+
+// check returns nil if amount is within limit (nil limit = unlimited) or the request was approved,
+// consuming the approval. Otherwise the request is recorded as held and ErrRequestHeld is returned.
+func (h *requestHolds) check(kind string, id, amount, limit *big.Int) error {
+	if limit == nil || amount.Cmp(limit) <= 0 {
+		return nil
+	}
+
+	key := holdKey{kind: kind, id: id.String()}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.approved[key] {
+		delete(h.approved, key)
+		delete(h.held, key)
+		return nil
+	}
+
+	if _, ok := h.held[key]; !ok {
+		h.held[key] = HeldRequest{
+			Type:   kind,
+			ID:     id.String(),
+			Amount: amount.String(),
+			Limit:  limit.String(),
+			HeldAt: time.Now().UTC(),
+		}
+	}
+	return fmt.Errorf("%w: %s %s amount %s exceeds limit %s", ErrRequestHeld, kind, id.String(), amount.String(), limit.String())
+}
+
+// Approve lets the next fulfillment attempt of the request bypass the limits
+func (h *requestHolds) Approve(kind string, id *big.Int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.approved[holdKey{kind: kind, id: id.String()}] = true
+}
+
+// List returns the currently held requests, oldest first
+func (h *requestHolds) List() []HeldRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	held := make([]HeldRequest, 0, len(h.held))
+	for _, req := range h.held {
+		held = append(held, req)
+	}
+	sort.Slice(held, func(i, j int) bool {
+		return held[i].HeldAt.Before(held[j].HeldAt)
+	})
+	return held
+}
@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestRequestHolds(t *testing.T) {
+	h := newRequestHolds()
+	limit := big.NewInt(1000)
+
+	if err := h.check("deposit", big.NewInt(1), big.NewInt(1000), limit); err != nil {
+		t.Fatalf("amount at limit: %v", err)
+	}
+	if err := h.check("deposit", big.NewInt(2), big.NewInt(5000), nil); err != nil {
+		t.Fatalf("no limit configured: %v", err)
+	}
+
+	err := h.check("deposit", big.NewInt(3), big.NewInt(1001), limit)
+	if !errors.Is(err, ErrRequestHeld) {
+		t.Fatalf("amount over limit: got %v, want ErrRequestHeld", err)
+	}
+	if held := h.List(); len(held) != 1 || held[0].ID != "3" || held[0].Type != "deposit" {
+		t.Fatalf("held = %+v, want deposit 3", held)
+	}
+
+	// Approval is per type and id
+	h.Approve("withdrawal", big.NewInt(3))
+	if err := h.check("deposit", big.NewInt(3), big.NewInt(1001), limit); !errors.Is(err, ErrRequestHeld) {
+		t.Fatalf("withdrawal approval released deposit: %v", err)
+	}
+
+	h.Approve("deposit", big.NewInt(3))
+	if err := h.check("deposit", big.NewInt(3), big.NewInt(1001), limit); err != nil {
+		t.Fatalf("approved request: %v", err)
+	}
+	if held := h.List(); len(held) != 0 {
+		t.Fatalf("held after approval = %+v, want none", held)
+	}
+
+	// Approvals are one-shot
+	if err := h.check("deposit", big.NewInt(3), big.NewInt(1001), limit); !errors.Is(err, ErrRequestHeld) {
+		t.Fatalf("approval reused: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+//go:build integration
+
+// Integration test against a local anvil node (plain or `anvil --fork-url ...`).
+//
+//	forge build                # from the repository root, produces ../out
+//	anvil &
+//	ANVIL_RPC_URL=http://127.0.0.1:8545 go test -tags integration -run Integration .
+//
+// The test deploys mock ERC20s, a MockOracle and a SectorVault from the forge artifacts,
+// requests a deposit, runs the listener and asserts the deposit is fulfilled on-chain.
+// It is skipped when ANVIL_RPC_URL is unset or the node / artifacts are unavailable.
+package engine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Well-known anvil dev account keys
+const (
+	anvilDeployerKey = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80" // also the fulfiller
+	anvilUserKey     = "59c6995e998f97a5a0044966f0945389dc9c86dae88c7a8412f4603b6b78690d"
+)
+
+// forgeArtifact is the subset of a forge build artifact needed to deploy a contract
+type forgeArtifact struct {
+	ABI      json.RawMessage `json:"abi"`
+	Bytecode struct {
+		Object string `json:"object"`
+	} `json:"bytecode"`
+}
+
+// anvilHarness deploys and drives contracts on the anvil node
+type anvilHarness struct {
+	t       *testing.T
+	ctx     context.Context
+	client  *ethclient.Client
+	chainID *big.Int
+	outDir  string
+}
+
+func newAnvilHarness(t *testing.T) *anvilHarness {
+	t.Helper()
+
+	rpcURL := os.Getenv("ANVIL_RPC_URL")
+	if rpcURL == "" {
+		t.Skip("ANVIL_RPC_URL not set")
+	}
+	outDir := os.Getenv("FORGE_OUT_DIR")
+	if outDir == "" {
+		outDir = filepath.Join("..", "out")
+	}
+	if _, err := os.Stat(outDir); err != nil {
+		t.Skipf("forge artifacts not found in %s (run forge build): %v", outDir, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		t.Skipf("anvil not reachable at %s: %v", rpcURL, err)
+	}
+	t.Cleanup(client.Close)
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Skipf("anvil not reachable at %s: %v", rpcURL, err)
+	}
+
+	return &anvilHarness{t: t, ctx: ctx, client: client, chainID: chainID, outDir: outDir}
+}
+
+func (h *anvilHarness) key(hexKey string) *ecdsa.PrivateKey {
+	h.t.Helper()
+	key, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		h.t.Fatalf("parse key: %v", err)
+	}
+	return key
+}
+
+func (h *anvilHarness) transactor(key *ecdsa.PrivateKey) *bind.TransactOpts {
+	h.t.Helper()
+	opts, err := bind.NewKeyedTransactorWithChainID(key, h.chainID)
+	if err != nil {
+		h.t.Fatalf("transactor: %v", err)
+	}
+	opts.Context = h.ctx
+	return opts
+}
+
+// deploy deploys contract name from <outDir>/<file>/<name>.json
+func (h *anvilHarness) deploy(key *ecdsa.PrivateKey, file, name string, args ...interface{}) (common.Address, *bind.BoundContract) {
+	h.t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(h.outDir, file, name+".json"))
+	if err != nil {
+		h.t.Skipf("artifact %s/%s not found (run forge build): %v", file, name, err)
+	}
+	var artifact forgeArtifact
+	if err := json.Unmarshal(data, &artifact); err != nil {
+		h.t.Fatalf("parse artifact %s: %v", name, err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(artifact.ABI)))
+	if err != nil {
+		h.t.Fatalf("parse %s abi: %v", name, err)
+	}
+
+	address, tx, contract, err := bind.DeployContract(h.transactor(key), parsed, common.FromHex(artifact.Bytecode.Object), h.client, args...)
+	if err != nil {
+		h.t.Fatalf("deploy %s: %v", name, err)
+	}
+	h.wait(tx)
+	return address, contract
+}
+
+// transact sends a transaction and waits for it to succeed
+func (h *anvilHarness) transact(key *ecdsa.PrivateKey, contract *bind.BoundContract, method string, args ...interface{}) {
+	h.t.Helper()
+	tx, err := contract.Transact(h.transactor(key), method, args...)
+	if err != nil {
+		h.t.Fatalf("%s: %v", method, err)
+	}
+	h.wait(tx)
+}
+
+func (h *anvilHarness) wait(tx *types.Transaction) *types.Receipt {
+	h.t.Helper()
+	receipt, err := bind.WaitMined(h.ctx, h.client, tx)
+	if err != nil {
+		h.t.Fatalf("wait for %s: %v", tx.Hash().Hex(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		h.t.Fatalf("transaction %s reverted", tx.Hash().Hex())
+	}
+	return receipt
+}
+
+func (h *anvilHarness) address(contract *bind.BoundContract, method string) common.Address {
+	h.t.Helper()
+	var out []interface{}
+	if err := contract.Call(&bind.CallOpts{Context: h.ctx}, &out, method); err != nil {
+		h.t.Fatalf("%s: %v", method, err)
+	}
+	return out[0].(common.Address)
+}
+
+func TestIntegrationDepositFulfillment(t *testing.T) {
+	h := newAnvilHarness(t)
+	deployer := h.key(anvilDeployerKey)
+	user := h.key(anvilUserKey)
+	deployerAddr := crypto.PubkeyToAddress(deployer.PublicKey)
+	userAddr := crypto.PubkeyToAddress(user.PublicKey)
+
+	// Mock tokens (18 decimals, 1M minted to the deployer, who is also the fulfiller)
+	quoteAddr, quote := h.deploy(deployer, "SectorVault.t.sol", "MockERC20", "Mock USD", "mUSD")
+	tokenA, _ := h.deploy(deployer, "SectorVault.t.sol", "MockERC20", "Token A", "TKA")
+	tokenB, _ := h.deploy(deployer, "SectorVault.t.sol", "MockERC20", "Token B", "TKB")
+	underlying := []common.Address{tokenA, tokenB}
+
+	// Oracle: $1.00 and $2.50 (6 decimals)
+	oracleAddr, oracle := h.deploy(deployer, "MockOracle.sol", "MockOracle")
+	h.transact(deployer, oracle, "setPrices", underlying, []*big.Int{big.NewInt(1_000000), big.NewInt(2_500000)})
+	h.transact(deployer, oracle, "setTokenDecimalsBatch", underlying, []uint8{18, 18})
+
+	vaultAddr, vault := h.deploy(deployer, "SectorVault.sol", "SectorVault",
+		quoteAddr, "Test Sector", "tTEST", underlying, []*big.Int{big.NewInt(6000), big.NewInt(4000)},
+		deployerAddr, oracleAddr)
+
+	// User requests a 100 mUSD deposit
+	deposit := new(big.Int).Mul(big.NewInt(100), new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+	h.transact(deployer, quote, "mint", userAddr, deposit)
+	h.transact(user, quote, "approve", vaultAddr, deposit)
+
+	// Run the engine against the node
+	rpc, err := DialRPCClient([]string{os.Getenv("ANVIL_RPC_URL")})
+	if err != nil {
+		t.Fatalf("dial engine client: %v", err)
+	}
+	defer rpc.Close()
+
+	config := &Config{
+		PollInterval:           1,
+		RPCCallTimeout:         15 * time.Second,
+		LogChunkSize:           10000,
+		WithdrawalToleranceBps: contractToleranceBps,
+		Oracle:                 defaultOracleVariant,
+	}
+	vaultConfig := VaultConfig{Name: "Test", Address: vaultAddr}
+	acc := &fulfillerAccount{
+		fromAddress: deployerAddr,
+		privateKey:  deployer,
+		client:      rpc,
+		callTimeout: config.RPCCallTimeout,
+	}
+	fulfiller, err := NewFulfiller(config, vaultConfig, acc, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewFulfiller: %v", err)
+	}
+	defer fulfiller.Close()
+
+	listener := NewEventListener(rpc, config, vaultConfig, fulfiller)
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+	go listener.Start(ctx)
+
+	h.transact(user, vault, "deposit", deposit)
+
+	// The listener picks up DepositRequested and the fulfiller mints shares to the user
+	sectorToken := bind.NewBoundContract(h.address(vault, "SECTOR_TOKEN"), mustParse(t, ParseERC20ABI), h.client, h.client, h.client)
+	deadline := time.Now().Add(time.Minute)
+	for {
+		var out []interface{}
+		if err := sectorToken.Call(&bind.CallOpts{Context: h.ctx}, &out, "balanceOf", userAddr); err != nil {
+			t.Fatalf("balanceOf: %v", err)
+		}
+		if shares := out[0].(*big.Int); shares.Sign() > 0 {
+			t.Logf("deposit fulfilled, user received %s shares", shares)
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("deposit was not fulfilled within a minute")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	pending, err := fulfiller.GetPendingDeposit(h.ctx, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("GetPendingDeposit: %v", err)
+	}
+	if !pending.Fulfilled && pending.User != (common.Address{}) {
+		t.Error("deposit 0 still pending on-chain")
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the fulfillment latency histogram
+var latencyBuckets = []float64{15, 30, 60, 120, 300, 600, 1800, 3600, 21600, 86400}
+
+type latencyHistogram struct {
+	counts []uint64 // per bucket, not cumulative; the last entry counts values above every bound
+	sum    float64
+	count  uint64
+}
+
+// latencyRecorder tracks the time from on-chain request to confirmed fulfillment by request kind
+type latencyRecorder struct {
+	mu    sync.Mutex
+	kinds map[string]*latencyHistogram
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{kinds: make(map[string]*latencyHistogram)}
+}
+
+// observe records a fulfillment confirmed at confirmedAt. Requests with an unknown time are skipped;
+// a confirmation before the request (clock skew between the engine and the chain) counts as zero.
+func (r *latencyRecorder) observe(kind string, requestedAt, confirmedAt time.Time) {
+	if requestedAt.IsZero() {
+		return
+	}
+	seconds := confirmedAt.Sub(requestedAt).Seconds()
+	if seconds < 0 {
+		seconds = 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.kinds[kind]
+	if !ok {
+		h = &latencyHistogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		r.kinds[kind] = h
+	}
+	i := 0
+	for i < len(latencyBuckets) && seconds > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+// writePrometheus writes the histograms in the Prometheus text format, labelled with the vault name
+func (r *latencyRecorder) writePrometheus(w io.Writer, vaultName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, kind := range []string{gasKindDeposit, gasKindWithdrawal} {
+		h, ok := r.kinds[kind]
+		if !ok {
+			continue
+		}
+		labels := fmt.Sprintf("vault=%q,type=%q", vaultName, kind)
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "tone_fulfillment_latency_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(bound, 'f', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "tone_fulfillment_latency_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(w, "tone_fulfillment_latency_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'f', -1, 64))
+		fmt.Fprintf(w, "tone_fulfillment_latency_seconds_count{%s} %d\n", labels, h.count)
+	}
+}
@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyRecorder(t *testing.T) {
+	r := newLatencyRecorder()
+	confirmed := time.Unix(1700000000, 0)
+	r.observe(gasKindDeposit, confirmed.Add(-20*time.Second), confirmed)
+	r.observe(gasKindDeposit, confirmed.Add(time.Minute), confirmed) // request clock ahead: clamped to 0
+	r.observe(gasKindDeposit, time.Time{}, confirmed)                // unknown request time: skipped
+
+	var out strings.Builder
+	r.writePrometheus(&out, "AI")
+	for _, want := range []string{
+		`tone_fulfillment_latency_seconds_bucket{vault="AI",type="deposit",le="15"} 1`,
+		`tone_fulfillment_latency_seconds_bucket{vault="AI",type="deposit",le="30"} 2`,
+		`tone_fulfillment_latency_seconds_bucket{vault="AI",type="deposit",le="+Inf"} 2`,
+		`tone_fulfillment_latency_seconds_sum{vault="AI",type="deposit"} 20`,
+		`tone_fulfillment_latency_seconds_count{vault="AI",type="deposit"} 2`,
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "withdrawal") {
+		t.Error("unexpected withdrawal series without observations")
+	}
+}
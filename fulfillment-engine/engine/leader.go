@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// How often a standby instance retries the leader lock
+var leaderRetryInterval = 5 * time.Second
+
+// leaderElector elects one active engine among instances sharing the LEADER_LOCK file.
+// The leader holds an exclusive lock on the file until it exits; the kernel releases the
+// lock if the process dies, and a standby polling the lock takes over. A nil elector
+// (no LEADER_LOCK) always reports leadership.
+type leaderElector struct {
+	path     string
+	file     *os.File
+	leader   atomic.Bool
+	takeover chan struct{} // closed when a standby becomes the leader
+}
+
+func newLeaderElector(path string) *leaderElector {
+	return &leaderElector{path: path, takeover: make(chan struct{})}
+}
+
+// IsLeader reports whether this instance should fulfill requests
+func (e *leaderElector) IsLeader() bool {
+	return e == nil || e.leader.Load()
+}
+
+// Takeover returns a channel closed when this instance takes over from a failed leader
+func (e *leaderElector) Takeover() <-chan struct{} {
+	if e == nil {
+		return nil
+	}
+	return e.takeover
+}
+
+// TryAcquire makes a single attempt to take the lock
+func (e *leaderElector) TryAcquire() (bool, error) {
+	if e.leader.Load() {
+		return true, nil
+	}
+	if e.file == nil {
+		file, err := os.OpenFile(e.path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return false, fmt.Errorf("open leader lock: %v", err)
+		}
+		e.file = file
+	}
+
+	ok, err := lockFile(e.file)
+	if err != nil || !ok {
+		return false, err
+	}
+	e.leader.Store(true)
+
+	// Record the holder for operators; the lock itself is what counts
+	hostname, _ := os.Hostname()
+	if err := e.file.Truncate(0); err == nil {
+		e.file.WriteAt([]byte(fmt.Sprintf("%s pid %d\n", hostname, os.Getpid())), 0)
+	}
+	return true, nil
+}
+
+// Run retries the lock until this instance is the leader, then holds it until ctx is cancelled
+func (e *leaderElector) Run(ctx context.Context) error {
+	defer e.release()
+
+	if !e.IsLeader() {
+		ticker := time.NewTicker(leaderRetryInterval)
+		defer ticker.Stop()
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				ok, err := e.TryAcquire()
+				if err != nil {
+					Logger.Warn("Failed to acquire leader lock", "lock", e.path, "error", err)
+				}
+				if ok {
+					break wait
+				}
+			}
+		}
+
+		Logger.Warn("Acquired leader lock, taking over fulfillment", "lock", e.path)
+		close(e.takeover)
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// release drops the lock by closing the file
+func (e *leaderElector) release() {
+	if e.file != nil {
+		e.file.Close()
+	}
+}
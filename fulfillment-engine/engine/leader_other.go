@@ -0,0 +1,12 @@
+//go:build !unix
+
+package engine
+
+import (
+	"fmt"
+	"os"
+)
+
+func lockFile(f *os.File) (bool, error) {
+	return false, fmt.Errorf("LEADER_LOCK is only supported on unix systems")
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLeaderElectorTakeover(t *testing.T) {
+	leaderRetryInterval = 10 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "leader.lock")
+
+	leader := newLeaderElector(path)
+	if ok, err := leader.TryAcquire(); !ok || err != nil {
+		t.Fatalf("first TryAcquire = %v, %v; want leader", ok, err)
+	}
+
+	standby := newLeaderElector(path)
+	if ok, err := standby.TryAcquire(); ok || err != nil {
+		t.Fatalf("second TryAcquire = %v, %v; want standby", ok, err)
+	}
+	if standby.IsLeader() {
+		t.Fatal("standby reports leadership")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go standby.Run(ctx)
+
+	// The leader exits; the standby takes over
+	leader.release()
+	select {
+	case <-standby.Takeover():
+	case <-time.After(5 * time.Second):
+		t.Fatal("standby did not take over")
+	}
+	if !standby.IsLeader() {
+		t.Error("standby not leader after takeover")
+	}
+
+	var disabled *leaderElector
+	if !disabled.IsLeader() || disabled.Takeover() != nil {
+		t.Error("nil elector should always lead and never take over")
+	}
+}
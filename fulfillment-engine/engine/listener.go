@@ -0,0 +1,990 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Request lifecycle event topics, derived from the event definitions in SectorVaultABI.
+// EVENT_TOPICS can override them for a vault redeployed with different event signatures.
+var (
+	depositRequestedSignature    = eventTopic("DepositRequested")
+	withdrawalRequestedSignature = eventTopic("WithdrawalRequested")
+
+	// Lifecycle events used by the startup backfill to skip settled requests
+	depositFulfilledSignature    = eventTopic("DepositFulfilled")
+	depositCancelledSignature    = eventTopic("DepositCancelled")
+	withdrawalFulfilledSignature = eventTopic("WithdrawalFulfilled")
+	withdrawalCancelledSignature = eventTopic("WithdrawalCancelled")
+)
+
+// sectorVaultEvents is SectorVaultABI parsed once for topic derivation and log decoding
+var sectorVaultEvents = mustParseSectorVaultABI()
+
+func mustParseSectorVaultABI() abi.ABI {
+	parsed, err := ParseSectorVaultABI()
+	if err != nil {
+		panic(fmt.Sprintf("parse sector vault abi: %v", err))
+	}
+	return parsed
+}
+
+// eventTopic returns the topic hash of a SectorVaultABI event as hex. The ABI is a
+// compile-time constant, so a missing event is a programming error.
+func eventTopic(name string) string {
+	event, ok := sectorVaultEvents.Events[name]
+	if !ok {
+		panic(fmt.Sprintf("sector vault abi has no event %s", name))
+	}
+	return event.ID.Hex()
+}
+
+// applyEventTopicOverrides replaces the topics of the named events (EVENT_TOPICS). Called once at startup.
+func applyEventTopicOverrides(overrides map[string]common.Hash) {
+	topics := map[string]*string{
+		"DepositRequested":    &depositRequestedSignature,
+		"WithdrawalRequested": &withdrawalRequestedSignature,
+		"DepositFulfilled":    &depositFulfilledSignature,
+		"DepositCancelled":    &depositCancelledSignature,
+		"WithdrawalFulfilled": &withdrawalFulfilledSignature,
+		"WithdrawalCancelled": &withdrawalCancelledSignature,
+	}
+	for name, topic := range overrides {
+		if target, ok := topics[name]; ok {
+			Logger.Warn("Overriding event topic", "event", name, "abi_topic", *target, "topic", topic.Hex())
+			*target = topic.Hex()
+		}
+	}
+}
+
+// unpackDepositRequested decodes a DepositRequested log. The ABI unpacker rejects data
+// shorter than the event layout.
+func unpackDepositRequested(vLog types.Log) (*DepositRequestedEvent, error) {
+	if len(vLog.Topics) < 3 {
+		return nil, fmt.Errorf("invalid event topics")
+	}
+	event := &DepositRequestedEvent{
+		User:      common.BytesToAddress(vLog.Topics[1].Bytes()),
+		DepositId: new(big.Int).SetBytes(vLog.Topics[2].Bytes()),
+	}
+	if err := sectorVaultEvents.UnpackIntoInterface(event, "DepositRequested", vLog.Data); err != nil {
+		return nil, fmt.Errorf("invalid event data: %v", err)
+	}
+	return event, nil
+}
+
+// unpackWithdrawalRequested decodes a WithdrawalRequested log
+func unpackWithdrawalRequested(vLog types.Log) (*WithdrawalRequestedEvent, error) {
+	if len(vLog.Topics) < 3 {
+		return nil, fmt.Errorf("invalid event topics")
+	}
+	event := &WithdrawalRequestedEvent{
+		User:         common.BytesToAddress(vLog.Topics[1].Bytes()),
+		WithdrawalId: new(big.Int).SetBytes(vLog.Topics[2].Bytes()),
+	}
+	if err := sectorVaultEvents.UnpackIntoInterface(event, "WithdrawalRequested", vLog.Data); err != nil {
+		return nil, fmt.Errorf("invalid event data: %v", err)
+	}
+	return event, nil
+}
+
+// Request types handled by this engine (FULFILL_MODE)
+const (
+	fulfillModeBoth        = "both"
+	fulfillModeDeposits    = "deposits"
+	fulfillModeWithdrawals = "withdrawals"
+)
+
+// Delay before re-subscribing after a dropped log subscription (a var so tests can shorten it)
+var resubscribeDelay = 2 * time.Second
+
+// Attempts per request history chunk before the startup scan fails
+const scanChunkAttempts = 4
+
+// Delay before retrying a request history chunk, doubling per attempt (a var so tests can shorten it)
+var scanChunkRetryDelay = time.Second
+
+// listenerClient is the subset of RPCClient used by event listeners
+type listenerClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error)
+}
+
+// Fulfillment is the subset of Fulfiller used by event listeners, so listener logic can be
+// tested against a fake instead of a chain
+type Fulfillment interface {
+	FulfillDeposit(ctx context.Context, depositId *big.Int, quoteAmount *big.Int, requestedAt time.Time) (common.Hash, error)
+	FulfillWithdrawal(ctx context.Context, withdrawalId *big.Int, sharesAmount *big.Int, requestedAt time.Time) (common.Hash, error)
+	GetNextDepositId(ctx context.Context) (*big.Int, error)
+	GetPendingDeposit(ctx context.Context, depositId *big.Int) (*PendingDeposit, error)
+	GetNextWithdrawalId(ctx context.Context) (*big.Int, error)
+	GetPendingWithdrawal(ctx context.Context, withdrawalId *big.Int) (*PendingWithdrawal, error)
+	calculateWithdrawalValue(ctx context.Context, sharesAmount *big.Int) (*big.Int, error)
+	setProcessedBlock(block uint64)
+}
+
+type EventListener struct {
+	client      listenerClient
+	config      *Config
+	vaultConfig VaultConfig
+	fulfiller   Fulfillment
+	lastBlock   uint64
+	seenLogs    *logDedupe // processed (txHash, logIndex) pairs
+
+	leader   *leaderElector  // only the leader fulfills (nil: always)
+	takeover <-chan struct{} // closed when this standby instance becomes the leader
+
+	breaker  *circuitBreaker // pauses fulfillment after repeated failures (nil: never)
+	deferred []types.Log     // requests received while the breaker was open, oldest first
+}
+
+func NewEventListener(client listenerClient, config *Config, vaultConfig VaultConfig, fulfiller Fulfillment) *EventListener {
+	return &EventListener{
+		client:      client,
+		config:      config,
+		vaultConfig: vaultConfig,
+		fulfiller:   fulfiller,
+		lastBlock:   0,
+		seenLogs:    newLogDedupe(processedLogCacheSize),
+		breaker:     newCircuitBreaker(config.BreakerFailures, config.BreakerWindow, config.BreakerCooldown),
+	}
+}
+
+// setLastBlock records block as fully processed, also for GET /status
+func (l *EventListener) setLastBlock(block uint64) {
+	l.lastBlock = block
+	if l.fulfiller != nil {
+		l.fulfiller.setProcessedBlock(block)
+	}
+}
+
+func (l *EventListener) Start(ctx context.Context) error {
+	// Get current block
+	header, err := withCallTimeout(ctx, l.config.RPCCallTimeout, "HeaderByNumber", func(ctx context.Context) (*types.Header, error) {
+		return l.client.HeaderByNumber(ctx, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %v", err)
+	}
+	currentBlock := header.Number.Uint64()
+
+	if err := l.scanPendingRequests(ctx, currentBlock); err != nil {
+		return err
+	}
+
+	// Set lastBlock to current
+	l.setLastBlock(currentBlock)
+
+	if l.config.SubscribeLogs {
+		Logger.Info("Event listener started",
+			"vault_name", l.vaultConfig.Name,
+			"vault_address", l.vaultConfig.Address.Hex(),
+			"start_block", l.lastBlock,
+			"mode", "subscribe",
+		)
+		return l.subscribe(ctx)
+	}
+
+	// Start at a random offset into the interval so vaults don't all poll the RPC at the same instant
+	interval, _ := l.config.vaultPollTiming(l.vaultConfig)
+	startDelay := randomDelay(interval)
+
+	Logger.Info("Event listener started",
+		"vault_name", l.vaultConfig.Name,
+		"vault_address", l.vaultConfig.Address.Hex(),
+		"start_block", l.lastBlock,
+		"poll_interval_seconds", int(interval.Seconds()),
+		"start_delay", startDelay,
+	)
+
+	timer := time.NewTimer(startDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.takeover:
+			if err := l.takeOver(ctx); err != nil {
+				return err
+			}
+		case <-timer.C:
+			if err := l.poll(ctx); err != nil {
+				Logger.Error("Polling error", "error", err)
+			}
+			// Re-read each time so a config reload applies from the next poll
+			interval, jitter := l.config.vaultPollTiming(l.vaultConfig)
+			timer.Reset(interval + randomDelay(jitter))
+		}
+	}
+}
+
+// setLeader makes the listener fulfill only while this instance holds the leader lock
+func (l *EventListener) setLeader(leader *leaderElector) {
+	l.leader = leader
+	l.takeover = leader.Takeover()
+}
+
+// takeOver rescans the request history after this standby instance becomes the leader,
+// picking up anything the previous leader left unfulfilled
+func (l *EventListener) takeOver(ctx context.Context) error {
+	l.takeover = nil
+	Logger.Info("Now leader, rescanning for pending requests", "vault_name", l.vaultConfig.Name)
+	return l.scanPendingRequests(ctx, l.lastBlock)
+}
+
+// randomDelay returns a uniformly random duration in [0, bound), or 0 if bound is not positive
+func randomDelay(bound time.Duration) time.Duration {
+	if bound <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(bound)))
+}
+
+// subscribe streams request logs over a subscription. Whenever the subscription is
+// (re-)established, the blocks since the last processed block are backfilled with poll before
+// resuming the live stream, so events emitted while disconnected are not lost. Logs delivered
+// by both the backfill and the stream are suppressed by the dedupe cache.
+func (l *EventListener) subscribe(ctx context.Context) error {
+	query := requestEventsQuery(0, 0, []common.Address{l.vaultConfig.Address}, l.config.FulfillMode)
+	query.FromBlock, query.ToBlock = nil, nil // live logs only
+
+	for {
+		logs := make(chan types.Log, 128)
+		sub, err := l.client.SubscribeFilterLogs(ctx, query, logs)
+		if err != nil {
+			Logger.Warn("Failed to subscribe to logs, retrying",
+				"vault_name", l.vaultConfig.Name,
+				"error", err,
+			)
+		} else {
+			// Backfill the gap before consuming the live stream
+			if err := l.poll(ctx); err != nil {
+				Logger.Error("Backfill after subscribing failed", "vault_name", l.vaultConfig.Name, "error", err)
+			}
+
+			err = l.consume(ctx, sub, logs)
+			sub.Unsubscribe()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			Logger.Warn("Log subscription dropped, resubscribing",
+				"vault_name", l.vaultConfig.Name,
+				"last_block", l.lastBlock,
+				"error", err,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// consume processes subscription logs until the subscription fails or ctx is cancelled
+func (l *EventListener) consume(ctx context.Context, sub ethereum.Subscription, logs <-chan types.Log) error {
+	retry := time.NewTicker(breakerRetryInterval)
+	defer retry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case <-retry.C:
+			l.retryDeferred(ctx)
+		case <-l.takeover:
+			if err := l.takeOver(ctx); err != nil {
+				return err
+			}
+		case vLog := <-logs:
+			l.processLog(ctx, vLog)
+			// Later logs of the same block may still be in flight, so only earlier blocks count as complete
+			if vLog.BlockNumber > 0 && vLog.BlockNumber-1 > l.lastBlock {
+				l.setLastBlock(vLog.BlockNumber - 1)
+			}
+		}
+	}
+}
+
+// scanPendingRequests fulfills any deposits and withdrawals left pending while the engine was down.
+// It pulls request, fulfillment and cancellation logs from the later of SCAN_FROM_BLOCK and the
+// vault's start block to toBlock in chunks, and only checks on-chain status for requests without a
+// matching fulfillment or cancellation. MAX_HISTORICAL_SCAN limits this to the latest blocks.
+// Returns an error if the history cannot be fetched, so the listener does not run without the
+// backfill, or ctx.Err() if the scan was interrupted by shutdown.
+func (l *EventListener) scanPendingRequests(ctx context.Context, toBlock uint64) error {
+	started := time.Now()
+	fromBlock := l.config.ScanFromBlock
+	if l.vaultConfig.StartBlock > fromBlock {
+		fromBlock = l.vaultConfig.StartBlock
+	}
+	if limit := l.config.MaxHistoricalScan; limit > 0 && toBlock >= limit && toBlock-limit+1 > fromBlock {
+		Logger.Info("Limiting the request history scan to the latest blocks",
+			"vault_name", l.vaultConfig.Name,
+			"max_historical_scan", limit,
+			"skipped_blocks", toBlock-limit+1-fromBlock,
+		)
+		fromBlock = toBlock - limit + 1
+	}
+
+	Logger.Info("Scanning request history for pending requests",
+		"vault_name", l.vaultConfig.Name,
+		"from_block", fromBlock,
+		"to_block", toBlock,
+	)
+
+	logs, err := l.fetchLifecycleLogs(ctx, fromBlock, toBlock)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("vault %s: failed to scan request history: %v", l.vaultConfig.Name, err)
+	}
+
+	pending := pendingRequestLogs(logs)
+	summary := newScanSummary(started, logs, pending)
+	defer summary.log(l.vaultConfig.Name)
+
+	if kept := requestsFromStartIDs(pending, l.vaultConfig.StartDepositID, l.vaultConfig.StartWithdrawalID); len(kept) < len(pending) {
+		Logger.Info("Skipping historical requests below the configured start ids",
+			"vault_name", l.vaultConfig.Name,
+			"skipped", len(pending)-len(kept),
+			"start_deposit_id", l.vaultConfig.StartDepositID,
+			"start_withdrawal_id", l.vaultConfig.StartWithdrawalID,
+		)
+		summary.SkippedBeforeStart = len(pending) - len(kept)
+		pending = kept
+	}
+	if len(pending) == 0 {
+		Logger.Info("All historical requests already settled", "vault_name", l.vaultConfig.Name)
+		return nil
+	}
+
+	Logger.Info("Found unsettled historical requests",
+		"vault_name", l.vaultConfig.Name,
+		"count", len(pending),
+		"order", l.config.RequestOrder,
+	)
+	l.orderRequests(ctx, pending)
+
+	for _, vLog := range pending {
+		// Abort promptly on shutdown instead of finishing a long backfill
+		if err := ctx.Err(); err != nil {
+			Logger.Info("Historical scan cancelled", "vault_name", l.vaultConfig.Name)
+			summary.Cancelled = true
+			return err
+		}
+
+		// Skip requests older than MAX_REQUEST_AGE_SECONDS (likely abandoned or mispriced by now)
+		if ts := requestTimestamp(vLog); l.config.MaxRequestAge > 0 && ts > 0 {
+			requestedAt := time.Unix(int64(ts), 0)
+			if age := time.Since(requestedAt); age > l.config.MaxRequestAge {
+				Logger.Warn("Skipping stale historical request",
+					"vault_name", l.vaultConfig.Name,
+					"status", "skipped-stale",
+					"request_id", new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String(),
+					"requested_at", requestedAt.UTC().Format(time.RFC3339),
+					"age_seconds", int64(age.Seconds()),
+					"tx_hash", vLog.TxHash.Hex(),
+				)
+				summary.SkippedStale++
+				continue
+			}
+		}
+
+		// The handlers re-check on-chain status before fulfilling
+		// Still pending on-chain, so it needs action even if a standby instance recorded the log
+		l.seenLogs.Forget(logKey{txHash: vLog.TxHash, logIndex: vLog.Index})
+		summary.record(vLog, l.processLog(ctx, vLog))
+	}
+
+	return nil
+}
+
+// scanSummary tallies the outcome of a startup scan for the consolidated summary log
+type scanSummary struct {
+	started            time.Time
+	Examined           int      // request logs in the scanned range
+	AlreadySettled     int      // settled by a fulfillment/cancellation log or found settled on-chain
+	Fulfilled          int      // fulfilled by this scan
+	Held               int      // held for manual approval
+	Failed             int      // fulfillment attempted and failed
+	FailedIDs          []string // e.g. "deposit:12"
+	SkippedStale       int      // older than MAX_REQUEST_AGE_SECONDS
+	SkippedBeforeStart int      // below the vault's start ids
+	SkippedFiltered    int      // from users excluded by FULFILL_DENYLIST or FULFILL_ALLOWLIST
+	Deferred           int      // kept for later by an open circuit breaker
+	Cancelled          bool     // interrupted by shutdown
+}
+
+func newScanSummary(started time.Time, logs, pending []types.Log) *scanSummary {
+	examined := 0
+	for _, vLog := range logs {
+		if vLog.Removed || len(vLog.Topics) == 0 {
+			continue
+		}
+		if sig := vLog.Topics[0].Hex(); sig == depositRequestedSignature || sig == withdrawalRequestedSignature {
+			examined++
+		}
+	}
+	return &scanSummary{
+		started:        started,
+		Examined:       examined,
+		AlreadySettled: examined - len(pending),
+	}
+}
+
+// record tallies the outcome of processing one pending request log
+func (s *scanSummary) record(vLog types.Log, outcome requestOutcome) {
+	switch outcome {
+	case outcomeSettled:
+		s.AlreadySettled++
+	case outcomeFulfilled:
+		s.Fulfilled++
+	case outcomeHeld:
+		s.Held++
+	case outcomeFiltered:
+		s.SkippedFiltered++
+	case outcomeDeferred:
+		s.Deferred++
+	case outcomeFailed:
+		kind := "deposit"
+		if vLog.Topics[0].Hex() == withdrawalRequestedSignature {
+			kind = "withdrawal"
+		}
+		s.Failed++
+		s.FailedIDs = append(s.FailedIDs, kind+":"+new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String())
+	}
+}
+
+// log emits the summary as a single structured entry
+func (s *scanSummary) log(vaultName string) {
+	Logger.Info("Historical scan summary",
+		"vault_name", vaultName,
+		"examined", s.Examined,
+		"already_settled", s.AlreadySettled,
+		"fulfilled", s.Fulfilled,
+		"held", s.Held,
+		"failed", s.Failed,
+		"failed_ids", s.FailedIDs,
+		"skipped_stale", s.SkippedStale,
+		"skipped_before_start", s.SkippedBeforeStart,
+		"skipped_filtered", s.SkippedFiltered,
+		"deferred", s.Deferred,
+		"cancelled", s.Cancelled,
+		"duration_ms", time.Since(s.started).Milliseconds(),
+	)
+}
+
+// requestsFromStartIDs drops request logs whose id is below the start id for their type
+func requestsFromStartIDs(logs []types.Log, startDepositID, startWithdrawalID uint64) []types.Log {
+	if startDepositID == 0 && startWithdrawalID == 0 {
+		return logs
+	}
+
+	var kept []types.Log
+	for _, vLog := range logs {
+		start := startDepositID
+		if vLog.Topics[0] == common.HexToHash(withdrawalRequestedSignature) {
+			start = startWithdrawalID
+		}
+		if id := new(big.Int).SetBytes(vLog.Topics[2].Bytes()); id.Cmp(new(big.Int).SetUint64(start)) < 0 {
+			continue
+		}
+		kept = append(kept, vLog)
+	}
+	return kept
+}
+
+// fetchLifecycleLogs pulls the request lifecycle logs handled in FULFILL_MODE for this vault in LogChunkSize block ranges
+func (l *EventListener) fetchLifecycleLogs(ctx context.Context, fromBlock, toBlock uint64) ([]types.Log, error) {
+	var signatures []common.Hash
+	if l.config.FulfillMode != fulfillModeWithdrawals {
+		signatures = append(signatures,
+			common.HexToHash(depositRequestedSignature),
+			common.HexToHash(depositFulfilledSignature),
+			common.HexToHash(depositCancelledSignature),
+		)
+	}
+	if l.config.FulfillMode != fulfillModeDeposits {
+		signatures = append(signatures,
+			common.HexToHash(withdrawalRequestedSignature),
+			common.HexToHash(withdrawalFulfilledSignature),
+			common.HexToHash(withdrawalCancelledSignature),
+		)
+	}
+	topics := [][]common.Hash{signatures}
+
+	var logs []types.Log
+	for start := fromBlock; start <= toBlock; start += l.config.LogChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		end := min(start+l.config.LogChunkSize-1, toBlock)
+		query := ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{l.vaultConfig.Address},
+			Topics:    topics,
+		}
+
+		chunk, err := l.filterLogsRetrying(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch logs for blocks %d-%d: %v", start, end, err)
+		}
+		logs = append(logs, chunk...)
+	}
+	return logs, nil
+}
+
+// filterLogsRetrying runs a FilterLogs query up to scanChunkAttempts times, so a transient RPC
+// error does not abort a long history scan
+func (l *EventListener) filterLogsRetrying(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	delay := scanChunkRetryDelay
+	var err error
+	for attempt := 1; attempt <= scanChunkAttempts; attempt++ {
+		if attempt > 1 {
+			Logger.Warn("Retrying request history chunk",
+				"vault_name", l.vaultConfig.Name,
+				"from_block", query.FromBlock.Uint64(),
+				"to_block", query.ToBlock.Uint64(),
+				"attempt", attempt,
+				"error", err,
+			)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+		var chunk []types.Log
+		chunk, err = withCallTimeout(ctx, l.config.RPCCallTimeout, "FilterLogs", func(ctx context.Context) ([]types.Log, error) {
+			return l.client.FilterLogs(ctx, query)
+		})
+		if err == nil {
+			return chunk, nil
+		}
+	}
+	return nil, fmt.Errorf("after %d attempts: %v", scanChunkAttempts, err)
+}
+
+// pendingRequestLogs returns the DepositRequested/WithdrawalRequested logs whose id has no
+// corresponding fulfillment or cancellation log, in their original order
+func pendingRequestLogs(logs []types.Log) []types.Log {
+	type requestKey struct {
+		withdrawal bool
+		id         common.Hash
+	}
+
+	settled := make(map[requestKey]bool)
+	for _, vLog := range logs {
+		if vLog.Removed || len(vLog.Topics) < 3 {
+			continue
+		}
+		switch vLog.Topics[0] {
+		case common.HexToHash(depositFulfilledSignature), common.HexToHash(depositCancelledSignature):
+			settled[requestKey{withdrawal: false, id: vLog.Topics[2]}] = true
+		case common.HexToHash(withdrawalFulfilledSignature), common.HexToHash(withdrawalCancelledSignature):
+			settled[requestKey{withdrawal: true, id: vLog.Topics[2]}] = true
+		}
+	}
+
+	var pending []types.Log
+	for _, vLog := range logs {
+		if vLog.Removed || len(vLog.Topics) < 3 {
+			continue
+		}
+		switch vLog.Topics[0] {
+		case common.HexToHash(depositRequestedSignature):
+			if !settled[requestKey{withdrawal: false, id: vLog.Topics[2]}] {
+				pending = append(pending, vLog)
+			}
+		case common.HexToHash(withdrawalRequestedSignature):
+			if !settled[requestKey{withdrawal: true, id: vLog.Topics[2]}] {
+				pending = append(pending, vLog)
+			}
+		}
+	}
+	return pending
+}
+
+// requestEventsQuery builds a filter for the DepositRequested and/or WithdrawalRequested events handled in mode
+func requestEventsQuery(fromBlock, toBlock uint64, addresses []common.Address, mode string) ethereum.FilterQuery {
+	var signatures []common.Hash
+	if mode != fulfillModeWithdrawals {
+		signatures = append(signatures, common.HexToHash(depositRequestedSignature))
+	}
+	if mode != fulfillModeDeposits {
+		signatures = append(signatures, common.HexToHash(withdrawalRequestedSignature))
+	}
+	return ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: addresses,
+		Topics:    [][]common.Hash{signatures},
+	}
+}
+
+func (l *EventListener) poll(ctx context.Context) error {
+	// Get current block
+	header, err := withCallTimeout(ctx, l.config.RPCCallTimeout, "HeaderByNumber", func(ctx context.Context) (*types.Header, error) {
+		return l.client.HeaderByNumber(ctx, nil)
+	})
+	if err != nil {
+		return err
+	}
+	currentBlock := header.Number.Uint64()
+
+	l.retryDeferred(ctx)
+
+	if currentBlock <= l.lastBlock {
+		Logger.Debug("No new blocks", "current_block", currentBlock)
+		return nil
+	}
+
+	// Catch up in LOG_QUERY_CHUNK_SIZE windows, keeping progress if a later window fails
+	for l.lastBlock < currentBlock {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from := l.lastBlock + 1
+		to := pollChunkEnd(from, currentBlock, l.config.PollChunkSize)
+
+		Logger.Debug("Checking block range for events",
+			"from_block", from,
+			"to_block", to,
+		)
+
+		// Query for the DepositRequested and/or WithdrawalRequested events handled in FULFILL_MODE
+		query := requestEventsQuery(from, to, []common.Address{l.vaultConfig.Address}, l.config.FulfillMode)
+
+		logs, err := withCallTimeout(ctx, l.config.RPCCallTimeout, "FilterLogs", func(ctx context.Context) ([]types.Log, error) {
+			return l.client.FilterLogs(ctx, query)
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(logs) > 0 {
+			Logger.Info("Events detected", "event_count", len(logs))
+		}
+
+		l.orderRequests(ctx, logs)
+		for _, vLog := range logs {
+			l.processLog(ctx, vLog)
+		}
+
+		l.setLastBlock(to)
+	}
+	return nil
+}
+
+// pollChunkEnd returns the last block of the poll window starting at from (unbounded if size is 0)
+func pollChunkEnd(from, currentBlock, size uint64) uint64 {
+	if size == 0 {
+		return currentBlock
+	}
+	return min(from+size-1, currentBlock)
+}
+
+// errAlreadySettled is returned by the event handlers when the request is no longer pending on-chain
+var errAlreadySettled = errors.New("request already settled")
+
+// errUserFiltered is returned for requests skipped by FULFILL_DENYLIST or FULFILL_ALLOWLIST
+var errUserFiltered = errors.New("request user filtered")
+
+// errDryRun is returned by the fulfiller for requests it only logged because DRY_RUN is set
+var errDryRun = errors.New("dry run, fulfillment not sent")
+
+// errZeroAmount is returned by the fulfiller for requests with nothing to fulfill
+var errZeroAmount = errors.New("zero amount")
+
+// requestOutcome is the result of processing a request log
+type requestOutcome int
+
+const (
+	outcomeIgnored   requestOutcome = iota // removed by a reorg, duplicate, zero amount, or not a request log
+	outcomeSettled                         // already fulfilled or cancelled on-chain
+	outcomeFulfilled                       // fulfilled now
+	outcomeHeld                            // held for manual approval
+	outcomeFailed                          // handling or fulfillment failed
+	outcomeFiltered                        // user excluded by FULFILL_DENYLIST or FULFILL_ALLOWLIST
+	outcomeDeferred                        // kept for later while the vault's circuit breaker is open, fulfillment is paused or gas is above MAX_GAS_PRICE_GWEI
+)
+
+// userFilterReason returns why requests from user must not be fulfilled, or "" if they may be
+func userFilterReason(config *Config, user common.Address) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if config.FulfillDenylist[user] {
+		return "denylisted"
+	}
+	if len(config.FulfillAllowlist) > 0 && !config.FulfillAllowlist[user] {
+		return "not allowlisted"
+	}
+	return ""
+}
+
+// processLog dispatches a single DepositRequested or WithdrawalRequested log
+func (l *EventListener) processLog(ctx context.Context, vLog types.Log) requestOutcome {
+	// Skip logs the node has marked as removed by a reorg
+	if vLog.Removed {
+		Logger.Warn("Skipping log removed by reorg",
+			"block", vLog.BlockNumber,
+			"tx_hash", vLog.TxHash.Hex(),
+			"log_index", vLog.Index,
+		)
+		return outcomeIgnored
+	}
+
+	// Suppress logs already processed (re-delivered after a reorg)
+	if l.seenLogs.Seen(logKey{txHash: vLog.TxHash, logIndex: vLog.Index}) {
+		Logger.Warn("Duplicate log suppressed, possible reorg",
+			"vault_name", l.vaultConfig.Name,
+			"block", vLog.BlockNumber,
+			"block_hash", vLog.BlockHash.Hex(),
+			"tx_hash", vLog.TxHash.Hex(),
+			"log_index", vLog.Index,
+		)
+		return outcomeIgnored
+	}
+
+	// A standby only records the log; the leader fulfills it, or this instance rescans on takeover
+	if !l.leader.IsLeader() {
+		Logger.Debug("Standby instance, leaving request to the leader",
+			"vault_name", l.vaultConfig.Name,
+			"tx_hash", vLog.TxHash.Hex(),
+			"log_index", vLog.Index,
+		)
+		return outcomeIgnored
+	}
+
+	// While fulfillment is paused, keep the request until it resumes
+	sig := vLog.Topics[0].Hex()
+	isRequest := sig == depositRequestedSignature || sig == withdrawalRequestedSignature
+	if isRequest && fulfillmentPause.paused() {
+		l.deferred = append(l.deferred, vLog)
+		Logger.Info("Fulfillment paused, deferring request",
+			"vault_name", l.vaultConfig.Name,
+			"status", "deferred",
+			"request_id", new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String(),
+			"tx_hash", vLog.TxHash.Hex(),
+			"deferred", len(l.deferred),
+		)
+		return outcomeDeferred
+	}
+
+	// While the circuit breaker is open, keep the request until the vault recovers
+	if isRequest && !l.breaker.allow() {
+		l.deferred = append(l.deferred, vLog)
+		Logger.Info("Circuit breaker open, deferring request",
+			"vault_name", l.vaultConfig.Name,
+			"status", "deferred",
+			"request_id", new(big.Int).SetBytes(vLog.Topics[2].Bytes()).String(),
+			"tx_hash", vLog.TxHash.Hex(),
+			"deferred", len(l.deferred),
+		)
+		return outcomeDeferred
+	}
+
+	outcome := l.handleRequestLog(ctx, vLog)
+	if outcome == outcomeDeferred { // paused (engine or vault), or gas too expensive, while the request was being handled
+		l.deferred = append(l.deferred, vLog)
+	}
+	if ctx.Err() == nil { // a fulfillment interrupted by shutdown is not a vault failure
+		l.recordOutcome(outcome)
+	}
+	return outcome
+}
+
+// handleRequestLog fulfills the request of a DepositRequested or WithdrawalRequested log
+func (l *EventListener) handleRequestLog(ctx context.Context, vLog types.Log) requestOutcome {
+	// Check which event it is based on the first topic (event signature)
+	var kind string
+	var err error
+	switch vLog.Topics[0].Hex() {
+	case depositRequestedSignature:
+		kind = gasKindDeposit
+		err = l.handleDepositEvent(ctx, vLog)
+	case withdrawalRequestedSignature:
+		kind = gasKindWithdrawal
+		err = l.handleWithdrawalEvent(ctx, vLog)
+	default:
+		return outcomeIgnored
+	}
+
+	// Held, filtered, deferred and skipped requests are logged by the fulfiller
+	outcome := requestErrOutcome(err)
+	if outcome == outcomeFailed {
+		Logger.Error("Error handling "+kind+" event",
+			"block", vLog.BlockNumber,
+			"tx_hash", vLog.TxHash.Hex(),
+			"error", err,
+		)
+	}
+	return outcome
+}
+
+// requestErrOutcome classifies the result of handling a request log
+func requestErrOutcome(err error) requestOutcome {
+	switch {
+	case err == nil:
+		return outcomeFulfilled
+	case errors.Is(err, errAlreadySettled):
+		return outcomeSettled
+	case errors.Is(err, ErrRequestHeld):
+		return outcomeHeld
+	case errors.Is(err, errUserFiltered):
+		return outcomeFiltered
+	case errors.Is(err, errDryRun), errors.Is(err, errZeroAmount):
+		return outcomeIgnored
+	case errors.Is(err, errPaused), errors.Is(err, errVaultPaused), errors.Is(err, ErrGasPriceTooHigh):
+		return outcomeDeferred
+	default:
+		return outcomeFailed
+	}
+}
+
+// recordOutcome feeds a request outcome to the circuit breaker and reports state changes
+func (l *EventListener) recordOutcome(outcome requestOutcome) {
+	opened, closed := l.breaker.record(outcome)
+	if opened {
+		Logger.Error("Circuit breaker opened, pausing fulfillment for vault",
+			"vault_name", l.vaultConfig.Name,
+			"failures", l.breaker.threshold,
+			"cooldown", l.breaker.cooldown,
+		)
+		sendBreakerAlert(l.config.AlertWebhookURL, breakerAlert(l.vaultConfig, l.breaker.threshold, l.breaker.cooldown))
+	}
+	if closed {
+		Logger.Info("Circuit breaker closed, resuming fulfillment for vault",
+			"vault_name", l.vaultConfig.Name,
+			"deferred", len(l.deferred),
+		)
+	}
+}
+
+// retryDeferred processes the requests deferred by the circuit breaker, a pause or a gas spike once
+// requests may go through again. Each is tried at most once per call, since it may be deferred
+// again. After a breaker cooldown the first of them is the probe.
+func (l *EventListener) retryDeferred(ctx context.Context) {
+	for n := len(l.deferred); n > 0 && l.breaker.ready() && !fulfillmentPause.paused() && ctx.Err() == nil; n-- {
+		vLog := l.deferred[0]
+		l.deferred = l.deferred[1:]
+		// Recorded as seen when first received
+		l.seenLogs.Forget(logKey{txHash: vLog.TxHash, logIndex: vLog.Index})
+		l.processLog(ctx, vLog)
+	}
+}
+
+func (l *EventListener) handleDepositEvent(ctx context.Context, vLog types.Log) error {
+	// Topics: [0] = event signature, [1] = user (indexed), [2] = depositId (indexed)
+	// Data: quoteAmount, timestamp
+	event, err := unpackDepositRequested(vLog)
+	if err != nil {
+		return err
+	}
+	depositId := event.DepositId
+	quoteAmount := event.QuoteAmount
+	requestedAt := time.Unix(event.Timestamp.Int64(), 0)
+
+	Logger.Info("New deposit event received",
+		"deposit_id", depositId.String(),
+		"user", event.User.Hex(),
+		"quote_amount", quoteAmount.String(),
+		"requested_at", requestedAt.UTC(),
+		"block", vLog.BlockNumber,
+		"tx_hash", vLog.TxHash.Hex(),
+	)
+
+	if reason := userFilterReason(l.config, event.User); reason != "" {
+		Logger.Warn("Skipping deposit from filtered user",
+			"vault_name", l.vaultConfig.Name,
+			"status", "skipped-filtered",
+			"deposit_id", depositId.String(),
+			"user", event.User.Hex(),
+			"reason", reason,
+		)
+		return errUserFiltered
+	}
+
+	// Re-check on-chain status in case it was already fulfilled (e.g. the log was re-emitted by a reorg)
+	deposit, err := l.fulfiller.GetPendingDeposit(ctx, depositId)
+	if err != nil {
+		return fmt.Errorf("failed to check deposit status: %v", err)
+	}
+	if deposit.Fulfilled || deposit.User == (common.Address{}) {
+		Logger.Info("Deposit already fulfilled or cancelled, skipping",
+			"deposit_id", depositId.String(),
+		)
+		return errAlreadySettled
+	}
+
+	// Fulfill the deposit
+	_, err = l.fulfiller.FulfillDeposit(ctx, depositId, quoteAmount, requestedAt)
+	return err
+}
+
+func (l *EventListener) handleWithdrawalEvent(ctx context.Context, vLog types.Log) error {
+	// Topics: [0] = event signature, [1] = user (indexed), [2] = withdrawalId (indexed)
+	// Data: sharesAmount, timestamp
+	event, err := unpackWithdrawalRequested(vLog)
+	if err != nil {
+		return err
+	}
+	withdrawalId := event.WithdrawalId
+	sharesAmount := event.SharesAmount
+	requestedAt := time.Unix(event.Timestamp.Int64(), 0)
+
+	Logger.Info("New withdrawal event received",
+		"withdrawal_id", withdrawalId.String(),
+		"user", event.User.Hex(),
+		"shares_amount", sharesAmount.String(),
+		"requested_at", requestedAt.UTC(),
+		"block", vLog.BlockNumber,
+		"tx_hash", vLog.TxHash.Hex(),
+	)
+
+	if reason := userFilterReason(l.config, event.User); reason != "" {
+		Logger.Warn("Skipping withdrawal from filtered user",
+			"vault_name", l.vaultConfig.Name,
+			"status", "skipped-filtered",
+			"withdrawal_id", withdrawalId.String(),
+			"user", event.User.Hex(),
+			"reason", reason,
+		)
+		return errUserFiltered
+	}
+
+	// Re-check on-chain status in case it was already fulfilled (e.g. the log was re-emitted by a reorg)
+	withdrawal, err := l.fulfiller.GetPendingWithdrawal(ctx, withdrawalId)
+	if err != nil {
+		return fmt.Errorf("failed to check withdrawal status: %v", err)
+	}
+	if withdrawal.Fulfilled || withdrawal.User == (common.Address{}) {
+		Logger.Info("Withdrawal already fulfilled or cancelled, skipping",
+			"withdrawal_id", withdrawalId.String(),
+		)
+		return errAlreadySettled
+	}
+
+	// Fulfill the withdrawal
+	_, err = l.fulfiller.FulfillWithdrawal(ctx, withdrawalId, sharesAmount, requestedAt)
+	return err
+}
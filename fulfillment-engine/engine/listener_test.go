@@ -0,0 +1,523 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func lifecycleLog(signature string, id int64) types.Log {
+	return types.Log{
+		Topics: []common.Hash{
+			common.HexToHash(signature),
+			common.HexToHash("0x01"), // user
+			common.BigToHash(bigInts(id)[0]),
+		},
+	}
+}
+
+func TestEventTopicsMatchVaultEvents(t *testing.T) {
+	// keccak256 of the SectorVault event signatures
+	want := map[string]string{
+		depositRequestedSignature:    "0x827893a5f98dbfaba92dbe0bb2cafe8b9fd5573711d9768ce5cd4e2af44601ac",
+		withdrawalRequestedSignature: "0x38e3d972947cfef94205163d483d6287ef27eb312e20cb8e0b13a49989db232e",
+		depositFulfilledSignature:    "0x73e3270116e683df4a28817ae80a4a4d67f89f564a33c78f8059de568874c10a",
+		depositCancelledSignature:    "0xea6a5867aa6fded974ad8936ccc2cc7e154e2b0a31226d7a62c683af0fbae580",
+		withdrawalFulfilledSignature: "0x567289124f980c60ab6be9d631895db98cf8d567e8ef80f55d8be6474ad2d0a6",
+		withdrawalCancelledSignature: "0x609802616efe88a6b73a266ced98c5dfd07c25e64549e620527605107ea30e81",
+	}
+	for got, expected := range want {
+		if got != expected {
+			t.Errorf("topic %s, want %s", got, expected)
+		}
+	}
+	if len(want) != 6 {
+		t.Errorf("event topics are not distinct")
+	}
+}
+
+func TestUnpackDepositRequested(t *testing.T) {
+	vLog := lifecycleLog(depositRequestedSignature, 7)
+	vLog.Data = append(common.LeftPadBytes(big.NewInt(5000000).Bytes(), 32), common.LeftPadBytes(big.NewInt(1700000000).Bytes(), 32)...)
+
+	event, err := unpackDepositRequested(vLog)
+	if err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+	if event.DepositId.Int64() != 7 || event.QuoteAmount.Int64() != 5000000 || event.Timestamp.Int64() != 1700000000 {
+		t.Errorf("decoded id %v amount %v timestamp %v", event.DepositId, event.QuoteAmount, event.Timestamp)
+	}
+	if event.User != common.HexToAddress("0x01") {
+		t.Errorf("decoded user %s", event.User.Hex())
+	}
+
+	// The timestamp word is part of the layout, so a truncated log is rejected
+	vLog.Data = vLog.Data[:32]
+	if _, err := unpackDepositRequested(vLog); err == nil {
+		t.Error("expected an error for truncated event data")
+	}
+	if _, err := unpackWithdrawalRequested(vLog); err == nil {
+		t.Error("expected an error for truncated withdrawal event data")
+	}
+}
+
+func TestPendingRequestLogs(t *testing.T) {
+	removed := lifecycleLog(depositFulfilledSignature, 3)
+	removed.Removed = true
+
+	logs := []types.Log{
+		lifecycleLog(depositRequestedSignature, 1),
+		lifecycleLog(depositRequestedSignature, 2),
+		lifecycleLog(depositRequestedSignature, 3),
+		lifecycleLog(withdrawalRequestedSignature, 1),
+		lifecycleLog(withdrawalRequestedSignature, 2),
+		lifecycleLog(depositFulfilledSignature, 1),
+		lifecycleLog(depositCancelledSignature, 2),
+		lifecycleLog(withdrawalFulfilledSignature, 2),
+		removed,
+	}
+
+	pending := pendingRequestLogs(logs)
+
+	want := []struct {
+		signature string
+		id        int64
+	}{
+		{depositRequestedSignature, 3},    // fulfillment log was reorged out
+		{withdrawalRequestedSignature, 1}, // deposit 1 settling must not settle withdrawal 1
+	}
+	if len(pending) != len(want) {
+		t.Fatalf("got %d pending logs, want %d", len(pending), len(want))
+	}
+	for i, w := range want {
+		if pending[i].Topics[0] != common.HexToHash(w.signature) || pending[i].Topics[2].Big().Int64() != w.id {
+			t.Errorf("pending[%d] = %s id %d, want %s id %d",
+				i, pending[i].Topics[0].Hex(), pending[i].Topics[2].Big().Int64(), w.signature, w.id)
+		}
+	}
+}
+
+func TestScanSummary(t *testing.T) {
+	logs := []types.Log{
+		lifecycleLog(depositRequestedSignature, 1),
+		lifecycleLog(depositRequestedSignature, 2),
+		lifecycleLog(depositRequestedSignature, 3),
+		lifecycleLog(withdrawalRequestedSignature, 1),
+		lifecycleLog(depositFulfilledSignature, 1),
+	}
+	pending := pendingRequestLogs(logs)
+
+	summary := newScanSummary(time.Now(), logs, pending)
+	summary.record(pending[0], outcomeFulfilled)
+	summary.record(pending[1], outcomeSettled)
+	summary.record(pending[2], outcomeFailed)
+
+	if summary.Examined != 4 || summary.AlreadySettled != 2 || summary.Fulfilled != 1 || summary.Failed != 1 {
+		t.Errorf("summary = %+v, want 4 examined, 2 already settled, 1 fulfilled, 1 failed", summary)
+	}
+	if len(summary.FailedIDs) != 1 || summary.FailedIDs[0] != "withdrawal:1" {
+		t.Errorf("failed ids = %v, want [withdrawal:1]", summary.FailedIDs)
+	}
+}
+
+func TestRequestsFromStartIDs(t *testing.T) {
+	logs := []types.Log{
+		lifecycleLog(depositRequestedSignature, 4),
+		lifecycleLog(depositRequestedSignature, 5),
+		lifecycleLog(withdrawalRequestedSignature, 1),
+		lifecycleLog(withdrawalRequestedSignature, 9),
+	}
+
+	kept := requestsFromStartIDs(logs, 5, 2)
+
+	want := []types.Log{logs[1], logs[3]}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d logs, want %d", len(kept), len(want))
+	}
+	for i := range want {
+		if kept[i].Topics[0] != want[i].Topics[0] || kept[i].Topics[2] != want[i].Topics[2] {
+			t.Errorf("kept[%d] = %s id %d, want id %d", i, kept[i].Topics[0].Hex(), kept[i].Topics[2].Big(), want[i].Topics[2].Big())
+		}
+	}
+}
+
+// fakeSubscription implements ethereum.Subscription
+type fakeSubscription struct {
+	err chan error
+}
+
+func (s *fakeSubscription) Unsubscribe()      {}
+func (s *fakeSubscription) Err() <-chan error { return s.err }
+
+// subscriptionStep scripts one subscription: the chain head when it is established
+// and the logs it delivers before dropping
+type subscriptionStep struct {
+	head uint64
+	logs []types.Log
+}
+
+// fakeListenerClient serves FilterLogs from a fixed chain and scripted subscriptions
+type fakeListenerClient struct {
+	mu    sync.Mutex
+	chain []types.Log
+	steps []subscriptionStep
+	head  uint64
+	done  chan struct{}
+	once  sync.Once
+
+	queries   [][2]uint64 // FilterLogs ranges requested
+	failQuery int         // 1-based FilterLogs call to fail (0: never)
+	failFrom  int         // 1-based FilterLogs call from which all calls fail (0: never)
+}
+
+func (c *fakeListenerClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &types.Header{Number: new(big.Int).SetUint64(c.head)}, nil
+}
+
+func (c *fakeListenerClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	c.queries = append(c.queries, [2]uint64{query.FromBlock.Uint64(), query.ToBlock.Uint64()})
+	fail := len(c.queries) == c.failQuery || (c.failFrom > 0 && len(c.queries) >= c.failFrom)
+	c.mu.Unlock()
+	if fail {
+		return nil, fmt.Errorf("query returned more than 10000 results")
+	}
+
+	var logs []types.Log
+	for _, vLog := range c.chain {
+		if vLog.BlockNumber >= query.FromBlock.Uint64() && vLog.BlockNumber <= query.ToBlock.Uint64() {
+			logs = append(logs, vLog)
+		}
+	}
+	return logs, nil
+}
+
+func (c *fakeListenerClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.steps) == 0 {
+		c.once.Do(func() { close(c.done) })
+		return nil, fmt.Errorf("script finished")
+	}
+	step := c.steps[0]
+	c.steps = c.steps[1:]
+	c.head = step.head
+
+	sub := &fakeSubscription{err: make(chan error, 1)}
+	go func() {
+		for _, vLog := range step.logs {
+			ch <- vLog
+		}
+		// Drop only once every delivered log has been picked up
+		for len(ch) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		sub.err <- fmt.Errorf("connection reset")
+	}()
+	return sub, nil
+}
+
+func TestSubscribeBackfillsDroppedBlocks(t *testing.T) {
+	defer func(d time.Duration) { resubscribeDelay = d }(resubscribeDelay)
+	resubscribeDelay = 0
+
+	requestLog := func(block uint64, index uint, id int64) types.Log {
+		vLog := lifecycleLog(depositRequestedSignature, id)
+		vLog.BlockNumber = block
+		vLog.Index = index
+		vLog.TxHash = common.BigToHash(big.NewInt(id))
+		return vLog
+	}
+	a := requestLog(11, 0, 1)
+	b1 := requestLog(12, 0, 2)
+	b2 := requestLog(12, 1, 3) // same block as b1, emitted after the first subscription dropped
+	c := requestLog(13, 0, 4)  // emitted while disconnected
+	d := requestLog(14, 0, 5)
+
+	client := &fakeListenerClient{
+		chain: []types.Log{a, b1, b2, c, d},
+		steps: []subscriptionStep{
+			{head: 10, logs: []types.Log{a, b1}},
+			{head: 13, logs: []types.Log{d}},
+		},
+		done: make(chan struct{}),
+	}
+
+	l := NewEventListener(client, &Config{}, VaultConfig{Name: "Test"}, nil)
+	l.lastBlock = 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- l.subscribe(ctx) }()
+
+	select {
+	case <-client.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for subscriptions")
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("subscribe returned %v, want context.Canceled", err)
+	}
+
+	for _, vLog := range client.chain {
+		if !l.seenLogs.Seen(logKey{txHash: vLog.TxHash, logIndex: vLog.Index}) {
+			t.Errorf("log in block %d index %d was never processed", vLog.BlockNumber, vLog.Index)
+		}
+	}
+}
+
+func TestPollChunksRange(t *testing.T) {
+	client := &fakeListenerClient{head: 25, failQuery: 2}
+	l := NewEventListener(client, &Config{PollChunkSize: 10}, VaultConfig{Name: "Test"}, nil)
+
+	if err := l.poll(context.Background()); err == nil {
+		t.Fatal("expected the second window to fail")
+	}
+	if l.lastBlock != 10 {
+		t.Errorf("lastBlock after a failed window = %d, want 10 (first window kept)", l.lastBlock)
+	}
+
+	if err := l.poll(context.Background()); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	want := [][2]uint64{{1, 10}, {11, 20}, {11, 20}, {21, 25}}
+	if fmt.Sprint(client.queries) != fmt.Sprint(want) {
+		t.Errorf("queried %v, want %v", client.queries, want)
+	}
+	if l.lastBlock != 25 {
+		t.Errorf("lastBlock = %d, want 25", l.lastBlock)
+	}
+}
+
+func TestScanMaxHistoricalScan(t *testing.T) {
+	client := &fakeListenerClient{head: 100}
+	l := NewEventListener(client, &Config{LogChunkSize: 1000, MaxHistoricalScan: 30}, VaultConfig{Name: "Test"}, nil)
+	if err := l.scanPendingRequests(context.Background(), 100); err != nil {
+		t.Fatalf("scanPendingRequests: %v", err)
+	}
+
+	// A later start block still wins over the limit
+	l.vaultConfig.StartBlock = 90
+	if err := l.scanPendingRequests(context.Background(), 100); err != nil {
+		t.Fatalf("scanPendingRequests: %v", err)
+	}
+
+	want := [][2]uint64{{71, 100}, {90, 100}}
+	if fmt.Sprint(client.queries) != fmt.Sprint(want) {
+		t.Errorf("queried %v, want %v", client.queries, want)
+	}
+}
+
+func TestScanRetriesChunks(t *testing.T) {
+	defer func(d time.Duration) { scanChunkRetryDelay = d }(scanChunkRetryDelay)
+	scanChunkRetryDelay = 0
+
+	// A transient error is retried and the scan completes
+	client := &fakeListenerClient{head: 25, failQuery: 2}
+	l := NewEventListener(client, &Config{LogChunkSize: 10, ScanFromBlock: 1}, VaultConfig{Name: "Test"}, nil)
+	if err := l.scanPendingRequests(context.Background(), 25); err != nil {
+		t.Fatalf("scanPendingRequests: %v", err)
+	}
+	want := [][2]uint64{{1, 10}, {11, 20}, {11, 20}, {21, 25}}
+	if fmt.Sprint(client.queries) != fmt.Sprint(want) {
+		t.Errorf("queried %v, want %v", client.queries, want)
+	}
+
+	// A chunk that keeps failing fails the scan instead of skipping the backfill
+	client = &fakeListenerClient{head: 25, failFrom: 2}
+	l = NewEventListener(client, &Config{LogChunkSize: 10, ScanFromBlock: 1}, VaultConfig{Name: "Test"}, nil)
+	if err := l.scanPendingRequests(context.Background(), 25); err == nil {
+		t.Fatal("scanPendingRequests succeeded with a failing chunk")
+	}
+	if len(client.queries) != 1+scanChunkAttempts {
+		t.Errorf("made %d queries, want 1 plus %d attempts", len(client.queries), scanChunkAttempts)
+	}
+}
+
+func TestRequestEventsQueryFulfillMode(t *testing.T) {
+	deposit := common.HexToHash(depositRequestedSignature)
+	withdrawal := common.HexToHash(withdrawalRequestedSignature)
+
+	tests := []struct {
+		mode string
+		want []common.Hash
+	}{
+		{mode: fulfillModeBoth, want: []common.Hash{deposit, withdrawal}},
+		{mode: fulfillModeDeposits, want: []common.Hash{deposit}},
+		{mode: fulfillModeWithdrawals, want: []common.Hash{withdrawal}},
+	}
+	for _, tt := range tests {
+		query := requestEventsQuery(1, 2, nil, tt.mode)
+		if len(query.Topics) != 1 || fmt.Sprint(query.Topics[0]) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: topics = %v, want [%v]", tt.mode, query.Topics, tt.want)
+		}
+	}
+}
+
+func TestRandomDelay(t *testing.T) {
+	if d := randomDelay(0); d != 0 {
+		t.Errorf("randomDelay(0) = %v, want 0", d)
+	}
+	for i := 0; i < 100; i++ {
+		if d := randomDelay(time.Second); d < 0 || d >= time.Second {
+			t.Fatalf("randomDelay(1s) = %v, want [0, 1s)", d)
+		}
+	}
+}
+
+// fakeFulfillment records fulfill calls and serves request state from maps
+type fakeFulfillment struct {
+	deposits    map[int64]*PendingDeposit
+	withdrawals map[int64]*PendingWithdrawal
+	fulfilled   []string // "deposit:<id>:<amount>:<requestedAt>"
+	err         error    // returned by every fulfill call
+}
+
+func (f *fakeFulfillment) FulfillDeposit(ctx context.Context, depositId *big.Int, quoteAmount *big.Int, requestedAt time.Time) (common.Hash, error) {
+	f.fulfilled = append(f.fulfilled, fmt.Sprintf("deposit:%s:%s:%d", depositId, quoteAmount, requestedAt.Unix()))
+	return common.Hash{}, f.err
+}
+
+func (f *fakeFulfillment) FulfillWithdrawal(ctx context.Context, withdrawalId *big.Int, sharesAmount *big.Int, requestedAt time.Time) (common.Hash, error) {
+	f.fulfilled = append(f.fulfilled, fmt.Sprintf("withdrawal:%s:%s:%d", withdrawalId, sharesAmount, requestedAt.Unix()))
+	return common.Hash{}, f.err
+}
+
+func (f *fakeFulfillment) GetNextDepositId(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(int64(len(f.deposits))), nil
+}
+
+func (f *fakeFulfillment) GetPendingDeposit(ctx context.Context, depositId *big.Int) (*PendingDeposit, error) {
+	if d, ok := f.deposits[depositId.Int64()]; ok {
+		return d, nil
+	}
+	return &PendingDeposit{}, nil
+}
+
+func (f *fakeFulfillment) GetNextWithdrawalId(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(int64(len(f.withdrawals))), nil
+}
+
+func (f *fakeFulfillment) GetPendingWithdrawal(ctx context.Context, withdrawalId *big.Int) (*PendingWithdrawal, error) {
+	if w, ok := f.withdrawals[withdrawalId.Int64()]; ok {
+		return w, nil
+	}
+	return &PendingWithdrawal{}, nil
+}
+
+func (f *fakeFulfillment) calculateWithdrawalValue(ctx context.Context, sharesAmount *big.Int) (*big.Int, error) {
+	return sharesAmount, nil
+}
+
+func (f *fakeFulfillment) setProcessedBlock(block uint64) {}
+
+func TestProcessLogFulfillsPendingRequests(t *testing.T) {
+	user := common.HexToAddress("0x01")
+	fake := &fakeFulfillment{
+		deposits: map[int64]*PendingDeposit{
+			1: {User: user},
+			2: {User: user, Fulfilled: true},
+		},
+		withdrawals: map[int64]*PendingWithdrawal{
+			3: {User: user},
+		},
+	}
+	l := NewEventListener(&fakeListenerClient{}, &Config{}, VaultConfig{Name: "Test"}, fake)
+
+	requestLog := func(signature string, id, amount, timestamp int64) types.Log {
+		vLog := lifecycleLog(signature, id)
+		vLog.TxHash = common.BigToHash(big.NewInt(id))
+		vLog.Data = append(common.LeftPadBytes(big.NewInt(amount).Bytes(), 32), common.LeftPadBytes(big.NewInt(timestamp).Bytes(), 32)...)
+		return vLog
+	}
+	deposit := requestLog(depositRequestedSignature, 1, 5000000, 1700000000)
+
+	tests := []struct {
+		name string
+		vLog types.Log
+		want requestOutcome
+	}{
+		{name: "pending deposit", vLog: deposit, want: outcomeFulfilled},
+		{name: "duplicate deposit log", vLog: deposit, want: outcomeIgnored},
+		{name: "fulfilled deposit", vLog: requestLog(depositRequestedSignature, 2, 7000000, 1700000010), want: outcomeSettled},
+		{name: "cancelled deposit", vLog: requestLog(depositRequestedSignature, 4, 7000000, 1700000010), want: outcomeSettled},
+		{name: "pending withdrawal", vLog: requestLog(withdrawalRequestedSignature, 3, 42, 1700000020), want: outcomeFulfilled},
+	}
+	for _, tt := range tests {
+		if got := l.processLog(context.Background(), tt.vLog); got != tt.want {
+			t.Errorf("%s: outcome = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	want := []string{"deposit:1:5000000:1700000000", "withdrawal:3:42:1700000020"}
+	if fmt.Sprint(fake.fulfilled) != fmt.Sprint(want) {
+		t.Errorf("fulfilled = %v, want %v", fake.fulfilled, want)
+	}
+}
+
+func TestProcessLogSkipsFilteredUsers(t *testing.T) {
+	user := common.HexToAddress("0x01") // the user topic of lifecycleLog
+	fake := &fakeFulfillment{deposits: map[int64]*PendingDeposit{1: {User: user}}}
+	vLog := lifecycleLog(depositRequestedSignature, 1)
+	vLog.Data = make([]byte, 64)
+	vLog.Data[31] = 1
+
+	tests := []struct {
+		name   string
+		config *Config
+		want   requestOutcome
+	}{
+		{name: "denylisted", config: &Config{FulfillDenylist: map[common.Address]bool{user: true}}, want: outcomeFiltered},
+		{name: "not allowlisted", config: &Config{FulfillAllowlist: map[common.Address]bool{common.HexToAddress("0x02"): true}}, want: outcomeFiltered},
+		{name: "allowlisted", config: &Config{FulfillAllowlist: map[common.Address]bool{user: true}}, want: outcomeFulfilled},
+	}
+	for _, tt := range tests {
+		fake.fulfilled = nil
+		l := NewEventListener(&fakeListenerClient{}, tt.config, VaultConfig{Name: "Test"}, fake)
+		if got := l.processLog(context.Background(), vLog); got != tt.want {
+			t.Errorf("%s: outcome = %v, want %v", tt.name, got, tt.want)
+		}
+		if fulfilled := len(fake.fulfilled) > 0; fulfilled != (tt.want == outcomeFulfilled) {
+			t.Errorf("%s: fulfilled = %v", tt.name, fake.fulfilled)
+		}
+	}
+}
+
+func TestProcessLogDefersWhilePaused(t *testing.T) {
+	user := common.HexToAddress("0x01")
+	fake := &fakeFulfillment{
+		deposits:    map[int64]*PendingDeposit{1: {User: user}},
+		withdrawals: map[int64]*PendingWithdrawal{},
+	}
+	l := NewEventListener(&fakeListenerClient{}, &Config{}, VaultConfig{Name: "Test"}, fake)
+	vLog := lifecycleLog(depositRequestedSignature, 1)
+	vLog.Data = append(common.LeftPadBytes(big.NewInt(1000000).Bytes(), 32), common.LeftPadBytes(big.NewInt(1700000000).Bytes(), 32)...)
+
+	fulfillmentPause.pause()
+	t.Cleanup(func() { fulfillmentPause.resume() })
+
+	ctx := context.Background()
+	if outcome := l.processLog(ctx, vLog); outcome != outcomeDeferred {
+		t.Fatalf("outcome %v while paused, want deferred", outcome)
+	}
+	l.retryDeferred(ctx)
+	if len(fake.fulfilled) != 0 || len(l.deferred) != 1 {
+		t.Fatalf("fulfilled %v, deferred %d while paused", fake.fulfilled, len(l.deferred))
+	}
+
+	fulfillmentPause.resume()
+	l.retryDeferred(ctx)
+	if len(fake.fulfilled) != 1 || len(l.deferred) != 0 {
+		t.Fatalf("after resume: fulfilled %v, deferred %d", fake.fulfilled, len(l.deferred))
+	}
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var Logger *slog.Logger
+
+// logLevel is the minimum level of Logger, changed at runtime by a config reload
+var logLevel = new(slog.LevelVar)
+
+// InitLogger initializes the global logger with the specified configuration, writing to out
+func InitLogger(level, format string, out io.Writer) {
+	logLevel.Set(parseLogLevel(level))
+
+	var handler slog.Handler
+	opts := &slog.HandlerOptions{
+		Level: logLevel,
+	}
+
+	// Use JSON format for production, text for development
+	if strings.ToUpper(format) == "JSON" {
+		handler = slog.NewJSONHandler(out, opts)
+	} else {
+		handler = slog.NewTextHandler(out, opts)
+	}
+
+	Logger = slog.New(handler)
+}
+
+// parseLogLevel maps a LOG_LEVEL value to a slog level (INFO if unrecognized)
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN", "WARNING":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// OpenLogOutput returns the log destination: stdout, or the rotating LOG_FILE (plus stdout
+// with LOG_STDOUT=true). The returned file must be closed on exit and is nil for stdout only.
+func OpenLogOutput(config *Config) (io.Writer, io.Closer, error) {
+	if config.LogFile == "" {
+		return os.Stdout, nil, nil
+	}
+
+	file, err := openRotatingFile(config.LogFile, config.LogMaxSize, config.LogMaxFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.LogStdout {
+		return io.MultiWriter(file, os.Stdout), file, nil
+	}
+	return file, file, nil
+}
+
+// newCorrelationID returns a short random id that ties together the log lines of one operation
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Webhook request timeout
+	alertWebhookTimeout = 10 * time.Second
+)
+
+// monitoredToken is a token whose fulfiller balance is checked against a threshold
+type monitoredToken struct {
+	address   common.Address
+	kind      string     // "quote" or "underlying"
+	threshold *big.Int   // alert when balance drops below this (read from config on each check)
+	fulfiller *Fulfiller // used to read the balance
+}
+
+// BalanceAlert is the JSON payload posted to the alert webhook.
+// The Text field makes it directly consumable by Slack incoming webhooks.
+type BalanceAlert struct {
+	Text      string `json:"text"`
+	Fulfiller string `json:"fulfiller"`
+	Token     string `json:"token"`
+	TokenKind string `json:"token_kind"`
+	Balance   string `json:"balance"`
+	Threshold string `json:"threshold"`
+}
+
+// BalanceMonitor periodically checks the fulfiller's token balances and posts
+// an alert to a webhook when any balance drops below its configured threshold
+type BalanceMonitor struct {
+	config      *Config
+	account     *fulfillerAccount
+	tokens      []monitoredToken
+	httpClient  *http.Client
+	lastAlerted map[common.Address]time.Time // debounce state, cleared once balance recovers
+	lastTopUp   map[common.Address]time.Time // top-up request times, cleared once balance recovers
+}
+
+func NewBalanceMonitor(config *Config, account *fulfillerAccount, fulfillers []*Fulfiller) *BalanceMonitor {
+	m := &BalanceMonitor{
+		config:      config,
+		account:     account,
+		httpClient:  &http.Client{Timeout: alertWebhookTimeout},
+		lastAlerted: make(map[common.Address]time.Time),
+		lastTopUp:   make(map[common.Address]time.Time),
+	}
+
+	// Collect the distinct set of tokens across all vaults (tokens may be shared). Thresholds are
+	// looked up on each check, so a config reload can add, change or remove them.
+	seen := make(map[common.Address]bool)
+	for _, f := range fulfillers {
+		if !seen[f.quoteTokenAddress] {
+			seen[f.quoteTokenAddress] = true
+			m.tokens = append(m.tokens, monitoredToken{
+				address:   f.quoteTokenAddress,
+				kind:      "quote",
+				fulfiller: f,
+			})
+		}
+
+		for _, token := range f.underlyingTokens {
+			if seen[token] {
+				continue
+			}
+			seen[token] = true
+			m.tokens = append(m.tokens, monitoredToken{
+				address:   token,
+				kind:      "underlying",
+				fulfiller: f,
+			})
+		}
+	}
+
+	return m
+}
+
+// Start runs the balance check loop until the context is cancelled
+func (m *BalanceMonitor) Start(ctx context.Context) error {
+	if len(m.tokens) == 0 {
+		Logger.Info("Balance monitor has no tokens to watch, not starting")
+		return nil
+	}
+
+	Logger.Info("Balance monitor started",
+		"token_count", m.thresholdCount(),
+		"check_interval", m.config.BalanceCheckInterval,
+		"alert_cooldown", m.config.BalanceAlertCooldown,
+	)
+
+	// Check once immediately so a low balance is reported at startup
+	m.check(ctx)
+
+	ticker := time.NewTicker(m.config.BalanceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+// thresholdCount returns how many watched tokens currently have a threshold
+func (m *BalanceMonitor) thresholdCount() int {
+	count := 0
+	for _, t := range m.tokens {
+		if m.config.balanceThreshold(t.kind, t.address) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *BalanceMonitor) check(ctx context.Context) {
+	for _, t := range m.tokens {
+		if t.threshold = m.config.balanceThreshold(t.kind, t.address); t.threshold == nil {
+			continue
+		}
+
+		balance, err := t.fulfiller.getTokenBalance(ctx, t.address, m.account.fromAddress)
+		if err != nil {
+			Logger.Warn("Failed to check fulfiller balance",
+				"token", t.address.Hex(),
+				"error", err,
+			)
+			continue
+		}
+
+		if balance.Cmp(t.threshold) >= 0 {
+			if _, alerted := m.lastAlerted[t.address]; alerted {
+				Logger.Info("Fulfiller balance recovered above threshold",
+					"token", t.address.Hex(),
+					"balance", balance.String(),
+					"threshold", t.threshold.String(),
+				)
+				delete(m.lastAlerted, t.address)
+			}
+			delete(m.lastTopUp, t.address)
+			continue
+		}
+
+		Logger.Warn("Fulfiller balance below threshold",
+			"token", t.address.Hex(),
+			"token_kind", t.kind,
+			"balance", balance.String(),
+			"threshold", t.threshold.String(),
+		)
+
+		m.requestTopUp(t, balance)
+
+		// Debounce: only re-alert once the cooldown has elapsed
+		if last, alerted := m.lastAlerted[t.address]; alerted && time.Since(last) < m.config.BalanceAlertCooldown {
+			continue
+		}
+
+		if err := m.sendAlert(ctx, t, balance); err != nil {
+			Logger.Error("Failed to send balance alert",
+				"token", t.address.Hex(),
+				"error", err,
+			)
+			continue
+		}
+		m.lastAlerted[t.address] = time.Now()
+	}
+}
+
+// requestTopUp asks the top-up hook to refund a token in the background. A token is requested at
+// most once per TopUpCooldown, even if the request fails, so a slow treasury is not asked twice.
+func (m *BalanceMonitor) requestTopUp(t monitoredToken, balance *big.Int) {
+	webhookURL, command, cooldown := m.config.TopUpWebhookURL, m.config.TopUpCommand, m.config.TopUpCooldown
+	if webhookURL == "" && command == "" {
+		return
+	}
+	if last, requested := m.lastTopUp[t.address]; requested && time.Since(last) < cooldown {
+		return
+	}
+	m.lastTopUp[t.address] = time.Now()
+
+	req := TopUpRequest{
+		Fulfiller: m.account.fromAddress.Hex(),
+		Token:     t.address.Hex(),
+		TokenKind: t.kind,
+		Balance:   balance.String(),
+		Threshold: t.threshold.String(),
+		Shortfall: new(big.Int).Sub(t.threshold, balance).String(),
+	}
+	Logger.Info("Requesting fulfiller top-up",
+		"token", req.Token,
+		"token_kind", req.TokenKind,
+		"shortfall", req.Shortfall,
+	)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), topUpTimeout)
+		defer cancel()
+		if err := sendTopUp(ctx, m.httpClient, webhookURL, command, req); err != nil {
+			Logger.Error("Failed to request fulfiller top-up", "token", req.Token, "error", err)
+		}
+	}()
+}
+
+func (m *BalanceMonitor) sendAlert(ctx context.Context, t monitoredToken, balance *big.Int) error {
+	if m.config.AlertWebhookURL == "" {
+		return nil
+	}
+
+	alert := BalanceAlert{
+		Text: fmt.Sprintf("Low fulfiller balance: %s token %s has %s, threshold %s (fulfiller %s)",
+			t.kind, t.address.Hex(), balance.String(), t.threshold.String(), m.account.fromAddress.Hex()),
+		Fulfiller: m.account.fromAddress.Hex(),
+		Token:     t.address.Hex(),
+		TokenKind: t.kind,
+		Balance:   balance.String(),
+		Threshold: t.threshold.String(),
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+
+	Logger.Info("Balance alert sent",
+		"token", t.address.Hex(),
+		"balance", balance.String(),
+		"threshold", t.threshold.String(),
+	)
+	return nil
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// SharePrice is the net asset value of one whole sector token
+type SharePrice struct {
+	VaultName     string `json:"vault_name"`
+	Price         string `json:"price"` // quote token base units per whole share
+	QuoteDecimals uint8  `json:"quote_decimals"`
+	TotalValue    string `json:"total_value"` // getTotalValue, in oracle decimals
+	TotalSupply   string `json:"total_supply"`
+}
+
+// sharePrice returns the quote token value (base units) of one whole share:
+// totalValue * 10^shareDecimals / totalSupply, rescaled from oracle to quote decimals.
+// Rounds down like the vault's calculateWithdrawalValue.
+func sharePrice(totalValue, totalSupply *big.Int, shareDecimals, oracleDecimals, quoteDecimals uint8) (*big.Int, error) {
+	if totalSupply.Sign() <= 0 {
+		return nil, fmt.Errorf("sector token supply is zero")
+	}
+
+	shareMultiplier := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shareDecimals)), nil)
+	price := new(big.Int).Mul(totalValue, shareMultiplier)
+	if quoteDecimals >= oracleDecimals {
+		price.Mul(price, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(quoteDecimals-oracleDecimals)), nil))
+		return price.Div(price, totalSupply), nil
+	}
+	price.Div(price, totalSupply)
+	return price.Div(price, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(oracleDecimals-quoteDecimals)), nil)), nil
+}
+
+// GetSharePrice computes the vault's NAV per share from getTotalValue and the sector token supply
+func (f *Fulfiller) GetSharePrice(ctx context.Context) (*SharePrice, error) {
+	totalValue, err := f.getTotalValue(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total value: %v", err)
+	}
+
+	sectorToken, err := f.getSectorTokenAddress(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sector token address: %v", err)
+	}
+	shareDecimals, err := f.getTokenDecimals(ctx, sectorToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sector token decimals: %v", err)
+	}
+	totalSupply, err := f.getSectorTokenTotalSupply(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sector token supply: %v", err)
+	}
+
+	price, err := sharePrice(totalValue, totalSupply, shareDecimals, f.oracleDecimals, f.quoteDecimals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SharePrice{
+		VaultName:     f.vaultConfig.Name,
+		Price:         price.String(),
+		QuoteDecimals: f.quoteDecimals,
+		TotalValue:    totalValue.String(),
+		TotalSupply:   totalSupply.String(),
+	}, nil
+}
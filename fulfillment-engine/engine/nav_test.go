@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSharePrice(t *testing.T) {
+	tests := []struct {
+		name                          string
+		totalValue, totalSupply       string
+		shareDec, oracleDec, quoteDec uint8
+		want                          string
+	}{
+		// $1,500 NAV (8-decimal oracle) over 1,000 shares -> 1.5 USDC per share
+		{"oracle above quote", "150000000000", "1000000000000000000000", 18, 8, 6, "1500000"},
+		// $1,500 NAV (6-decimal oracle) over 1,000 shares, 18-decimal quote token
+		{"oracle below quote", "1500000000", "1000000000000000000000", 18, 6, 18, "1500000000000000000"},
+		{"rounds down", "1000000", "3000000", 6, 6, 6, "333333"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totalValue, _ := new(big.Int).SetString(tt.totalValue, 10)
+			totalSupply, _ := new(big.Int).SetString(tt.totalSupply, 10)
+			got, err := sharePrice(totalValue, totalSupply, tt.shareDec, tt.oracleDec, tt.quoteDec)
+			if err != nil {
+				t.Fatalf("sharePrice: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("sharePrice = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := sharePrice(big.NewInt(1), big.NewInt(0), 18, 8, 6); err == nil {
+		t.Error("expected an error for zero supply")
+	}
+}
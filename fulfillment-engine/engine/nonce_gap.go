@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Consecutive transaction timeouts before checking for a nonce gap
+const nonceGapTimeoutThreshold = 3
+
+// recordTxMined resets the consecutive timeout counter once any transaction is mined
+func (f *fulfillerAccount) recordTxMined() {
+	f.mu.Lock()
+	f.txTimeouts = 0
+	f.mu.Unlock()
+}
+
+// recordTxTimeout counts a transaction that was not mined in time. After
+// nonceGapTimeoutThreshold consecutive timeouts, and if gap recovery is enabled,
+// it checks for a nonce gap and fills it.
+func (f *fulfillerAccount) recordTxTimeout(ctx context.Context) {
+	f.mu.Lock()
+	f.txTimeouts++
+	check := f.gapRecovery && f.txTimeouts >= nonceGapTimeoutThreshold
+	if check {
+		f.txTimeouts = 0
+	}
+	f.mu.Unlock()
+
+	if !check {
+		return
+	}
+	if err := f.recoverNonceGap(ctx); err != nil {
+		Logger.Error("Nonce gap recovery failed", "error", err)
+	}
+}
+
+// recoverNonceGap compares the confirmed and pending nonces with the next nonce the engine hands
+// out. If the node has nothing pending above the confirmed nonce but the engine has already used
+// higher nonces, the transaction at the confirmed nonce was dropped, so a zero-value self-transfer
+// is sent at that nonce to unblock the later ones. Transactions that are pending but slow are left
+// to the gas price replacements in waitForTransaction: a filler would cancel them.
+func (f *fulfillerAccount) recoverNonceGap(ctx context.Context) error {
+	confirmed, err := withCallTimeout(ctx, f.callTimeout, "NonceAt", func(ctx context.Context) (uint64, error) {
+		return f.client.NonceAt(ctx, f.fromAddress, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("get confirmed nonce: %w", err)
+	}
+	pending, err := withCallTimeout(ctx, f.callTimeout, "PendingNonceAt", func(ctx context.Context) (uint64, error) {
+		return f.client.PendingNonceAt(ctx, f.fromAddress)
+	})
+	if err != nil {
+		return fmt.Errorf("get pending nonce: %w", err)
+	}
+
+	f.mu.Lock()
+	tracked := pending
+	if f.nonce != nil && *f.nonce > tracked {
+		tracked = *f.nonce
+	}
+	f.mu.Unlock()
+
+	if pending > confirmed {
+		Logger.Info("Transactions pending above the confirmed nonce, not filling",
+			"confirmed_nonce", confirmed,
+			"pending_nonce", pending,
+		)
+		return nil
+	}
+	if tracked <= confirmed {
+		Logger.Info("No nonce gap detected", "confirmed_nonce", confirmed, "pending_nonce", pending)
+		return nil
+	}
+
+	Logger.Warn("Nonce gap detected, sending filler transaction",
+		"confirmed_nonce", confirmed,
+		"pending_nonce", pending,
+		"tracked_nonce", tracked,
+	)
+
+	gasPrice, err := withCallTimeout(ctx, f.callTimeout, "SuggestGasPrice", f.client.SuggestGasPrice)
+	if err != nil {
+		return fmt.Errorf("get gas price: %w", err)
+	}
+	gasPrice = bumpGasPrice(gasPrice)
+	if f.maxGasPrice != nil && gasPrice.Cmp(f.maxGasPrice) > 0 {
+		Logger.Warn("Gas price above maximum, not sending filler transaction",
+			"nonce", confirmed,
+			"gas_price_gwei", formatGwei(gasPrice),
+			"max_gas_price_gwei", formatGwei(f.maxGasPrice),
+		)
+		return nil
+	}
+
+	chainID, err := f.getChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx := types.NewTransaction(confirmed, f.fromAddress, big.NewInt(0), 21000, gasPrice, nil)
+	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), f.privateKey)
+	if err != nil {
+		return fmt.Errorf("sign: %w", err)
+	}
+	if err := f.client.SendTransaction(ctx, signedTx); err != nil {
+		return fmt.Errorf("send filler transaction at nonce %d: %w", confirmed, err)
+	}
+
+	Logger.Info("Filler transaction sent",
+		"tx_hash", signedTx.Hash().Hex(),
+		"nonce", confirmed,
+		"gas_price", gasPrice.String(),
+	)
+	return nil
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestRecoverNonceGap(t *testing.T) {
+	client := newMockEthClient()
+	client.confirmedNonce = 4
+	client.pendingNonce = 4 // the transaction at nonce 4 was dropped
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	acc := f.account
+	acc.gapRecovery = true
+	next := uint64(6)
+	acc.nonce = &next
+
+	ctx := context.Background()
+	for i := 0; i < nonceGapTimeoutThreshold-1; i++ {
+		acc.recordTxTimeout(ctx)
+	}
+	if len(client.sent) != 0 {
+		t.Fatalf("filler sent after %d timeouts, want none before the threshold", nonceGapTimeoutThreshold-1)
+	}
+
+	acc.recordTxTimeout(ctx)
+	if len(client.sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1 filler", len(client.sent))
+	}
+	filler := client.sent[0]
+	if filler.Nonce() != 4 {
+		t.Errorf("filler nonce = %d, want confirmed nonce 4", filler.Nonce())
+	}
+	if filler.To() == nil || *filler.To() != acc.fromAddress || filler.Value().Sign() != 0 {
+		t.Errorf("filler is not a zero-value self-transfer")
+	}
+
+	// No gap: nothing is sent
+	client.confirmedNonce = 6
+	client.pendingNonce = 6
+	for i := 0; i < nonceGapTimeoutThreshold; i++ {
+		acc.recordTxTimeout(ctx)
+	}
+	if len(client.sent) != 1 {
+		t.Errorf("sent %d transactions without a gap, want 1", len(client.sent))
+	}
+}
+
+func TestRecoverNonceGapSlowNotDropped(t *testing.T) {
+	client := newMockEthClient()
+	client.confirmedNonce = 4
+	client.pendingNonce = 6 // nonces 4 and 5 are still in the mempool
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	acc := f.account
+	next := uint64(6)
+	acc.nonce = &next
+
+	if err := acc.recoverNonceGap(context.Background()); err != nil {
+		t.Fatalf("recoverNonceGap: %v", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions for slow pending transactions, want none", len(client.sent))
+	}
+}
+
+func TestRecoverNonceGapAboveMaxGasPrice(t *testing.T) {
+	client := newMockEthClient()
+	client.confirmedNonce = 4
+	client.pendingNonce = 4
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	acc := f.account
+	next := uint64(6)
+	acc.nonce = &next
+	acc.maxGasPrice = big.NewInt(1) // the bumped filler price is 2
+
+	if err := acc.recoverNonceGap(context.Background()); err != nil {
+		t.Fatalf("recoverNonceGap: %v", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions above MAX_GAS_PRICE_GWEI, want none", len(client.sent))
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceStore persists the next nonce of the fulfiller account so a restart does not reuse
+// nonces of transactions still sitting in the mempool
+type nonceStore struct {
+	path string
+}
+
+type persistedNonce struct {
+	Address common.Address `json:"address"`
+	Nonce   uint64         `json:"nonce"`
+}
+
+func newNonceStore(path string) *nonceStore {
+	return &nonceStore{path: path}
+}
+
+// Load returns the stored next nonce for address. ok is false when nothing is stored yet
+// or the file belongs to a different account.
+func (s *nonceStore) Load(address common.Address) (nonce uint64, ok bool, err error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read nonce file: %v", err)
+	}
+
+	var stored persistedNonce
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return 0, false, fmt.Errorf("parse nonce file: %v", err)
+	}
+	if stored.Address != address {
+		return 0, false, nil
+	}
+	return stored.Nonce, true, nil
+}
+
+// Save atomically replaces the stored next nonce
+func (s *nonceStore) Save(address common.Address, nonce uint64) error {
+	data, err := json.Marshal(persistedNonce{Address: address, Nonce: nonce})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".nonce-*")
+	if err != nil {
+		return fmt.Errorf("create temp nonce file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write nonce file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write nonce file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("replace nonce file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNonceStoreReconcile(t *testing.T) {
+	address := common.HexToAddress("0x01")
+	store := newNonceStore(filepath.Join(t.TempDir(), "nonce.json"))
+
+	if _, ok, err := store.Load(address); ok || err != nil {
+		t.Fatalf("Load on missing file = ok %v, err %v; want nothing stored", ok, err)
+	}
+	if err := store.Save(address, 7); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, ok, _ := store.Load(common.HexToAddress("0x02")); ok {
+		t.Error("nonce stored for another account was returned")
+	}
+
+	tests := []struct {
+		name    string
+		pending uint64
+		want    uint64
+	}{
+		{"persisted ahead of network", 5, 7},
+		{"network ahead of persisted", 9, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := &fulfillerAccount{fromAddress: address, nonceStore: store}
+			if got := acc.reconcileNonce(tt.pending); got != tt.want {
+				t.Errorf("reconcileNonce(%d) = %d, want %d", tt.pending, got, tt.want)
+			}
+			// Only the first fetch after startup consults the file
+			if got := acc.reconcileNonce(3); got != 3 {
+				t.Errorf("second reconcileNonce(3) = %d, want 3", got)
+			}
+		})
+	}
+}
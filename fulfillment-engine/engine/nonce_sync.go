@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Consecutive checks the tracked nonce must disagree with the network before it is resynced, so
+// transactions still propagating to the node are not mistaken for dropped ones
+const nonceSyncChecks = 3
+
+// NonceReconciler periodically compares the tracked nonce with the network's pending nonce and
+// resyncs it when they stay apart. This heals a tracker left ahead of the network by a dropped
+// transaction, which would otherwise queue every later transaction behind the missing nonce.
+type NonceReconciler struct {
+	interval  time.Duration
+	threshold uint64
+	account   *fulfillerAccount
+	drift     int // Consecutive checks the tracked nonce was off by more than threshold
+}
+
+func NewNonceReconciler(config *Config, account *fulfillerAccount) *NonceReconciler {
+	return &NonceReconciler{
+		interval:  config.NonceSyncInterval,
+		threshold: config.NonceSyncThreshold,
+		account:   account,
+	}
+}
+
+func (r *NonceReconciler) Start(ctx context.Context) error {
+	if r.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.check(ctx); err != nil {
+				Logger.Error("Nonce reconciliation failed", "error", err)
+			}
+		}
+	}
+}
+
+// check compares the tracked nonce with the network once, resyncing it after nonceSyncChecks
+// consecutive mismatches
+func (r *NonceReconciler) check(ctx context.Context) error {
+	f := r.account
+	confirmed, err := withCallTimeout(ctx, f.callTimeout, "NonceAt", func(ctx context.Context) (uint64, error) {
+		return f.client.NonceAt(ctx, f.fromAddress, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("get confirmed nonce: %w", err)
+	}
+	pending, err := withCallTimeout(ctx, f.callTimeout, "PendingNonceAt", func(ctx context.Context) (uint64, error) {
+		return f.client.PendingNonceAt(ctx, f.fromAddress)
+	})
+	if err != nil {
+		return fmt.Errorf("get pending nonce: %w", err)
+	}
+
+	f.mu.Lock()
+	if f.nonce == nil {
+		// Nothing tracked yet (or reset after an error): the next send fetches the nonce
+		f.mu.Unlock()
+		r.drift = 0
+		return nil
+	}
+	tracked := *f.nonce
+	f.mu.Unlock()
+
+	diff := tracked - pending
+	if pending > tracked {
+		diff = pending - tracked
+	}
+	if diff <= r.threshold {
+		r.drift = 0
+		return nil
+	}
+
+	r.drift++
+	Logger.Debug("Tracked nonce differs from the network",
+		"tracked_nonce", tracked,
+		"pending_nonce", pending,
+		"confirmed_nonce", confirmed,
+		"checks", r.drift,
+	)
+	if r.drift < nonceSyncChecks {
+		return nil
+	}
+	r.drift = 0
+
+	if !f.resyncNonce(tracked, pending) {
+		return nil // a send reserved a nonce meanwhile; look again on the next check
+	}
+	Logger.Warn("Tracked nonce out of sync with the network, resynced",
+		"tracked_nonce", tracked,
+		"pending_nonce", pending,
+		"confirmed_nonce", confirmed,
+		"checks", nonceSyncChecks,
+	)
+	return nil
+}
+
+// resyncNonce replaces the tracked nonce with the network's pending nonce, unless it changed from
+// expected since it was read. The persisted nonce follows, so a restart does not restore the
+// stale value.
+func (f *fulfillerAccount) resyncNonce(expected, pending uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.nonce == nil || *f.nonce != expected {
+		return false
+	}
+	f.nonce = &pending
+	f.released = nil
+	f.reconciled = true
+	if f.nonceStore != nil {
+		if err := f.nonceStore.Save(f.fromAddress, pending); err != nil {
+			Logger.Warn("Failed to persist nonce", "nonce", pending, "error", err)
+		} else {
+			f.savedNonce = pending
+		}
+	}
+	return true
+}
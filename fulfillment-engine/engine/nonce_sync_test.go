@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNonceReconcilerResyncsDroppedNonce(t *testing.T) {
+	client := newMockEthClient()
+	client.confirmedNonce = 5
+	client.pendingNonce = 5
+	f := newTestFulfiller(t, client, 6, 6, nil)
+	tracked := uint64(7) // two transactions the node no longer knows about
+	f.account.nonce = &tracked
+	r := NewNonceReconciler(&Config{NonceSyncThreshold: 1}, f.account)
+
+	ctx := context.Background()
+	for i := 0; i < nonceSyncChecks-1; i++ {
+		if err := r.check(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *f.account.nonce != 7 {
+		t.Fatalf("nonce resynced to %d before %d checks", *f.account.nonce, nonceSyncChecks)
+	}
+	if err := r.check(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if *f.account.nonce != 5 {
+		t.Fatalf("nonce = %d after %d checks, want the pending nonce 5", *f.account.nonce, nonceSyncChecks)
+	}
+
+	// A difference within the threshold is left alone
+	client.pendingNonce = 4
+	for i := 0; i < nonceSyncChecks; i++ {
+		if err := r.check(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *f.account.nonce != 5 {
+		t.Errorf("nonce = %d, want 5 kept within the threshold", *f.account.nonce)
+	}
+}
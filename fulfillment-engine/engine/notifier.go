@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Per-notification delivery timeout
+	notifyTimeout = 10 * time.Second
+)
+
+// FulfillmentEvent describes the outcome of a deposit or withdrawal fulfillment
+type FulfillmentEvent struct {
+	Kind      string // "deposit" or "withdrawal"
+	VaultName string
+	RequestID string
+	Amount    string // quote amount for deposits, shares amount for withdrawals
+	TxHash    string // empty if no transaction was mined
+	Err       error  // nil on success
+	Time      time.Time
+}
+
+// Message renders the event as a human-readable line
+func (e FulfillmentEvent) Message() string {
+	if e.Err != nil {
+		return fmt.Sprintf("❌ %s %s #%s failed (amount %s, tx %s): %v",
+			e.VaultName, e.Kind, e.RequestID, e.Amount, orNone(e.TxHash), e.Err)
+	}
+	return fmt.Sprintf("✅ %s %s #%s fulfilled (amount %s, tx %s)",
+		e.VaultName, e.Kind, e.RequestID, e.Amount, orNone(e.TxHash))
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// Notifier delivers fulfillment events to an external channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event FulfillmentEvent) error
+}
+
+// WebhookNotifier posts events as JSON to a generic webhook.
+// The payload carries both "text" (Slack) and "content" (Discord) so it works with either.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    webhookURL,
+		client: &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event FulfillmentEvent) error {
+	payload := map[string]interface{}{
+		"text":       event.Message(),
+		"content":    event.Message(),
+		"kind":       event.Kind,
+		"vault_name": event.VaultName,
+		"request_id": event.RequestID,
+		"amount":     event.Amount,
+		"tx_hash":    event.TxHash,
+		"success":    event.Err == nil,
+		"timestamp":  event.Time.Unix(),
+	}
+	if event.Err != nil {
+		payload["error"] = event.Err.Error()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	return postJSON(ctx, n.client, n.url, body)
+}
+
+// TelegramNotifier sends events through the Telegram Bot API
+type TelegramNotifier struct {
+	botToken string
+	chatID   string
+	client   *http.Client
+}
+
+func NewTelegramNotifier(botToken, chatID string) *TelegramNotifier {
+	return &TelegramNotifier{
+		botToken: botToken,
+		chatID:   chatID,
+		client:   &http.Client{Timeout: notifyTimeout},
+	}
+}
+
+func (n *TelegramNotifier) Name() string { return "telegram" }
+
+func (n *TelegramNotifier) Notify(ctx context.Context, event FulfillmentEvent) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": n.chatID,
+		"text":    event.Message(),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", url.PathEscape(n.botToken))
+	return postJSON(ctx, n.client, endpoint, body)
+}
+
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Strip the URL from the error so bot tokens never end up in logs
+		if urlErr, ok := err.(*url.Error); ok {
+			return fmt.Errorf("post: %w", urlErr.Err)
+		}
+		return fmt.Errorf("post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// NotificationQueue fans events out to notifiers from a background goroutine.
+// Enqueueing never blocks: when the bounded queue is full the event is dropped.
+type NotificationQueue struct {
+	notifiers []Notifier
+	events    chan FulfillmentEvent
+	wg        sync.WaitGroup
+}
+
+func NewNotificationQueue(notifiers []Notifier, size int) *NotificationQueue {
+	return &NotificationQueue{
+		notifiers: notifiers,
+		events:    make(chan FulfillmentEvent, size),
+	}
+}
+
+// NewNotificationQueueFromConfig builds the notifiers enabled in config. client is used to read
+// fulfillment receipts for the fulfillment webhooks. Returns nil if no notifier is configured.
+func NewNotificationQueueFromConfig(config *Config, client EthClient) *NotificationQueue {
+	var notifiers []Notifier
+	if config.NotifyWebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(config.NotifyWebhookURL))
+	}
+	if config.TelegramBotToken != "" && config.TelegramChatID != "" {
+		notifiers = append(notifiers, NewTelegramNotifier(config.TelegramBotToken, config.TelegramChatID))
+	}
+	// Last, so its retries do not hold up the operator alerts
+	if webhook := NewFulfillmentWebhookNotifier(config.SectorVaults, client); webhook != nil {
+		notifiers = append(notifiers, webhook)
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+	return NewNotificationQueue(notifiers, config.NotifyQueueSize)
+}
+
+// Start launches the delivery worker; it exits when ctx is cancelled
+func (q *NotificationQueue) Start(ctx context.Context) {
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-q.events:
+				q.deliver(ctx, event)
+			}
+		}
+	}()
+}
+
+func (q *NotificationQueue) deliver(ctx context.Context, event FulfillmentEvent) {
+	for _, n := range q.notifiers {
+		if err := n.Notify(ctx, event); err != nil {
+			Logger.Warn("Failed to deliver notification",
+				"notifier", n.Name(),
+				"kind", event.Kind,
+				"request_id", event.RequestID,
+				"error", err,
+			)
+		}
+	}
+}
+
+// Enqueue queues an event for delivery without blocking. Safe to call on a nil queue.
+func (q *NotificationQueue) Enqueue(event FulfillmentEvent) {
+	if q == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	select {
+	case q.events <- event:
+	default:
+		Logger.Warn("Notification queue full, dropping event",
+			"kind", event.Kind,
+			"vault_name", event.VaultName,
+			"request_id", event.RequestID,
+		)
+	}
+}
+
+// Drain delivers the events still queued once the worker has stopped, giving up when ctx
+// expires. It returns how many events were delivered and how many were dropped.
+func (q *NotificationQueue) Drain(ctx context.Context) (flushed, dropped int) {
+	if q == nil {
+		return 0, 0
+	}
+	q.wg.Wait()
+
+	for {
+		select {
+		case event := <-q.events:
+			if ctx.Err() != nil {
+				dropped++
+				continue
+			}
+			q.deliver(ctx, event)
+			flushed++
+		default:
+			return flushed, dropped
+		}
+	}
+}
+
+// Wait blocks until the delivery worker has exited
+func (q *NotificationQueue) Wait() {
+	if q == nil {
+		return
+	}
+	q.wg.Wait()
+}
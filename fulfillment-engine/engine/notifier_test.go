@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []FulfillmentEvent
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(ctx context.Context, event FulfillmentEvent) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func TestNotificationQueueDrain(t *testing.T) {
+	notifier := &recordingNotifier{}
+	q := NewNotificationQueue([]Notifier{notifier}, 10)
+
+	// Worker already stopped, as after a shutdown signal
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	q.Start(ctx)
+	q.Wait()
+
+	for _, id := range []string{"1", "2", "3"} {
+		q.Enqueue(FulfillmentEvent{Kind: "deposit", RequestID: id})
+	}
+
+	flushed, dropped := q.Drain(context.Background())
+	if flushed != 3 || dropped != 0 {
+		t.Errorf("Drain = %d flushed, %d dropped; want 3, 0", flushed, dropped)
+	}
+	if len(notifier.events) != 3 {
+		t.Errorf("delivered %d events, want 3", len(notifier.events))
+	}
+
+	// Past the shutdown deadline everything left is dropped
+	q.Enqueue(FulfillmentEvent{Kind: "withdrawal", RequestID: "4"})
+	flushed, dropped = q.Drain(ctx)
+	if flushed != 0 || dropped != 1 {
+		t.Errorf("Drain after deadline = %d flushed, %d dropped; want 0, 1", flushed, dropped)
+	}
+}
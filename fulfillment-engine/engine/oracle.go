@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// OracleVariant describes how prices are read from an oracle backend
+type OracleVariant struct {
+	Name       string
+	PriceFn    string // price function name
+	DecimalsFn string // decimals function name
+	// PerTokenFeed is set for aggregator-style oracles (e.g. Chainlink): each token has its own
+	// feed, and the price function takes no arguments and returns int256. Otherwise a single
+	// oracle contract exposes priceFn(address token) returning uint256.
+	PerTokenFeed bool
+}
+
+// Built-in oracle variants, selected with ORACLE_VARIANT
+var oracleVariants = map[string]OracleVariant{
+	"custom":    {Name: "custom", PriceFn: "getPrice", DecimalsFn: "decimals"},
+	"chainlink": {Name: "chainlink", PriceFn: "latestAnswer", DecimalsFn: "decimals", PerTokenFeed: true},
+}
+
+// defaultOracleVariant matches the vault's own oracle interface (OracleABI)
+var defaultOracleVariant = oracleVariants["custom"]
+
+// ParseABI builds the oracle ABI for this variant's function names
+func (v OracleVariant) ParseABI() (abi.ABI, error) {
+	priceInputs := `[{"name": "token", "type": "address"}]`
+	priceOutput := "uint256"
+	if v.PerTokenFeed {
+		priceInputs = `[]`
+		priceOutput = "int256"
+	}
+
+	return abi.JSON(strings.NewReader(fmt.Sprintf(`[
+	{
+		"constant": true,
+		"inputs": %s,
+		"name": %q,
+		"outputs": [{"name": "", "type": %q}],
+		"type": "function"
+	},
+	{
+		"constant": true,
+		"inputs": [],
+		"name": %q,
+		"outputs": [{"name": "", "type": "uint8"}],
+		"type": "function"
+	}
+]`, priceInputs, v.PriceFn, priceOutput, v.DecimalsFn)))
+}
+
+// priceSource returns the contract to query for token's price
+func (v OracleVariant) priceSource(oracle common.Address, feeds map[common.Address]common.Address, token common.Address) (common.Address, error) {
+	if !v.PerTokenFeed {
+		return oracle, nil
+	}
+	feed, ok := feeds[token]
+	if !ok {
+		return common.Address{}, fmt.Errorf("no %s feed configured for token %s (set ORACLE_FEEDS)", v.Name, token.Hex())
+	}
+	return feed, nil
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Orders in which a batch of pending requests is fulfilled (REQUEST_ORDER)
+const (
+	requestOrderFIFO     = "fifo"     // oldest request first (default)
+	requestOrderLargest  = "largest"  // largest quote value first
+	requestOrderSmallest = "smallest" // smallest quote value first
+)
+
+// sortRequests orders request logs by their timestamp and, for the value orders, by value(log).
+// The sort is stable, so requests of equal value stay oldest first.
+func sortRequests(logs []types.Log, order string, value func(types.Log) *big.Int) {
+	sort.SliceStable(logs, func(i, j int) bool {
+		return requestTimestamp(logs[i]) < requestTimestamp(logs[j])
+	})
+	if order != requestOrderLargest && order != requestOrderSmallest {
+		return
+	}
+
+	values := make(map[logKey]*big.Int, len(logs))
+	for _, vLog := range logs {
+		values[logKey{txHash: vLog.TxHash, logIndex: vLog.Index}] = value(vLog)
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		cmp := values[logKey{txHash: logs[i].TxHash, logIndex: logs[i].Index}].Cmp(values[logKey{txHash: logs[j].TxHash, logIndex: logs[j].Index}])
+		if order == requestOrderLargest {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+// requestTimestamp reads the timestamp from a request log's data (quoteAmount|sharesAmount, timestamp)
+func requestTimestamp(vLog types.Log) uint64 {
+	if len(vLog.Data) < 64 {
+		return 0
+	}
+	return new(big.Int).SetBytes(vLog.Data[32:64]).Uint64()
+}
+
+// requestValue is a request's size in quote token base units: the deposited amount, or
+// the vault's current value of the withdrawn shares. Unknown values count as zero.
+func (l *EventListener) requestValue(ctx context.Context, vLog types.Log) *big.Int {
+	if len(vLog.Data) < 32 || len(vLog.Topics) == 0 {
+		return new(big.Int)
+	}
+	amount := new(big.Int).SetBytes(vLog.Data[0:32])
+	if vLog.Topics[0] != common.HexToHash(withdrawalRequestedSignature) {
+		return amount
+	}
+
+	value, err := l.fulfiller.calculateWithdrawalValue(ctx, amount)
+	if err != nil {
+		Logger.Warn("Failed to value withdrawal for ordering",
+			"vault_name", l.vaultConfig.Name,
+			"tx_hash", vLog.TxHash.Hex(),
+			"error", err,
+		)
+		return new(big.Int)
+	}
+	return value
+}
+
+// orderRequests sorts a batch of this vault's pending request logs per REQUEST_ORDER
+func (l *EventListener) orderRequests(ctx context.Context, logs []types.Log) {
+	sortRequests(logs, l.config.RequestOrder, func(vLog types.Log) *big.Int {
+		return l.requestValue(ctx, vLog)
+	})
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func requestLog(index uint, amount, timestamp int64) types.Log {
+	data := append(common.LeftPadBytes(big.NewInt(amount).Bytes(), 32), common.LeftPadBytes(big.NewInt(timestamp).Bytes(), 32)...)
+	return types.Log{Index: index, Data: data}
+}
+
+func TestSortRequests(t *testing.T) {
+	amount := func(vLog types.Log) *big.Int { return new(big.Int).SetBytes(vLog.Data[0:32]) }
+
+	tests := []struct {
+		order string
+		want  []uint // log indexes in processing order
+	}{
+		{requestOrderFIFO, []uint{2, 0, 1, 3}},
+		{requestOrderLargest, []uint{1, 2, 3, 0}},
+		{requestOrderSmallest, []uint{0, 2, 3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			logs := []types.Log{
+				requestLog(0, 10, 200),
+				requestLog(1, 500, 300),
+				requestLog(2, 100, 100),
+				requestLog(3, 100, 400),
+			}
+			sortRequests(logs, tt.order, amount)
+			for i, vLog := range logs {
+				if vLog.Index != tt.want[i] {
+					t.Fatalf("position %d has log %d, want order %v", i, vLog.Index, tt.want)
+				}
+			}
+		})
+	}
+}
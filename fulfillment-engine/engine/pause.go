@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errPaused is returned by the fulfiller while fulfillment is paused through POST /pause
+var errPaused = errors.New("fulfillment paused")
+
+// fulfillmentPause is the engine-wide switch behind POST /pause and POST /resume. While it is
+// set, listeners keep tracking events and defer requests until fulfillment resumes.
+var fulfillmentPause pauseState
+
+type pauseState struct {
+	mu    sync.RWMutex
+	since time.Time // when fulfillment was paused (zero while running)
+}
+
+// pause stops new fulfillments. It returns false if fulfillment was already paused.
+func (p *pauseState) pause() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.since.IsZero() {
+		return false
+	}
+	p.since = time.Now()
+	return true
+}
+
+// resume lets fulfillments through again. It returns false if fulfillment was not paused.
+func (p *pauseState) resume() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.since.IsZero() {
+		return false
+	}
+	p.since = time.Time{}
+	return true
+}
+
+// pausedSince returns when fulfillment was paused, or the zero time if it is running
+func (p *pauseState) pausedSince() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.since
+}
+
+func (p *pauseState) paused() bool {
+	return !p.pausedSince().IsZero()
+}
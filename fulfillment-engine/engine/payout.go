@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Withdrawal payout modes (WITHDRAWAL_PAYOUT_MODE)
+const (
+	// The fulfiller pays the user in the quote token and receives underlying tokens from the vault
+	withdrawalPayoutUnderlying = "underlying"
+	// The fulfiller only pays the user in the quote token, via fulfillWithdrawal(uint256)
+	withdrawalPayoutQuote = "quote"
+)
+
+// fulfillWithdrawalQuoteSig is the signature of the quote payout overload of fulfillWithdrawal
+const fulfillWithdrawalQuoteSig = "fulfillWithdrawal(uint256)"
+
+// packFulfillWithdrawalQuote encodes fulfillWithdrawal(uint256). The overload is looked up by its
+// signature, since the ABI parser names overloads by declaration order.
+func packFulfillWithdrawalQuote(parsedABI abi.ABI, withdrawalId *big.Int) ([]byte, error) {
+	for _, m := range parsedABI.Methods {
+		if m.Sig != fulfillWithdrawalQuoteSig {
+			continue
+		}
+		args, err := m.Inputs.Pack(withdrawalId)
+		if err != nil {
+			return nil, err
+		}
+		return append(m.ID, args...), nil
+	}
+	return nil, fmt.Errorf("method %s not found in abi", fulfillWithdrawalQuoteSig)
+}
+
+// packFulfillWithdrawal encodes the fulfillWithdrawal call for the configured payout mode
+func (f *Fulfiller) packFulfillWithdrawal(withdrawalId *big.Int, amounts []*big.Int) ([]byte, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return nil, fmt.Errorf("parse sector vault abi: %w", err)
+	}
+	if f.config.WithdrawalPayoutMode == withdrawalPayoutQuote {
+		return packFulfillWithdrawalQuote(parsedABI, withdrawalId)
+	}
+	return parsedABI.Pack("fulfillWithdrawal", withdrawalId, amounts)
+}
+
+// probeWithdrawalPayout reports whether the vault implements the fulfillWithdrawal variant of a
+// payout mode. It calls the variant for a withdrawal id that cannot exist: an implemented variant
+// reverts with an error (WithdrawalNotFound), while a missing one reverts without revert data.
+func (f *Fulfiller) probeWithdrawalPayout(ctx context.Context, mode string) bool {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return false
+	}
+	var data []byte
+	if mode == withdrawalPayoutQuote {
+		data, err = packFulfillWithdrawalQuote(parsedABI, math.MaxBig256)
+	} else {
+		data, err = parsedABI.Pack("fulfillWithdrawal", math.MaxBig256, []*big.Int{})
+	}
+	if err != nil {
+		return false
+	}
+
+	to := f.vaultConfig.Address
+	_, err = withCallTimeout(ctx, f.config.RPCCallTimeout, "CallContract", func(ctx context.Context) ([]byte, error) {
+		return f.client.CallContract(ctx, ethereum.CallMsg{
+			From: f.account.fromAddress,
+			To:   &to,
+			Data: data,
+		}, nil)
+	})
+	return err == nil || len(revertData(err)) > 0
+}
+
+// checkWithdrawalPayout fails if the vault lacks the fulfillWithdrawal variant of the configured
+// payout mode but implements the other one, so a misconfigured mode is caught at startup
+func (f *Fulfiller) checkWithdrawalPayout(ctx context.Context) error {
+	mode := f.config.WithdrawalPayoutMode
+	other := withdrawalPayoutQuote
+	if mode == withdrawalPayoutQuote {
+		other = withdrawalPayoutUnderlying
+	}
+
+	if f.probeWithdrawalPayout(ctx, mode) {
+		return nil
+	}
+	if f.probeWithdrawalPayout(ctx, other) {
+		return fmt.Errorf("vault does not implement the %s withdrawal payout - set WITHDRAWAL_PAYOUT_MODE=%s", mode, other)
+	}
+	Logger.Warn("Could not confirm the vault's withdrawal payout mode, the RPC node returned no revert data",
+		"vault_name", f.vaultConfig.Name,
+		"withdrawal_payout_mode", mode,
+	)
+	return nil
+}
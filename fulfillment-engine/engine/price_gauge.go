@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// priceGauge keeps the last oracle price read for each token, so /metrics can expose a time
+// series of the prices fulfillments were computed with
+type priceGauge struct {
+	mu     sync.Mutex
+	prices map[common.Address]*big.Int
+}
+
+func newPriceGauge() *priceGauge {
+	return &priceGauge{prices: make(map[common.Address]*big.Int)}
+}
+
+// observe records price, in oracle decimals, as the latest price of token
+func (g *priceGauge) observe(token common.Address, price *big.Int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prices[token] = new(big.Int).Set(price)
+}
+
+// writePrometheus writes one gauge per token in the Prometheus text format, labelled with the vault name
+func (g *priceGauge) writePrometheus(w io.Writer, vaultName string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	tokens := make([]common.Address, 0, len(g.prices))
+	for token := range g.prices {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Hex() < tokens[j].Hex() })
+
+	for _, token := range tokens {
+		value, _ := new(big.Float).SetInt(g.prices[token]).Float64()
+		fmt.Fprintf(w, "tone_oracle_price{vault=%q,token=%q} %s\n",
+			vaultName, token.Hex(), strconv.FormatFloat(value, 'g', -1, 64))
+	}
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPriceGaugeUpdatedInDryRun(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 8, []testToken{
+		{decimals: 18, weight: 5000, price: "200000000000"},
+		{decimals: 6, weight: 5000, price: "100000000"},
+	})
+	f.config.DryRun = true
+
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); !errors.Is(err, errDryRun) {
+		t.Fatalf("FulfillDeposit: %v, want a dry run", err)
+	}
+
+	var out strings.Builder
+	f.prices.writePrometheus(&out, "AI")
+	for i, want := range []string{"2e+11", "1e+08"} {
+		line := fmt.Sprintf("tone_oracle_price{vault=\"AI\",token=%q} %s\n", f.underlyingTokens[i].Hex(), want)
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PriceSource reports the price of a token in oracle units (USD scaled by the oracle decimals)
+type PriceSource interface {
+	Price(ctx context.Context, token common.Address) (*big.Int, error)
+}
+
+// oraclePriceSource reads prices from the vault's on-chain oracle, which the vault itself
+// validates fulfillments against
+type oraclePriceSource struct {
+	f *Fulfiller
+}
+
+// Price fetches token's price from the configured oracle variant.
+// Zero and negative prices are rejected with ErrInvalidPrice.
+func (s oraclePriceSource) Price(ctx context.Context, token common.Address) (*big.Int, error) {
+	f := s.f
+	oracle := f.config.Oracle
+	parsedABI, err := oracle.ParseABI()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := oracle.priceSource(f.oracleAddress, f.config.OracleFeeds, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if oracle.PerTokenFeed {
+		data, err = parsedABI.Pack(oracle.PriceFn)
+	} else {
+		data, err = parsedABI.Pack(oracle.PriceFn, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := f.callContract(ctx, oracle.PriceFn, source, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var price *big.Int
+	err = parsedABI.UnpackIntoInterface(&price, oracle.PriceFn, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// int256 feeds can report a negative answer; never price anything off a non-positive value
+	if price.Sign() <= 0 {
+		return nil, fmt.Errorf("%w %s from %s", ErrInvalidPrice, price.String(), source.Hex())
+	}
+
+	return price, nil
+}
+
+// priceCheckTimeout bounds each request to the off-chain price API
+const priceCheckTimeout = 10 * time.Second
+
+// restPriceSource reads USD prices from a JSON HTTP API such as CoinGecko's
+// /simple/token_price endpoint and scales them to oracle units
+type restPriceSource struct {
+	client   *http.Client
+	url      string // {token} is replaced by the lowercase token address
+	field    string // dot-separated path to the price; {token} is replaced as in url
+	decimals uint8  // oracle decimals the price is scaled to
+}
+
+func newRESTPriceSource(endpoint, field string, decimals uint8) *restPriceSource {
+	return &restPriceSource{
+		client:   &http.Client{Timeout: priceCheckTimeout},
+		url:      endpoint,
+		field:    field,
+		decimals: decimals,
+	}
+}
+
+// Price fetches token's USD price from the API
+func (s *restPriceSource) Price(ctx context.Context, token common.Address) (*big.Int, error) {
+	addr := strings.ToLower(token.Hex())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(s.url, "{token}", addr), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Strip the URL from the error so API keys never end up in logs
+		if urlErr, ok := err.(*url.Error); ok {
+			return nil, fmt.Errorf("get: %w", urlErr.Err)
+		}
+		return nil, fmt.Errorf("get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode response: %v", err)
+	}
+
+	field := strings.ReplaceAll(s.field, "{token}", addr)
+	value, err := jsonField(body, field)
+	if err != nil {
+		return nil, err
+	}
+	return scalePrice(value, s.decimals)
+}
+
+// jsonField follows a dot-separated path of object keys through a decoded JSON document.
+// Keys are matched case-insensitively, since APIs differ in how they case addresses.
+func jsonField(doc interface{}, path string) (interface{}, error) {
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("no %q in price response", path)
+		}
+		next, ok := obj[key]
+		if !ok {
+			for k, v := range obj {
+				if strings.EqualFold(k, key) {
+					next, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("no %q in price response", path)
+		}
+		doc = next
+	}
+	return doc, nil
+}
+
+// scalePrice converts a decimal USD price (JSON number or string) to an integer with the given decimals
+func scalePrice(value interface{}, decimals uint8) (*big.Int, error) {
+	var str string
+	switch v := value.(type) {
+	case json.Number:
+		str = v.String()
+	case string:
+		str = v
+	default:
+		return nil, fmt.Errorf("price %v is not a number", value)
+	}
+
+	price, ok := new(big.Float).SetPrec(256).SetString(str)
+	if !ok {
+		return nil, fmt.Errorf("price %q is not a number", str)
+	}
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	scaled, _ := price.Mul(price, scale).Int(nil)
+	if scaled.Sign() <= 0 {
+		return nil, fmt.Errorf("%w %s from price API", ErrInvalidPrice, str)
+	}
+	return scaled, nil
+}
+
+// priceDeviationBps returns how far price is from reference, in bps of reference
+func priceDeviationBps(price, reference *big.Int) int64 {
+	diff := new(big.Int).Sub(price, reference)
+	diff.Abs(diff).Mul(diff, big.NewInt(10000)).Div(diff, reference)
+	if !diff.IsInt64() {
+		return 1<<63 - 1
+	}
+	return diff.Int64()
+}
+
+// priceAlertCooldown is the minimum time between deviation alerts for the same token
+const priceAlertCooldown = 15 * time.Minute
+
+// PriceDeviationAlert is the JSON payload posted to the alert webhook when the oracle price of
+// a token diverges from the price API and fulfillments are aborted. Text suits Slack webhooks.
+type PriceDeviationAlert struct {
+	Text         string `json:"text"`
+	VaultName    string `json:"vault_name"`
+	Vault        string `json:"vault"`
+	Token        string `json:"token"`
+	OraclePrice  string `json:"oracle_price"`
+	CheckPrice   string `json:"check_price"`
+	DeviationBps int64  `json:"deviation_bps"`
+}
+
+// sendPriceAlert posts a price deviation alert to webhookURL in the background
+func sendPriceAlert(webhookURL string, alert PriceDeviationAlert) {
+	if webhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		Logger.Error("Failed to marshal price deviation alert", "error", err)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertWebhookTimeout)
+		defer cancel()
+		if err := postJSON(ctx, &http.Client{Timeout: alertWebhookTimeout}, webhookURL, body); err != nil {
+			Logger.Error("Failed to send price deviation alert", "vault_name", alert.VaultName, "error", err)
+		}
+	}()
+}
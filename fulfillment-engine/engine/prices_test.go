@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// stubPriceSource returns fixed prices per token
+type stubPriceSource map[common.Address]*big.Int
+
+func (s stubPriceSource) Price(ctx context.Context, token common.Address) (*big.Int, error) {
+	price, ok := s[token]
+	if !ok {
+		return nil, fmt.Errorf("no price for %s", token.Hex())
+	}
+	return price, nil
+}
+
+func TestRESTPriceSource(t *testing.T) {
+	token := common.HexToAddress("0x00000000000000000000000000000000000000Ab")
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.String()
+		// Keyed by address in another case than requested
+		fmt.Fprintf(w, `{"%s": {"usd": 1.0004}}`, token.Hex())
+	}))
+	defer server.Close()
+
+	src := newRESTPriceSource(server.URL+"/price?contract_addresses={token}", "{token}.usd", 8)
+	price, err := src.Price(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Price: %v", err)
+	}
+	if price.String() != "100040000" {
+		t.Errorf("price = %s, want 100040000", price)
+	}
+	if want := "/price?contract_addresses=" + strings.ToLower(token.Hex()); gotPath != want {
+		t.Errorf("requested %s, want %s", gotPath, want)
+	}
+
+	src.field = "{token}.eur"
+	if _, err := src.Price(context.Background(), token); err == nil {
+		t.Error("Price succeeded for a missing field")
+	}
+}
+
+func TestScalePrice(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		decimals uint8
+		want     string
+	}{
+		{value: "2500.5", decimals: 8, want: "250050000000"},
+		{value: "0.000001", decimals: 6, want: "1"},
+		{value: "1e3", decimals: 2, want: "100000"},
+	}
+	for _, tt := range tests {
+		got, err := scalePrice(tt.value, tt.decimals)
+		if err != nil || got.String() != tt.want {
+			t.Errorf("scalePrice(%v, %d) = %v, %v; want %s", tt.value, tt.decimals, got, err, tt.want)
+		}
+	}
+	for _, bad := range []interface{}{"0", "abc", 12.5, nil} {
+		if _, err := scalePrice(bad, 8); err == nil {
+			t.Errorf("scalePrice(%v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestPriceDeviationBps(t *testing.T) {
+	tests := []struct {
+		price, reference int64
+		want             int64
+	}{
+		{price: 100, reference: 100, want: 0},
+		{price: 102, reference: 100, want: 200},
+		{price: 97, reference: 100, want: 300},
+		{price: 1000150, reference: 1000000, want: 1}, // rounded down
+	}
+	for _, tt := range tests {
+		if got := priceDeviationBps(big.NewInt(tt.price), big.NewInt(tt.reference)); got != tt.want {
+			t.Errorf("priceDeviationBps(%d, %d) = %d, want %d", tt.price, tt.reference, got, tt.want)
+		}
+	}
+}
+
+func TestFulfillDepositAbortsOnPriceDeviation(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+	f.config.MaxPriceDeviationBps = 200
+	token := f.underlyingTokens[0]
+
+	// Within the allowed deviation the deposit is fulfilled
+	f.priceCheck = stubPriceSource{token: big.NewInt(1015000)}
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); err != nil {
+		t.Fatalf("FulfillDeposit within deviation: %v", err)
+	}
+	sent := len(client.sent)
+
+	f.priceCheck = stubPriceSource{token: big.NewInt(1100000)}
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(2), big.NewInt(1000000), time.Time{}); !errors.Is(err, ErrPriceDeviation) {
+		t.Fatalf("FulfillDeposit error = %v, want ErrPriceDeviation", err)
+	}
+	if len(client.sent) != sent {
+		t.Errorf("sent %d transactions after a price deviation, want none", len(client.sent)-sent)
+	}
+
+	// A price check that cannot answer also blocks the fulfillment
+	f.priceCheck = stubPriceSource{}
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(3), big.NewInt(1000000), time.Time{}); err == nil {
+		t.Fatal("FulfillDeposit succeeded without a price check answer")
+	}
+}
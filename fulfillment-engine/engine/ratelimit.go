@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How often a throttled RPC limiter logs, so a sustained burst does not flood the logs
+const rpcThrottleLogInterval = 30 * time.Second
+
+// rpcLimiter is a token bucket bounding outbound RPC calls (RPC_MAX_RPS). One limiter is shared
+// by every vault, so the engine as a whole stays under the provider's rate limit.
+type rpcLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // bucket size: one second worth of calls
+	tokens float64 // negative while callers are waiting for reserved tokens
+	last   time.Time
+
+	throttled int       // calls delayed since the last throttle log
+	loggedAt  time.Time // last throttle log
+}
+
+// newRPCLimiter returns a limiter allowing maxRPS calls per second, or nil (unlimited) if maxRPS is 0
+func newRPCLimiter(maxRPS int) *rpcLimiter {
+	if maxRPS <= 0 {
+		return nil
+	}
+	return &rpcLimiter{
+		rate:   float64(maxRPS),
+		burst:  float64(maxRPS),
+		tokens: float64(maxRPS),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a call may be made. It fails with ctx's error if ctx is done first.
+func (l *rpcLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens-- // reserve a token, possibly ahead of its refill
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		l.throttled++
+		if now.Sub(l.loggedAt) >= rpcThrottleLogInterval {
+			Logger.Warn("RPC rate limit reached, throttling calls - consider raising RPC_MAX_RPS or the RPC plan",
+				"max_rps", int(l.rate),
+				"throttled_calls", l.throttled,
+				"delay", delay,
+			)
+			l.throttled = 0
+			l.loggedAt = now
+		}
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the reserved token back to the callers still waiting
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRPCLimiter(t *testing.T) {
+	l := newRPCLimiter(20)
+	ctx := context.Background()
+
+	// A full bucket lets one second worth of calls through at once
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if err := l.wait(ctx); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Fatalf("burst took %s, want no throttling", elapsed)
+	}
+
+	// The next call waits for a refill (1/20 s)
+	start = time.Now()
+	if err := l.wait(ctx); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("throttled call took %s, want about 50ms", elapsed)
+	}
+
+	// A caller giving up while throttled gets its context error
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
+
+func TestRPCLimiterUnlimited(t *testing.T) {
+	if l := newRPCLimiter(0); l != nil {
+		t.Fatalf("newRPCLimiter(0) = %+v, want nil", l)
+	}
+	var l *rpcLimiter
+	if err := l.wait(context.Background()); err != nil {
+		t.Errorf("nil limiter wait: %v", err)
+	}
+}
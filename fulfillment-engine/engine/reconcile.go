@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// reconcileTimeout bounds the one-shot reconciliation
+const reconcileTimeout = 10 * time.Minute
+
+// Reconciliation issues
+const (
+	// The WAL shows a transaction submitted (or confirmed) but the request is still pending on-chain
+	issueSubmittedNotSettled = "submitted_not_settled"
+	// The WAL shows a transaction submitted for a request id the vault has not issued (rolled back by a reorg)
+	issueNotOnChain = "not_on_chain"
+	// The request is settled on-chain but the WAL has no record of it being fulfilled
+	issueSettledWithoutRecord = "settled_without_record"
+)
+
+// ReconcileDiscrepancy is a request whose on-chain state disagrees with the WAL
+type ReconcileDiscrepancy struct {
+	VaultName string `json:"vault_name"`
+	Type      string `json:"type"` // "deposit" or "withdrawal"
+	ID        string `json:"id"`
+	Issue     string `json:"issue"`
+	WALStage  string `json:"wal_stage,omitempty"` // last stage recorded for the request
+	TxHash    string `json:"tx_hash,omitempty"`   // last transaction submitted for the request
+}
+
+// ReconcileReport is the result of comparing the WAL with on-chain request state
+type ReconcileReport struct {
+	CheckedAt       time.Time              `json:"checked_at"`
+	RequestsChecked int                    `json:"requests_checked"`
+	Discrepancies   []ReconcileDiscrepancy `json:"discrepancies"`
+}
+
+// walHistory summarizes the WAL records of one request
+type walHistory struct {
+	last       walRecord // last record written
+	submission walRecord // last submitted record (zero if no transaction was sent)
+}
+
+// loadWALHistory reads the WAL at path and summarizes it per request
+func loadWALHistory(path string, maxFiles int) (map[walKey]*walHistory, error) {
+	history := make(map[walKey]*walHistory)
+	err := readWAL(path, maxFiles, func(rec walRecord) {
+		key := walKey{rec.VaultName, rec.Type, rec.ID}
+		h, ok := history[key]
+		if !ok {
+			h = &walHistory{}
+			history[key] = h
+		}
+		h.last = rec
+		if rec.Stage == walSubmitted {
+			h.submission = rec
+		}
+	})
+	return history, err
+}
+
+// reconcileVault compares one vault's requests with the WAL. Every request the WAL mentions is
+// checked, as is every settled request from the lowest id in the WAL on, so requests fulfilled
+// before the WAL was enabled are not reported. It returns the discrepancies and the number of
+// requests checked.
+func reconcileVault(ctx context.Context, vaultName string, chain Fulfillment, history map[walKey]*walHistory) ([]ReconcileDiscrepancy, int, error) {
+	discrepancies := []ReconcileDiscrepancy{}
+	checked := 0
+
+	for _, kind := range []string{gasKindDeposit, gasKindWithdrawal} {
+		nextId := chain.GetNextDepositId
+		pending := func(ctx context.Context, id *big.Int) (bool, error) {
+			deposit, err := chain.GetPendingDeposit(ctx, id)
+			if err != nil {
+				return false, err
+			}
+			return deposit.User != (common.Address{}) && !deposit.Fulfilled, nil
+		}
+		if kind == gasKindWithdrawal {
+			nextId = chain.GetNextWithdrawalId
+			pending = func(ctx context.Context, id *big.Int) (bool, error) {
+				withdrawal, err := chain.GetPendingWithdrawal(ctx, id)
+				if err != nil {
+					return false, err
+				}
+				return withdrawal.User != (common.Address{}) && !withdrawal.Fulfilled, nil
+			}
+		}
+
+		// Requests of this vault and kind in the WAL, and the lowest id among them
+		recorded := make(map[string]*walHistory)
+		var fromId *big.Int
+		for key, h := range history {
+			if key.vaultName != vaultName || key.kind != kind {
+				continue
+			}
+			id, ok := new(big.Int).SetString(key.id, 10)
+			if !ok {
+				continue
+			}
+			recorded[id.String()] = h
+			if fromId == nil || id.Cmp(fromId) < 0 {
+				fromId = id
+			}
+		}
+		if fromId == nil {
+			continue
+		}
+
+		next, err := nextId(ctx)
+		if err != nil {
+			return nil, checked, fmt.Errorf("get next %s id: %v", kind, err)
+		}
+
+		discrepancy := func(id string, issue string) ReconcileDiscrepancy {
+			d := ReconcileDiscrepancy{VaultName: vaultName, Type: kind, ID: id, Issue: issue}
+			if h, ok := recorded[id]; ok {
+				d.WALStage = h.last.Stage
+				d.TxHash = h.submission.TxHash
+			}
+			return d
+		}
+
+		// Submissions for ids the vault never issued
+		for id, h := range recorded {
+			requestId, _ := new(big.Int).SetString(id, 10)
+			if requestId.Cmp(next) >= 0 && h.submission.TxHash != "" {
+				checked++
+				discrepancies = append(discrepancies, discrepancy(id, issueNotOnChain))
+			}
+		}
+
+		for id := new(big.Int).Set(fromId); id.Cmp(next) < 0; id.Add(id, big.NewInt(1)) {
+			if err := ctx.Err(); err != nil {
+				return nil, checked, err
+			}
+			isPending, err := pending(ctx, id)
+			if err != nil {
+				return nil, checked, fmt.Errorf("get pending %s %s: %v", kind, id, err)
+			}
+			checked++
+
+			h, ok := recorded[id.String()]
+			switch {
+			case isPending && ok && h.submission.TxHash != "" && h.last.Stage != walFailed:
+				discrepancies = append(discrepancies, discrepancy(id.String(), issueSubmittedNotSettled))
+			case !isPending && (!ok || (h.submission.TxHash == "" && h.last.Stage != walSettled)):
+				discrepancies = append(discrepancies, discrepancy(id.String(), issueSettledWithoutRecord))
+			}
+		}
+	}
+
+	sort.Slice(discrepancies, func(i, j int) bool {
+		a, b := discrepancies[i], discrepancies[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if len(a.ID) != len(b.ID) {
+			return len(a.ID) < len(b.ID) // numeric order of decimal ids
+		}
+		return a.ID < b.ID
+	})
+	return discrepancies, checked, nil
+}
+
+// runReconcile cross-references the WAL with every vault's on-chain request state and writes a
+// JSON report to w. It reports whether any discrepancy was found.
+func runReconcile(config *Config, fulfillers []*Fulfiller, w io.Writer) (bool, error) {
+	if config.WALFile == "" {
+		return false, fmt.Errorf("WAL_FILE is not set")
+	}
+	history, err := loadWALHistory(config.WALFile, config.WALMaxFiles)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	report := ReconcileReport{CheckedAt: time.Now().UTC(), Discrepancies: []ReconcileDiscrepancy{}}
+	for _, f := range fulfillers {
+		discrepancies, checked, err := reconcileVault(ctx, f.vaultConfig.Name, f, history)
+		if err != nil {
+			return false, fmt.Errorf("vault %s: %v", f.vaultConfig.Name, err)
+		}
+		report.RequestsChecked += checked
+		report.Discrepancies = append(report.Discrepancies, discrepancies...)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return false, err
+	}
+	return len(report.Discrepancies) > 0, nil
+}
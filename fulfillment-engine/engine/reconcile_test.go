@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReconcileVault(t *testing.T) {
+	user := common.HexToAddress("0x01")
+	chain := &fakeFulfillment{
+		deposits: map[int64]*PendingDeposit{
+			0: {},           // settled before the WAL was enabled
+			1: {},           // fulfilled by us
+			2: {User: user}, // submitted, but still pending
+			3: {},           // settled with no submission recorded
+			4: {User: user}, // failed attempt, still pending
+			5: {},           // settled by another instance
+		},
+		withdrawals: map[int64]*PendingWithdrawal{},
+	}
+
+	history := make(map[walKey]*walHistory)
+	record := func(kind, id, stage, txHash string) {
+		key := walKey{"Test", kind, id}
+		h, ok := history[key]
+		if !ok {
+			h = &walHistory{}
+			history[key] = h
+		}
+		rec := walRecord{VaultName: "Test", Type: kind, ID: id, Stage: stage, TxHash: txHash}
+		h.last = rec
+		if stage == walSubmitted {
+			h.submission = rec
+		}
+	}
+	record("deposit", "1", walSubmitted, "0x01")
+	record("deposit", "1", walConfirmed, "0x01")
+	record("deposit", "2", walSubmitted, "0x02")
+	record("deposit", "2", walConfirmed, "0x02")
+	record("deposit", "3", walStarted, "")
+	record("deposit", "4", walSubmitted, "0x04")
+	record("deposit", "4", walFailed, "0x04")
+	record("deposit", "5", walSettled, "")
+	record("withdrawal", "0", walSubmitted, "0x05") // rolled back by a reorg
+
+	// Another vault's request with the same id is not checked against this vault
+	history[walKey{"Other", "deposit", "2"}] = &walHistory{last: walRecord{Stage: walFailed}}
+
+	discrepancies, checked, err := reconcileVault(context.Background(), "Test", chain, history)
+	if err != nil {
+		t.Fatalf("reconcileVault: %v", err)
+	}
+	if checked != 6 {
+		t.Errorf("checked %d requests, want 6", checked)
+	}
+
+	var got []string
+	for _, d := range discrepancies {
+		got = append(got, fmt.Sprintf("%s:%s:%s:%s", d.Type, d.ID, d.Issue, d.TxHash))
+	}
+	want := []string{
+		"deposit:2:submitted_not_settled:0x02",
+		"deposit:3:settled_without_record:",
+		"withdrawal:0:not_on_chain:0x05",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("discrepancies = %v, want %v", got, want)
+	}
+}
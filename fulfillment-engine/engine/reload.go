@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/joho/godotenv"
+)
+
+// configMu guards the Config fields a SIGHUP reload may change. Code reading them after
+// startup goes through the accessors below.
+var configMu sync.RWMutex
+
+// reloadableFields are the Config fields applied by a reload; changes to any other field
+// only take effect after a restart
+var reloadableFields = map[string]bool{
+	"LogLevel":                    true,
+	"PollInterval":                true,
+	"PollJitter":                  true,
+	"MaxDepositValue":             true,
+	"MaxWithdrawalShares":         true,
+	"FulfillDenylist":             true,
+	"FulfillAllowlist":            true,
+	"QuoteBalanceThreshold":       true,
+	"UnderlyingBalanceThreshold":  true,
+	"UnderlyingBalanceThresholds": true,
+}
+
+// reloadConfig re-reads the environment and .env file and applies the reloadable settings
+// to the live config. The RPC connections, listeners and fulfillers are left running.
+func reloadConfig(config *Config) {
+	// Values already in the environment win over .env in LoadConfig, so let the file override them
+	_ = godotenv.Overload()
+
+	next, err := LoadConfig()
+	if err != nil {
+		Logger.Error("Config reload failed, keeping current settings", "error", err)
+		return
+	}
+
+	applied, restartRequired := applyConfig(config, next)
+	logLevel.Set(parseLogLevel(next.LogLevel))
+
+	Logger.Info("Config reloaded", "applied", applied)
+	if len(restartRequired) > 0 {
+		Logger.Warn("Changed settings require a restart to take effect", "settings", restartRequired)
+	}
+}
+
+// applyConfig copies the reloadable fields of next into config and returns the names of the
+// fields it changed and of the changed fields that need a restart
+func applyConfig(config, next *Config) (applied, restartRequired []string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	current := reflect.ValueOf(config).Elem()
+	updated := reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		if reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			continue
+		}
+		name := current.Type().Field(i).Name
+		if !reloadableFields[name] {
+			restartRequired = append(restartRequired, name)
+			continue
+		}
+		current.Field(i).Set(updated.Field(i))
+		applied = append(applied, name)
+	}
+	return applied, restartRequired
+}
+
+// pollTiming returns the poll interval and the max random delay added to it
+func (c *Config) pollTiming() (interval, jitter time.Duration) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return time.Duration(c.PollInterval) * time.Second, c.PollJitter
+}
+
+// vaultPollTiming is pollTiming with the vault's own poll interval, if it has one
+func (c *Config) vaultPollTiming(vault VaultConfig) (interval, jitter time.Duration) {
+	interval, jitter = c.pollTiming()
+	if vault.PollInterval > 0 {
+		interval = time.Duration(vault.PollInterval) * time.Second
+	}
+	return interval, jitter
+}
+
+// requestLimits returns the auto-fulfillment limits (nil if unlimited)
+func (c *Config) requestLimits() (maxDepositValue, maxWithdrawalShares *big.Int) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return c.MaxDepositValue, c.MaxWithdrawalShares
+}
+
+// balanceThreshold returns the low-balance alert threshold of a token (nil if unmonitored)
+func (c *Config) balanceThreshold(kind string, token common.Address) *big.Int {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if kind == "quote" {
+		return c.QuoteBalanceThreshold
+	}
+	if threshold, ok := c.UnderlyingBalanceThresholds[token]; ok {
+		return threshold
+	}
+	return c.UnderlyingBalanceThreshold
+}
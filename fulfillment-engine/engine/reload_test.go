@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestApplyConfig(t *testing.T) {
+	user := common.HexToAddress("0x01")
+	config := &Config{
+		PollInterval: 12,
+		RPCURLs:      []string{"https://a.example"},
+		LogFormat:    "TEXT",
+	}
+	next := &Config{
+		PollInterval:    30,
+		RPCURLs:         []string{"https://b.example"},
+		LogFormat:       "TEXT",
+		MaxDepositValue: big.NewInt(1000),
+		FulfillDenylist: map[common.Address]bool{user: true},
+	}
+
+	applied, restartRequired := applyConfig(config, next)
+
+	if fmt.Sprint(applied) != "[PollInterval MaxDepositValue FulfillDenylist]" {
+		t.Errorf("applied = %v", applied)
+	}
+	if fmt.Sprint(restartRequired) != "[RPCURLs]" {
+		t.Errorf("restartRequired = %v", restartRequired)
+	}
+	if interval, _ := config.pollTiming(); interval != 30*time.Second {
+		t.Errorf("poll interval = %v, want 30s", interval)
+	}
+	if interval, _ := config.vaultPollTiming(VaultConfig{}); interval != 30*time.Second {
+		t.Errorf("default vault poll interval = %v, want 30s", interval)
+	}
+	if interval, _ := config.vaultPollTiming(VaultConfig{PollInterval: 4}); interval != 4*time.Second {
+		t.Errorf("vault poll interval = %v, want its own 4s", interval)
+	}
+	if maxDeposit, _ := config.requestLimits(); maxDeposit == nil || maxDeposit.Int64() != 1000 {
+		t.Errorf("max deposit value = %v, want 1000", maxDeposit)
+	}
+	if reason := userFilterReason(config, user); reason != "denylisted" {
+		t.Errorf("filter reason = %q, want denylisted", reason)
+	}
+	if config.RPCURLs[0] != "https://a.example" {
+		t.Errorf("RPC endpoints changed without a restart: %v", config.RPCURLs)
+	}
+}
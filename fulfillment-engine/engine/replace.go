@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// bumpGasPrice raises price by 20% (at least 1 wei), above the 10% nodes require to accept a replacement
+func bumpGasPrice(price *big.Int) *big.Int {
+	bumped := new(big.Int).Div(new(big.Int).Mul(price, big.NewInt(12)), big.NewInt(10))
+	if bumped.Cmp(price) <= 0 {
+		bumped = new(big.Int).Add(price, big.NewInt(1))
+	}
+	return bumped
+}
+
+// replaceTransaction re-sends tx at the same nonce with a bumped gas price (or the current
+// suggested price if that is higher), so a transaction stuck behind rising fees can be mined
+func (f *fulfillerAccount) replaceTransaction(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	gasPrice := bumpGasPrice(tx.GasPrice())
+	suggested, err := withCallTimeout(ctx, f.callTimeout, "SuggestGasPrice", f.client.SuggestGasPrice)
+	if err != nil {
+		return nil, fmt.Errorf("get gas price: %w", err)
+	}
+	if suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	// The original keeps waiting at its price rather than being replaced above MAX_GAS_PRICE_GWEI
+	if f.maxGasPrice != nil && gasPrice.Cmp(f.maxGasPrice) > 0 {
+		Logger.Warn("Gas price above maximum, not replacing transaction",
+			"tx_hash", tx.Hash().Hex(),
+			"gas_price_gwei", formatGwei(gasPrice),
+			"max_gas_price_gwei", formatGwei(f.maxGasPrice),
+		)
+		return nil, fmt.Errorf("%w: %s gwei > %s gwei", ErrGasPriceTooHigh, formatGwei(gasPrice), formatGwei(f.maxGasPrice))
+	}
+
+	chainID, err := f.getChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replacement := types.NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), gasPrice, tx.Data())
+	signedTx, err := types.SignTx(replacement, types.NewEIP155Signer(chainID), f.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	if err := f.client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, fmt.Errorf("send replacement at nonce %d: %w", tx.Nonce(), err)
+	}
+	return signedTx, nil
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// defaultSettledReverts are the SectorVault errors a fulfillment reverts with when the request
+// was fulfilled or cancelled first. The vault deletes settled requests, hence the NotFound errors.
+var defaultSettledReverts = []string{
+	"DepositAlreadyFulfilled()",
+	"DepositNotFound()",
+	"WithdrawalAlreadyFulfilled()",
+	"WithdrawalNotFound()",
+}
+
+// revertMatcher matches a revert by its 4-byte selector, or by a substring of its reason
+type revertMatcher struct {
+	selector  []byte
+	substring string
+}
+
+// parseRevertMatcher parses a SETTLED_REVERTS entry: an error signature such as
+// "DepositNotFound()", a selector such as "0x12345678", or any other text to match in the reason
+func parseRevertMatcher(entry string) (revertMatcher, error) {
+	switch {
+	case strings.HasSuffix(entry, ")"):
+		return revertMatcher{selector: crypto.Keccak256([]byte(entry))[:4]}, nil
+	case strings.HasPrefix(entry, "0x"):
+		selector, err := hex.DecodeString(entry[2:])
+		if err != nil || len(selector) != 4 {
+			return revertMatcher{}, fmt.Errorf("invalid revert selector %q - expected 0x and 8 hex digits", entry)
+		}
+		return revertMatcher{selector: selector}, nil
+	default:
+		return revertMatcher{substring: entry}, nil
+	}
+}
+
+func (m revertMatcher) matches(data []byte, reason string) bool {
+	if m.selector != nil {
+		return len(data) >= 4 && string(data[:4]) == string(m.selector)
+	}
+	return strings.Contains(reason, m.substring)
+}
+
+// settledRevert replays a reverted fulfillment call against the latest state and reports whether
+// it fails with one of the SETTLED_REVERTS, i.e. the request was settled before our transaction
+// was mined. The returned reason describes the revert for logging.
+func (f *Fulfiller) settledRevert(ctx context.Context, data []byte) (string, bool) {
+	to := f.vaultConfig.Address
+	_, err := withCallTimeout(ctx, f.config.RPCCallTimeout, "CallContract", func(ctx context.Context) ([]byte, error) {
+		return f.client.CallContract(ctx, ethereum.CallMsg{
+			From: f.account.fromAddress,
+			To:   &to,
+			Data: data,
+		}, nil)
+	})
+	if err == nil {
+		return "", false
+	}
+
+	reason := err.Error()
+	revert := revertData(err)
+	if msg, unpackErr := abi.UnpackRevert(revert); unpackErr == nil {
+		reason = msg
+	} else if len(revert) >= 4 {
+		reason = fmt.Sprintf("%s (selector %s)", reason, hexutil.Encode(revert[:4]))
+	}
+
+	for _, m := range f.config.SettledReverts {
+		if m.matches(revert, reason) {
+			return reason, true
+		}
+	}
+	return reason, false
+}
+
+// revertData returns the revert data attached to a failed eth_call, or nil if the node sent none
+func revertData(err error) []byte {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+	encoded, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+	data, decodeErr := hexutil.Decode(encoded)
+	if decodeErr != nil {
+		return nil
+	}
+	return data
+}
+
+// errCallUnsupported means a contract reverted or returned nothing for a view call, as when
+// it does not implement the function
+var errCallUnsupported = errors.New("call not supported by contract")
+
+// isCallRevert reports whether a failed eth_call was reverted by the contract, rather than
+// failing in the node or on the way to it
+func isCallRevert(err error) bool {
+	return len(revertData(err)) > 0 || strings.Contains(err.Error(), "execution reverted")
+}
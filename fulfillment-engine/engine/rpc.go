@@ -0,0 +1,380 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// Consecutive failures (on every endpoint) before the clients are re-dialed
+	rpcMaxConsecutiveFailures = 3
+	// Reconnect backoff bounds
+	rpcReconnectInitialBackoff = 1 * time.Second
+	rpcReconnectMaxBackoff     = 60 * time.Second
+	// Timeout for the health check performed after re-dialing
+	rpcHealthCheckTimeout = 10 * time.Second
+	// How often to try returning to the primary endpoint after a failover
+	rpcPrimaryRetryInterval = 60 * time.Second
+)
+
+// rpcEndpoint is one configured RPC URL and its (lazily dialed) client
+type rpcEndpoint struct {
+	url    string
+	client *ethclient.Client
+}
+
+// RPCClient wraps one or more *ethclient.Client endpoints. Calls go to the active
+// endpoint and transparently fail over to the next one on connection errors; after a
+// failover the primary is periodically re-checked and restored once healthy. When every
+// endpoint keeps failing, all endpoints are re-dialed with exponential backoff.
+// All fulfillers and listeners share one RPCClient, so a failover re-wires every consumer at once.
+type RPCClient struct {
+	mu        sync.RWMutex
+	endpoints []*rpcEndpoint
+	active    int
+
+	failures     atomic.Int32
+	connected    atomic.Bool
+	reconnecting atomic.Bool
+	closed       atomic.Bool
+	stop         chan struct{}
+
+	limiter *rpcLimiter // Throttle shared with the other clients (unlimited if nil)
+}
+
+var _ EthClient = (*RPCClient)(nil)
+
+// DialRPCClient connects to the given endpoints; the first one is the primary
+func DialRPCClient(urls []string) (*RPCClient, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no RPC endpoints configured")
+	}
+
+	r := &RPCClient{stop: make(chan struct{})}
+	for _, url := range urls {
+		r.endpoints = append(r.endpoints, &rpcEndpoint{url: url})
+	}
+
+	// The primary must dial; fallbacks are dialed lazily on first failover
+	client, err := ethclient.Dial(urls[0])
+	if err != nil {
+		return nil, err
+	}
+	r.endpoints[0].client = client
+	r.connected.Store(true)
+
+	if len(r.endpoints) > 1 {
+		go r.watchPrimary()
+	}
+	return r, nil
+}
+
+// Connected reports whether the last calls to the endpoint succeeded
+func (r *RPCClient) Connected() bool {
+	return r.connected.Load()
+}
+
+// ActiveURL returns the endpoint currently serving calls
+func (r *RPCClient) ActiveURL() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.endpoints[r.active].url
+}
+
+// clientAt returns the client for endpoint i, dialing it if needed
+func (r *RPCClient) clientAt(i int) (*ethclient.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ep := r.endpoints[i]
+	if ep.client == nil {
+		client, err := ethclient.Dial(ep.url)
+		if err != nil {
+			return nil, err
+		}
+		ep.client = client
+	}
+	return ep.client, nil
+}
+
+// switchTo makes endpoint i the active one
+func (r *RPCClient) switchTo(i int, reason string) {
+	r.mu.Lock()
+	old := r.active
+	r.active = i
+	r.mu.Unlock()
+
+	if old != i {
+		Logger.Warn("RPC endpoint switched",
+			"reason", reason,
+			"old_endpoint", r.endpoints[old].url,
+			"new_endpoint", r.endpoints[i].url,
+		)
+	}
+}
+
+// callRPC runs fn against the active endpoint, failing over to the remaining endpoints in
+// order on connection errors. Errors returned by the node itself (reverts etc.) are not retried.
+// The call first waits for the rate limiter, if any; failover attempts are not limited again.
+func callRPC[T any](ctx context.Context, r *RPCClient, fn func(*ethclient.Client) (T, error)) (T, error) {
+	var result T
+	if err := r.limiter.wait(ctx); err != nil {
+		return result, err
+	}
+
+	r.mu.RLock()
+	start := r.active
+	r.mu.RUnlock()
+
+	var err error
+	for n := 0; n < len(r.endpoints); n++ {
+		i := (start + n) % len(r.endpoints)
+
+		client, dialErr := r.clientAt(i)
+		if dialErr != nil {
+			err = dialErr
+			continue
+		}
+
+		result, err = fn(client)
+		if err == nil || !isConnectionError(err) {
+			if n > 0 {
+				r.switchTo(i, "failover")
+			}
+			r.failures.Store(0)
+			r.connected.Store(true)
+			return result, err
+		}
+
+		Logger.Debug("RPC connection error", "endpoint", r.endpoints[i].url, "error", err)
+	}
+
+	r.recordFailure(err)
+	return result, err
+}
+
+// recordFailure tracks calls that failed on every endpoint and triggers a reconnect
+func (r *RPCClient) recordFailure(err error) {
+	failures := r.failures.Add(1)
+	Logger.Debug("RPC call failed on all endpoints", "consecutive_failures", failures, "error", err)
+
+	if failures >= rpcMaxConsecutiveFailures {
+		r.connected.Store(false)
+		if r.reconnecting.CompareAndSwap(false, true) {
+			go r.reconnect()
+		}
+	}
+}
+
+// healthy re-dials url and checks that it answers
+func healthy(url string) (*ethclient.Client, error) {
+	client, err := ethclient.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), rpcHealthCheckTimeout)
+	defer cancel()
+	if _, err := client.BlockNumber(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// replaceClient swaps in a freshly dialed client for endpoint i
+func (r *RPCClient) replaceClient(i int, client *ethclient.Client) {
+	r.mu.Lock()
+	old := r.endpoints[i].client
+	r.endpoints[i].client = client
+	r.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+}
+
+// reconnect re-dials the endpoints (primary first) with exponential backoff until one is healthy
+func (r *RPCClient) reconnect() {
+	defer r.reconnecting.Store(false)
+
+	backoff := rpcReconnectInitialBackoff
+	for attempt := 1; !r.closed.Load(); attempt++ {
+		Logger.Warn("RPC connection lost, reconnecting",
+			"attempt", attempt,
+			"backoff", backoff,
+		)
+		time.Sleep(backoff)
+
+		for i, ep := range r.endpoints {
+			client, err := healthy(ep.url)
+			if err != nil {
+				Logger.Warn("RPC reconnect attempt failed", "attempt", attempt, "endpoint", ep.url, "error", err)
+				continue
+			}
+
+			r.replaceClient(i, client)
+			r.switchTo(i, "reconnect")
+			r.failures.Store(0)
+			r.connected.Store(true)
+			Logger.Info("RPC connection re-established", "attempt", attempt, "endpoint", ep.url)
+			return
+		}
+
+		backoff = min(backoff*2, rpcReconnectMaxBackoff)
+	}
+}
+
+// watchPrimary periodically tries to return to the primary endpoint after a failover
+func (r *RPCClient) watchPrimary() {
+	ticker := time.NewTicker(rpcPrimaryRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.RLock()
+		onPrimary := r.active == 0
+		r.mu.RUnlock()
+		if onPrimary {
+			continue
+		}
+
+		client, err := healthy(r.endpoints[0].url)
+		if err != nil {
+			Logger.Debug("Primary RPC endpoint still unavailable", "endpoint", r.endpoints[0].url, "error", err)
+			continue
+		}
+		r.replaceClient(0, client)
+		r.switchTo(0, "primary recovered")
+	}
+}
+
+// withCallTimeout bounds a single RPC call by timeout (no bound if zero) and names the
+// stalled call in the returned error
+func withCallTimeout[T any](ctx context.Context, timeout time.Duration, call string, fn func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, fmt.Errorf("RPC call %s timed out after %s: %w", call, timeout, context.DeadlineExceeded)
+	}
+	return result, err
+}
+
+// isConnectionError distinguishes transport failures from errors returned by the node
+// (reverts, not-found, etc.) which say nothing about connection health
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+func (r *RPCClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) ([]byte, error) {
+		return c.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+func (r *RPCClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (uint64, error) {
+		return c.PendingNonceAt(ctx, account)
+	})
+}
+
+func (r *RPCClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (uint64, error) {
+		return c.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+func (r *RPCClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (*big.Int, error) {
+		return c.SuggestGasPrice(ctx)
+	})
+}
+
+func (r *RPCClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (*big.Int, error) {
+		return c.NetworkID(ctx)
+	})
+}
+
+func (r *RPCClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := callRPC(ctx, r, func(c *ethclient.Client) (struct{}, error) {
+		return struct{}{}, c.SendTransaction(ctx, tx)
+	})
+	return err
+}
+
+func (r *RPCClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (*types.Receipt, error) {
+		return c.TransactionReceipt(ctx, txHash)
+	})
+}
+
+func (r *RPCClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (*types.Header, error) {
+		return c.HeaderByNumber(ctx, number)
+	})
+}
+
+func (r *RPCClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) ([]types.Log, error) {
+		return c.FilterLogs(ctx, query)
+	})
+}
+
+// SubscribeFilterLogs subscribes on the active endpoint, which must be a WebSocket (or IPC) URL.
+// The subscription is tied to that endpoint and is not moved on failover.
+func (r *RPCClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return callRPC(ctx, r, func(c *ethclient.Client) (ethereum.Subscription, error) {
+		return c.SubscribeFilterLogs(ctx, query, ch)
+	})
+}
+
+// splitRPCClient sends log subscriptions to a dedicated WebSocket client and
+// everything else to the HTTP client
+type splitRPCClient struct {
+	*RPCClient
+	ws *RPCClient
+}
+
+func (c *splitRPCClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return c.ws.SubscribeFilterLogs(ctx, query, ch)
+}
+
+func (r *RPCClient) Close() {
+	if r.closed.Swap(true) {
+		return
+	}
+	close(r.stop)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ep := range r.endpoints {
+		if ep.client != nil {
+			ep.client.Close()
+		}
+	}
+}
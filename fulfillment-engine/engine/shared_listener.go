@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// SharedEventListener polls all vaults with a single header and FilterLogs query per
+// interval and dispatches each log to the per-vault EventListener that owns its address
+type SharedEventListener struct {
+	client    listenerClient
+	config    *Config
+	listeners map[common.Address]*EventListener
+	addresses []common.Address
+	lastBlock uint64
+	takeover  <-chan struct{} // closed when this standby instance becomes the leader
+}
+
+func NewSharedEventListener(client listenerClient, config *Config, listeners []*EventListener) *SharedEventListener {
+	s := &SharedEventListener{
+		client:    client,
+		config:    config,
+		listeners: make(map[common.Address]*EventListener),
+	}
+	for _, l := range listeners {
+		s.listeners[l.vaultConfig.Address] = l
+		s.addresses = append(s.addresses, l.vaultConfig.Address)
+		s.takeover = l.takeover // all listeners share the engine's leader elector
+	}
+	return s
+}
+
+func (s *SharedEventListener) Start(ctx context.Context) error {
+	// Get current block
+	header, err := withCallTimeout(ctx, s.config.RPCCallTimeout, "HeaderByNumber", func(ctx context.Context) (*types.Header, error) {
+		return s.client.HeaderByNumber(ctx, nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get latest block: %v", err)
+	}
+	currentBlock := header.Number.Uint64()
+
+	// Scan each vault for requests left pending while the engine was down
+	for _, address := range s.addresses {
+		if err := s.listeners[address].scanPendingRequests(ctx, currentBlock); err != nil {
+			return err
+		}
+	}
+
+	s.setLastBlock(currentBlock)
+
+	interval, jitter := s.config.pollTiming()
+	Logger.Info("Shared event listener started",
+		"vault_count", len(s.addresses),
+		"start_block", s.lastBlock,
+		"poll_interval_seconds", int(interval.Seconds()),
+	)
+
+	timer := time.NewTimer(interval + randomDelay(jitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.takeover:
+			// Pick up anything the previous leader left unfulfilled
+			s.takeover = nil
+			Logger.Info("Now leader, rescanning for pending requests", "vault_count", len(s.addresses))
+			for _, address := range s.addresses {
+				if err := s.listeners[address].scanPendingRequests(ctx, s.lastBlock); err != nil {
+					return err
+				}
+			}
+		case <-timer.C:
+			if err := s.poll(ctx); err != nil {
+				Logger.Error("Polling error", "error", err)
+			}
+			interval, jitter := s.config.pollTiming()
+			timer.Reset(interval + randomDelay(jitter))
+		}
+	}
+}
+
+func (s *SharedEventListener) poll(ctx context.Context) error {
+	// Get current block
+	header, err := withCallTimeout(ctx, s.config.RPCCallTimeout, "HeaderByNumber", func(ctx context.Context) (*types.Header, error) {
+		return s.client.HeaderByNumber(ctx, nil)
+	})
+	if err != nil {
+		return err
+	}
+	currentBlock := header.Number.Uint64()
+
+	for _, address := range s.addresses {
+		s.listeners[address].retryDeferred(ctx)
+	}
+
+	if currentBlock <= s.lastBlock {
+		Logger.Debug("No new blocks", "current_block", currentBlock)
+		return nil
+	}
+
+	// Catch up in LOG_QUERY_CHUNK_SIZE windows, keeping progress if a later window fails
+	for s.lastBlock < currentBlock {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from := s.lastBlock + 1
+		to := pollChunkEnd(from, currentBlock, s.config.PollChunkSize)
+
+		Logger.Debug("Checking block range for events across vaults",
+			"from_block", from,
+			"to_block", to,
+			"vault_count", len(s.addresses),
+		)
+
+		logs, err := withCallTimeout(ctx, s.config.RPCCallTimeout, "FilterLogs", func(ctx context.Context) ([]types.Log, error) {
+			return s.client.FilterLogs(ctx, requestEventsQuery(from, to, s.addresses, s.config.FulfillMode))
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(logs) > 0 {
+			Logger.Info("Events detected", "event_count", len(logs))
+		}
+
+		sortRequests(logs, s.config.RequestOrder, func(vLog types.Log) *big.Int {
+			if listener, ok := s.listeners[vLog.Address]; ok {
+				return listener.requestValue(ctx, vLog)
+			}
+			return new(big.Int)
+		})
+		for _, vLog := range logs {
+			listener, ok := s.listeners[vLog.Address]
+			if !ok {
+				Logger.Warn("Received log for unknown vault",
+					"address", vLog.Address.Hex(),
+					"tx_hash", vLog.TxHash.Hex(),
+				)
+				continue
+			}
+			listener.processLog(ctx, vLog)
+		}
+
+		s.setLastBlock(to)
+	}
+	return nil
+}
+
+// setLastBlock records block as fully processed for every vault
+func (s *SharedEventListener) setLastBlock(block uint64) {
+	s.lastBlock = block
+	for _, l := range s.listeners {
+		if l.fulfiller != nil {
+			l.fulfiller.setProcessedBlock(block)
+		}
+	}
+}
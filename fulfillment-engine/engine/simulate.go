@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// simulateTimeout bounds the one-shot deposit simulation
+const simulateTimeout = 2 * time.Minute
+
+// SimulatedTokenAmount is one underlying token of a simulated deposit fulfillment
+type SimulatedTokenAmount struct {
+	Token    common.Address
+	Decimals uint8
+	Weight   string
+	Price    string // oracle decimals
+	Amount   string // token base units
+	Value    string // oracle decimals
+}
+
+// DepositSimulation is what FulfillDeposit would send for a deposit, computed from live prices
+// and weights without sending anything
+type DepositSimulation struct {
+	VaultName       string
+	Vault           string
+	AllocationMode  string
+	QuoteAmount     string // quote token base units
+	TargetValue     string // quote amount in oracle decimals
+	ProvidedValue   string // oracle decimals
+	Difference      string // provided - target
+	Tolerance       string // max |difference| the vault accepts
+	WithinTolerance bool
+	Tokens          []SimulatedTokenAmount
+
+	MaxOvercommitBps int64  // MAX_DEPOSIT_OVERCOMMIT_BPS (disabled if 0)
+	OracleValue      string // provided value according to the vault oracle's getValue (if the cap is enabled)
+	Overcommitted    bool   // FulfillDeposit would abort with ErrDepositOvercommit
+}
+
+// SimulateDeposit computes the underlying amounts for a deposit of quoteAmount the way
+// FulfillDeposit does, reading prices and weights from the chain
+func (f *Fulfiller) SimulateDeposit(ctx context.Context, quoteAmount *big.Int) (*DepositSimulation, error) {
+	u := f.underlying()
+	amounts, prices, err := f.depositAmounts(ctx, Logger.With("vault_name", f.vaultConfig.Name), u, quoteAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	target := normalizeDecimals(quoteAmount, f.quoteDecimals, f.oracleDecimals)
+	provided := totalValue(amounts, prices, u.decimals)
+	difference := new(big.Int).Sub(provided, target)
+	tolerance := valueTolerance(target, contractToleranceBps)
+
+	sim := &DepositSimulation{
+		VaultName:       f.vaultConfig.Name,
+		Vault:           f.vaultConfig.Address.Hex(),
+		AllocationMode:  f.config.DepositAllocationMode,
+		QuoteAmount:     quoteAmount.String(),
+		TargetValue:     target.String(),
+		ProvidedValue:   provided.String(),
+		Difference:      difference.String(),
+		Tolerance:       tolerance.String(),
+		WithinTolerance: new(big.Int).Abs(difference).Cmp(tolerance) <= 0,
+		Tokens:          make([]SimulatedTokenAmount, len(u.tokens)),
+	}
+	if bps := f.config.MaxDepositOvercommitBps; bps > 0 {
+		oracleValue, err := f.oracleValue(ctx, u.tokens, amounts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to value deposit amounts with the oracle: %v", err)
+		}
+		sim.MaxOvercommitBps = bps
+		sim.OracleValue = oracleValue.String()
+		sim.Overcommitted = exceedsBps(oracleValue, target, bps)
+	}
+	for i, token := range u.tokens {
+		sim.Tokens[i] = SimulatedTokenAmount{
+			Token:    token,
+			Decimals: u.decimals[i],
+			Weight:   u.weights[i].String(),
+			Price:    prices[i].String(),
+			Amount:   amounts[i].String(),
+			Value:    totalValue(amounts[i:i+1], prices[i:i+1], u.decimals[i:i+1]).String(),
+		}
+	}
+	return sim, nil
+}
+
+// runSimulate writes the simulated deposit fulfillment of quoteAmount (quote token base units)
+// for the named vault to w
+func runSimulate(fulfillers []*Fulfiller, vaultName, quoteAmount string, w io.Writer) error {
+	var f *Fulfiller
+	for _, candidate := range fulfillers {
+		if strings.EqualFold(candidate.vaultConfig.Name, vaultName) {
+			f = candidate
+		}
+	}
+	if f == nil {
+		return fmt.Errorf("unknown vault %q", vaultName)
+	}
+	amount, ok := new(big.Int).SetString(quoteAmount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return fmt.Errorf("quote amount %q must be a positive integer in quote token base units", quoteAmount)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), simulateTimeout)
+	defer cancel()
+
+	sim, err := f.SimulateDeposit(ctx, amount)
+	if err != nil {
+		return fmt.Errorf("vault %s: %v", f.vaultConfig.Name, err)
+	}
+	return writeSimulation(w, sim)
+}
+
+// writeSimulation prints the per-token amounts of a simulated deposit and its tolerance check
+func writeSimulation(w io.Writer, sim *DepositSimulation) error {
+	fmt.Fprintf(w, "%s (%s) deposit quote_amount=%s allocation=%s\n", sim.VaultName, sim.Vault, sim.QuoteAmount, sim.AllocationMode)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "token\tdecimals\tweight\tprice\tamount\tvalue\t")
+	for _, t := range sim.Tokens {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t\n", t.Token.Hex(), t.Decimals, t.Weight, t.Price, t.Amount, t.Value)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	result := "ok"
+	if !sim.WithinTolerance {
+		result = "OUTSIDE TOLERANCE"
+	}
+	fmt.Fprintf(w, "target_value=%s provided_value=%s difference=%s tolerance=%s %s\n",
+		sim.TargetValue, sim.ProvidedValue, sim.Difference, sim.Tolerance, result)
+	if sim.MaxOvercommitBps > 0 {
+		result = "ok"
+		if sim.Overcommitted {
+			result = "EXCEEDED, the deposit would be aborted"
+		}
+		fmt.Fprintf(w, "oracle_value=%s max_overcommit_bps=%d %s\n", sim.OracleValue, sim.MaxOvercommitBps, result)
+	}
+	return nil
+}
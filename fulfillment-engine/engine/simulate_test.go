@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestSimulateDeposit(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 8, []testToken{
+		{decimals: 18, weight: 6000, price: "300000000"}, // $3
+		{decimals: 6, weight: 4000, price: "100000000"},  // $1
+	})
+
+	sim, err := f.SimulateDeposit(context.Background(), big.NewInt(10000000)) // 10 USDC
+	if err != nil {
+		t.Fatalf("SimulateDeposit: %v", err)
+	}
+
+	// Same math as a real fulfillment
+	u := f.underlying()
+	want, err := computeDepositAmounts(big.NewInt(10000000), u.weights, []*big.Int{big.NewInt(300000000), big.NewInt(100000000)},
+		u.decimals, 6, 8, contractToleranceBps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, token := range sim.Tokens {
+		if token.Amount != want[i].String() {
+			t.Errorf("token %d amount %s, want %s", i, token.Amount, want[i])
+		}
+	}
+	if sim.TargetValue != "1000000000" || !sim.WithinTolerance {
+		t.Errorf("target value %s, within tolerance %v; want 1000000000, true", sim.TargetValue, sim.WithinTolerance)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions, want none", len(client.sent))
+	}
+
+	var out strings.Builder
+	if err := writeSimulation(&out, sim); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), want[0].String()) || !strings.Contains(out.String(), "tolerance="+sim.Tolerance+" ok") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
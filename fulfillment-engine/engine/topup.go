@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// topUpTimeout bounds each top-up webhook request and command run
+const topUpTimeout = 30 * time.Second
+
+// TopUpRequest is the JSON payload sent to the top-up hook when a fulfiller balance drops
+// below its threshold. Shortfall is the amount that brings the balance back to the threshold.
+type TopUpRequest struct {
+	Fulfiller string `json:"fulfiller"`
+	Token     string `json:"token"`
+	TokenKind string `json:"token_kind"`
+	Balance   string `json:"balance"`
+	Threshold string `json:"threshold"`
+	Shortfall string `json:"shortfall"`
+}
+
+// sendTopUp requests a top-up through the configured webhook and command. The webhook gets the
+// request as a JSON POST; the command is run with the token and shortfall appended to its
+// arguments and the JSON request on stdin. Both run even if one fails.
+func sendTopUp(ctx context.Context, client *http.Client, webhookURL, command string, req TopUpRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal top-up request: %w", err)
+	}
+
+	var errs []string
+	if webhookURL != "" {
+		if err := postJSON(ctx, client, webhookURL, body); err != nil {
+			errs = append(errs, fmt.Sprintf("webhook: %v", err))
+		}
+	}
+	if args := strings.Fields(command); len(args) > 0 {
+		args = append(args, req.Token, req.Shortfall)
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = bytes.NewReader(body)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			errs = append(errs, fmt.Sprintf("command: %v: %s", err, strings.TrimSpace(string(output))))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("top-up request failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
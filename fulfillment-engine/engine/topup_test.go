@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSendTopUp(t *testing.T) {
+	req := TopUpRequest{
+		Fulfiller: "0xF",
+		Token:     "0xToken",
+		TokenKind: "quote",
+		Balance:   "40",
+		Threshold: "100",
+		Shortfall: "60",
+	}
+
+	var posted TopUpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&posted)
+	}))
+	defer server.Close()
+
+	if err := sendTopUp(context.Background(), server.Client(), server.URL, "", req); err != nil {
+		t.Fatalf("sendTopUp: %v", err)
+	}
+	if posted != req {
+		t.Errorf("webhook got %+v, want %+v", posted, req)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("top-up command test uses a shell script")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "topup.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+out+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendTopUp(context.Background(), nil, "", script+" --treasury main", req); err != nil {
+		t.Fatalf("sendTopUp command: %v", err)
+	}
+	args, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(args)); got != "--treasury main 0xToken 60" {
+		t.Errorf("command args = %q, want the configured args followed by token and shortfall", got)
+	}
+
+	if err := sendTopUp(context.Background(), nil, "", "false", req); err == nil {
+		t.Error("failing command reported success")
+	}
+}
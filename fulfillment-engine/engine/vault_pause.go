@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errVaultPaused is returned by the fulfiller while the vault contract reports paused()
+var errVaultPaused = errors.New("vault paused")
+
+// How long a paused() reading is reused, so retrying a backlog of deferred requests costs one call
+const vaultPauseCacheTTL = 5 * time.Second
+
+// vaultPauseState caches the vault's on-chain pause flag (VAULT_PAUSE_CHECK)
+type vaultPauseState struct {
+	mu      sync.Mutex
+	paused  bool
+	checked time.Time // when paused was last read (zero: never)
+}
+
+// probeVaultPause enables the pause check if the vault implements paused(). Vaults without it are
+// fulfilled as before.
+func (f *Fulfiller) probeVaultPause(ctx context.Context) error {
+	paused, err := f.isVaultPaused(ctx)
+	if errors.Is(err, errCallUnsupported) {
+		Logger.Info("Vault has no paused() function, not checking it",
+			"vault_name", f.vaultConfig.Name,
+			"error", err,
+		)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to probe vault paused(): %v", err)
+	}
+
+	f.vaultPause = &vaultPauseState{paused: paused, checked: time.Now()}
+	if paused {
+		Logger.Warn("Vault is paused, deferring fulfillments until it is unpaused", "vault_name", f.vaultConfig.Name)
+	} else {
+		Logger.Info("Vault pause check enabled", "vault_name", f.vaultConfig.Name)
+	}
+	return nil
+}
+
+// checkVaultPaused returns errVaultPaused while the vault is paused on-chain. If paused() cannot be
+// read, the fulfillment goes ahead: a paused vault then reverts it, as without the check.
+func (f *Fulfiller) checkVaultPaused(ctx context.Context) error {
+	s := f.vaultPause
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.checked) >= vaultPauseCacheTTL {
+		paused, err := f.isVaultPaused(ctx)
+		if err != nil {
+			Logger.Warn("Failed to read vault paused(), fulfilling anyway",
+				"vault_name", f.vaultConfig.Name,
+				"error", err,
+			)
+			return nil
+		}
+		if paused && !s.paused {
+			Logger.Warn("Vault paused, deferring fulfillments until it is unpaused", "vault_name", f.vaultConfig.Name)
+		} else if !paused && s.paused {
+			Logger.Info("Vault unpaused, resuming fulfillments", "vault_name", f.vaultConfig.Name)
+		}
+		s.paused = paused
+		s.checked = time.Now()
+	}
+
+	if s.paused {
+		return errVaultPaused
+	}
+	return nil
+}
+
+// isVaultPaused calls the vault's paused(). A revert or empty result is errCallUnsupported.
+func (f *Fulfiller) isVaultPaused(ctx context.Context) (bool, error) {
+	parsedABI, err := ParseSectorVaultABI()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := parsedABI.Pack("paused")
+	if err != nil {
+		return false, err
+	}
+
+	result, err := f.callContract(ctx, "paused", f.vaultConfig.Address, data)
+	if err != nil {
+		if isCallRevert(err) {
+			return false, fmt.Errorf("%w: paused() reverted: %v", errCallUnsupported, err)
+		}
+		return false, err
+	}
+	if len(result) == 0 {
+		return false, fmt.Errorf("%w: paused() returned no data", errCallUnsupported)
+	}
+
+	var paused bool
+	err = parsedABI.UnpackIntoInterface(&paused, "paused", result)
+	if err != nil {
+		return false, err
+	}
+	return paused, nil
+}
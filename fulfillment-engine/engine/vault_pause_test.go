@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestVaultPauseCheck(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 8, []testToken{
+		{decimals: 18, weight: 10000, price: "100000000"},
+	})
+
+	// A vault without paused() is not checked
+	if err := f.probeVaultPause(context.Background()); err != nil {
+		t.Fatalf("probe without paused(): %v", err)
+	}
+	if f.vaultPause != nil {
+		t.Fatal("pause check enabled for a vault without paused()")
+	}
+
+	paused := true
+	client.vaultPaused = &paused
+	if err := f.probeVaultPause(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); !errors.Is(err, errVaultPaused) {
+		t.Fatalf("FulfillDeposit while paused: %v, want errVaultPaused", err)
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions while paused", len(client.sent))
+	}
+
+	// Unpausing takes effect once the cached reading expires
+	paused = false
+	if err := f.checkVaultPaused(context.Background()); !errors.Is(err, errVaultPaused) {
+		t.Errorf("cached reading: %v, want errVaultPaused", err)
+	}
+	f.vaultPause.checked = time.Now().Add(-vaultPauseCacheTTL)
+	if _, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{}); err != nil {
+		t.Fatalf("FulfillDeposit after unpause: %v", err)
+	}
+	if len(client.sent) != 1 {
+		t.Errorf("sent %d transactions after unpause, want 1", len(client.sent))
+	}
+}
@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// Stages of a fulfillment attempt recorded in the WAL
+const (
+	walStarted     = "started"     // accepted for fulfillment
+	walComputed    = "computed"    // amounts and prices determined
+	walSubmitted   = "submitted"   // transaction broadcast
+	walConfirmed   = "confirmed"   // transaction mined successfully
+	walSettled     = "settled"     // settled by another instance before or while we sent ours
+	walFailed      = "failed"      // failed; any transaction sent was reverted or never broadcast
+	walUnconfirmed = "unconfirmed" // stopped waiting (timeout or shutdown); the transaction may still be mined
+)
+
+// walRecord is one line of the fulfillment WAL
+type walRecord struct {
+	Time       time.Time `json:"time"`
+	VaultName  string    `json:"vault_name"`
+	Type       string    `json:"type"` // "deposit" or "withdrawal"
+	ID         string    `json:"id"`
+	Stage      string    `json:"stage"`
+	Amount     string    `json:"amount,omitempty"`      // quote amount (deposit) or shares (withdrawal) requested
+	QuoteValue string    `json:"quote_value,omitempty"` // quote tokens paid out for a withdrawal
+	Amounts    []string  `json:"amounts,omitempty"`     // underlying token amounts
+	Prices     []string  `json:"prices,omitempty"`      // oracle prices the amounts were computed with
+	TxHash     string    `json:"tx_hash,omitempty"`
+	Nonce      *uint64   `json:"nonce,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type walKey struct {
+	vaultName string
+	kind      string
+	id        string
+}
+
+// fulfillmentWAL is an append-only JSONL log of every stage of every fulfillment attempt, rotated
+// by size. It tracks the requests whose transaction was submitted but has not reached a final stage,
+// including those left by a previous run, so a restart waits for that transaction instead of sending
+// a duplicate. A nil WAL records nothing.
+type fulfillmentWAL struct {
+	mu        sync.Mutex
+	file      *rotatingFile
+	submitted map[walKey]walRecord
+}
+
+// openFulfillmentWAL replays the WAL at path (rotated files first) and opens it for appending
+func openFulfillmentWAL(path string, maxSize int64, maxFiles int) (*fulfillmentWAL, error) {
+	w := &fulfillmentWAL{submitted: make(map[walKey]walRecord)}
+	if err := readWAL(path, maxFiles, w.track); err != nil {
+		return nil, err
+	}
+
+	file, err := openRotatingFile(path, maxSize, maxFiles)
+	if err != nil {
+		return nil, fmt.Errorf("open WAL: %v", err)
+	}
+	w.file = file
+	return w, nil
+}
+
+// readWAL passes every record of the WAL at path to fn in the order written, starting with
+// the oldest of up to maxFiles rotated files
+func readWAL(path string, maxFiles int, fn func(walRecord)) error {
+	for i := maxFiles; i >= 0; i-- {
+		name := path
+		if i > 0 {
+			name = fmt.Sprintf("%s.%d", path, i)
+		}
+		if err := readWALFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readWALFile(path string, fn func(walRecord)) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read WAL: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// A line torn by a crash mid-write; the records around it are still valid
+			Logger.Warn("Skipping unreadable WAL record", "file", path, "error", err)
+			continue
+		}
+		fn(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read WAL %s: %v", path, err)
+	}
+	return nil
+}
+
+// track updates the set of submitted, unconfirmed requests with rec
+func (w *fulfillmentWAL) track(rec walRecord) {
+	key := walKey{rec.VaultName, rec.Type, rec.ID}
+	switch rec.Stage {
+	case walSubmitted:
+		w.submitted[key] = rec
+	case walConfirmed, walSettled, walFailed:
+		delete(w.submitted, key)
+	}
+}
+
+// Append writes rec to the WAL and syncs it to disk
+func (w *fulfillmentWAL) Append(rec walRecord) error {
+	if w == nil {
+		return nil
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write WAL: %v", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("sync WAL: %v", err)
+	}
+	w.track(rec)
+	return nil
+}
+
+// Submitted returns the last submission of a request that has not reached a final stage
+func (w *fulfillmentWAL) Submitted(vaultName, kind, id string) (walRecord, bool) {
+	if w == nil {
+		return walRecord{}, false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rec, ok := w.submitted[walKey{vaultName, kind, id}]
+	return rec, ok
+}
+
+func (w *fulfillmentWAL) Close() error {
+	if w == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+// bigStrings formats amounts for a WAL record
+func bigStrings(values []*big.Int) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = v.String()
+	}
+	return out
+}
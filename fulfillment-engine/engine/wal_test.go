@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestFulfillmentWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fulfillments.wal")
+	w, err := openFulfillmentWAL(path, 1<<20, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	for _, rec := range []walRecord{
+		{VaultName: "AI", Type: "deposit", ID: "1", Stage: walStarted},
+		{VaultName: "AI", Type: "deposit", ID: "1", Stage: walSubmitted, TxHash: "0x01"},
+		{VaultName: "AI", Type: "deposit", ID: "2", Stage: walSubmitted, TxHash: "0x02"},
+		{VaultName: "AI", Type: "deposit", ID: "2", Stage: walConfirmed, TxHash: "0x02"},
+		{VaultName: "AI", Type: "withdrawal", ID: "1", Stage: walSubmitted, TxHash: "0x03"},
+		{VaultName: "AI", Type: "withdrawal", ID: "1", Stage: walUnconfirmed, TxHash: "0x03"},
+	} {
+		if err := w.Append(rec); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	w.Close()
+
+	// A crash mid-write leaves a torn last line
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open for append: %v", err)
+	}
+	file.WriteString(`{"vault_name":"AI","type":"dep`)
+	file.Close()
+
+	w, err = openFulfillmentWAL(path, 1<<20, 2)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer w.Close()
+
+	if rec, ok := w.Submitted("AI", "deposit", "1"); !ok || rec.TxHash != "0x01" {
+		t.Errorf("deposit 1 = %+v, %v; want the unconfirmed submission 0x01", rec, ok)
+	}
+	if _, ok := w.Submitted("AI", "deposit", "2"); ok {
+		t.Errorf("confirmed deposit 2 still listed as submitted")
+	}
+	if _, ok := w.Submitted("AI", "withdrawal", "1"); !ok {
+		t.Errorf("withdrawal 1 that timed out is no longer listed as submitted")
+	}
+}
+
+func TestFulfillDepositAwaitsPriorSubmission(t *testing.T) {
+	client := newMockEthClient()
+	f := newTestFulfiller(t, client, 6, 6, []testToken{{price: "1000000", decimals: 18, weight: 10000}})
+
+	wal, err := openFulfillmentWAL(filepath.Join(t.TempDir(), "fulfillments.wal"), 1<<20, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer wal.Close()
+	f.wal = wal
+
+	// Sent before a restart and mined since (the mock reports every transaction as mined)
+	prior := common.HexToHash("0xabc")
+	if err := wal.Append(walRecord{VaultName: "Test", Type: "deposit", ID: "1", Stage: walSubmitted, TxHash: prior.Hex()}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	txHash, err := f.FulfillDeposit(context.Background(), big.NewInt(1), big.NewInt(1000000), time.Time{})
+	if err != nil {
+		t.Fatalf("FulfillDeposit: %v", err)
+	}
+	if txHash != prior {
+		t.Errorf("tx hash %s, want the prior submission %s", txHash.Hex(), prior.Hex())
+	}
+	if len(client.sent) != 0 {
+		t.Errorf("sent %d transactions, want none", len(client.sent))
+	}
+	if _, ok := wal.Submitted("Test", "deposit", "1"); ok {
+		t.Errorf("deposit still listed as submitted after confirmation")
+	}
+}
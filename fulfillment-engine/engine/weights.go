@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WeightSyncer periodically re-reads each vault's underlying tokens and target weights,
+// which governance can change after the engine has cached them at startup
+type WeightSyncer struct {
+	interval   time.Duration
+	fulfillers []*Fulfiller
+}
+
+func NewWeightSyncer(config *Config, fulfillers []*Fulfiller) *WeightSyncer {
+	return &WeightSyncer{interval: config.WeightSyncInterval, fulfillers: fulfillers}
+}
+
+func (w *WeightSyncer) Start(ctx context.Context) error {
+	if w.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			for _, f := range w.fulfillers {
+				if err := f.resyncWeights(ctx); err != nil {
+					Logger.Error("Failed to re-sync underlying weights",
+						"vault_name", f.vaultConfig.Name,
+						"error", err,
+					)
+				}
+			}
+		}
+	}
+}
+
+// resyncWeights re-reads the vault's tokens and target weights and replaces the cached ones if they changed
+func (f *Fulfiller) resyncWeights(ctx context.Context) error {
+	tokens, weights, err := f.fetchUnderlyingTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	current := f.underlying()
+	if !sameTokens(tokens, current.tokens) {
+		return f.replaceUnderlying(ctx, Logger, tokens, weights)
+	}
+	if sameWeights(weights, current.weights) {
+		Logger.Debug("Underlying weights unchanged", "vault_name", f.vaultConfig.Name)
+		return nil
+	}
+
+	f.setUnderlying(tokens, weights)
+	for i, token := range tokens {
+		if weights[i].Cmp(current.weights[i]) == 0 {
+			continue
+		}
+		Logger.Warn("VAULT TARGET WEIGHT CHANGED - applies to all subsequent fulfillments",
+			"vault_name", f.vaultConfig.Name,
+			"token", token.Hex(),
+			"old_weight", current.weights[i].String(),
+			"new_weight", weights[i].String(),
+		)
+	}
+	return nil
+}
+
+// refreshAfterRevert checks whether a reverted fulfillment was caused by the vault's token list
+// changing since it was cached (the amounts array no longer matches) and if so reloads it,
+// so the next attempt is built for the new composition
+func (f *Fulfiller) refreshAfterRevert(ctx context.Context, logger *slog.Logger) {
+	tokens, weights, err := f.fetchUnderlyingTokens(ctx)
+	if err != nil {
+		logger.Warn("Failed to re-read underlying tokens after revert", "error", err)
+		return
+	}
+	if sameTokens(tokens, f.underlying().tokens) {
+		return
+	}
+	if err := f.replaceUnderlying(ctx, logger, tokens, weights); err != nil {
+		logger.Error("Failed to refresh underlying tokens after revert", "error", err)
+	}
+}
+
+// replaceUnderlying loads decimals for any new tokens and swaps in the new token list
+func (f *Fulfiller) replaceUnderlying(ctx context.Context, logger *slog.Logger, tokens []common.Address, weights []*big.Int) error {
+	before := f.underlying().tokens
+
+	decimals := make(map[common.Address]uint8)
+	for _, token := range tokens {
+		f.mu.Lock()
+		_, ok := f.tokenDecimals[token]
+		f.mu.Unlock()
+		if ok {
+			continue
+		}
+		d, err := f.getTokenDecimals(ctx, token)
+		if err != nil {
+			return fmt.Errorf("failed to get decimals for token %s: %v", token.Hex(), err)
+		}
+		if err := validateDecimals(d); err != nil {
+			return fmt.Errorf("underlying token %s: %v", token.Hex(), err)
+		}
+		decimals[token] = d
+	}
+
+	f.mu.Lock()
+	for token, d := range decimals {
+		f.tokenDecimals[token] = d
+	}
+	f.mu.Unlock()
+	f.setUnderlying(tokens, weights)
+
+	logger.Warn("VAULT UNDERLYING TOKENS CHANGED - refreshed cached composition",
+		"vault_name", f.vaultConfig.Name,
+		"before_tokens", tokenList(before),
+		"after_tokens", tokenList(tokens),
+	)
+	return nil
+}
+
+func tokenList(tokens []common.Address) string {
+	hexes := make([]string, len(tokens))
+	for i, token := range tokens {
+		hexes[i] = token.Hex()
+	}
+	return strings.Join(hexes, ",")
+}
+
+func sameTokens(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameWeights(a, b []*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -1,40 +0,0 @@
-package main
-
-import (
-	"log/slog"
-	"os"
-	"strings"
-)
-
-var Logger *slog.Logger
-
-// InitLogger initializes the global logger with the specified configuration
-func InitLogger(level, format string) {
-	var logLevel slog.Level
-	switch strings.ToUpper(level) {
-	case "DEBUG":
-		logLevel = slog.LevelDebug
-	case "INFO":
-		logLevel = slog.LevelInfo
-	case "WARN", "WARNING":
-		logLevel = slog.LevelWarn
-	case "ERROR":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
-	}
-
-	var handler slog.Handler
-	opts := &slog.HandlerOptions{
-		Level: logLevel,
-	}
-
-	// Use JSON format for production, text for development
-	if strings.ToUpper(format) == "JSON" {
-		handler = slog.NewJSONHandler(os.Stdout, opts)
-	} else {
-		handler = slog.NewTextHandler(os.Stdout, opts)
-	}
-
-	Logger = slog.New(handler)
-}
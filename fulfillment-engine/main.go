@@ -2,19 +2,26 @@ package main
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"flag"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"tone-fulfillment-engine/engine"
 )
 
 func main() {
+	// Command-line flags override their environment variables; what is left names a subcommand
+	args, err := engine.ParseFlags(os.Args[1:], os.Stderr)
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+
 	// Load configuration first (before logging is initialized)
-	config, err := LoadConfig()
+	config, err := engine.LoadConfig()
 	if err != nil {
 		// Can't use logger yet, use stderr
 		os.Stderr.WriteString("Failed to load config: " + err.Error() + "\n")
@@ -22,150 +29,96 @@ func main() {
 	}
 
 	// Initialize logger with configuration
-	InitLogger(config.LogLevel, config.LogFormat)
+	logOutput, logFile, err := engine.OpenLogOutput(config)
+	if err != nil {
+		os.Stderr.WriteString("Failed to open log file: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	engine.InitLogger(config.LogLevel, config.LogFormat, logOutput)
+	logger := engine.Logger
 
-	Logger.Info("TONE Finance - Fulfillment Engine starting",
+	logger.Info("TONE Finance - Fulfillment Engine starting",
 		"log_level", config.LogLevel,
 		"log_format", config.LogFormat,
 		"vault_count", len(config.SectorVaults),
+		"rpc_endpoint_count", len(config.RPCURLs),
+		"fulfill_mode", config.FulfillMode,
 	)
 
-	// Connect to Ethereum client (shared across all vaults)
-	client, err := ethclient.Dial(config.RPCURL)
+	eng, err := engine.New(config)
 	if err != nil {
-		Logger.Error("Failed to connect to ethereum client", "error", err)
+		logger.Error("Failed to start fulfillment engine", "error", err)
 		os.Exit(1)
 	}
-	defer client.Close()
+	defer eng.Shutdown()
 
-	// Parse private key (shared across all vaults)
-	privateKey, err := crypto.HexToECDSA(config.PrivateKey[2:]) // Remove 0x prefix
-	if err != nil {
-		Logger.Error("Invalid private key", "error", err)
-		os.Exit(1)
-	}
-
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		Logger.Error("Cannot assert type: publicKey is not of type *ecdsa.PublicKey")
-		os.Exit(1)
-	}
-
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	Logger.Info("Fulfiller wallet initialized",
-		"address", fromAddress.Hex(),
-	)
-
-	// Create fulfillers and listeners for each vault
-	var fulfillers []*Fulfiller
-	var listeners []*EventListener
-
-	acc := &fulfillerAccount{
-		nonce:       nil, // fetch on first use
-		fromAddress: fromAddress,
-		privateKey:  privateKey,
-		client:      client,
+	// One-shot report mode: `tone-fulfillment-engine drift` prints composition drift and exits
+	if len(args) > 0 && args[0] == "drift" {
+		if err := eng.DriftReport(os.Stdout); err != nil {
+			logger.Error("Drift report failed", "error", err)
+			eng.Shutdown()
+			os.Exit(1)
+		}
+		return
 	}
 
-	for _, vaultConfig := range config.SectorVaults {
-		Logger.Debug("Initializing vault",
-			"vault_name", vaultConfig.Name,
-			"vault_address", vaultConfig.Address.Hex(),
-		)
-
-		// Create fulfiller for this vault
-		fulfiller, err := NewFulfiller(config, vaultConfig, acc)
+	// One-shot audit: `tone-fulfillment-engine reconcile` compares the WAL with on-chain request state.
+	// Exits 2 if they disagree, so it can back a monitoring check.
+	if len(args) > 0 && args[0] == "reconcile" {
+		mismatch, err := eng.Reconcile(os.Stdout)
 		if err != nil {
-			Logger.Error("Failed to create fulfiller",
-				"vault_name", vaultConfig.Name,
-				"error", err,
-			)
+			logger.Error("Reconciliation failed", "error", err)
+			eng.Shutdown()
 			os.Exit(1)
 		}
-		fulfillers = append(fulfillers, fulfiller)
-
-		// Create event listener for this vault
-		listener := NewEventListener(client, config, vaultConfig, fulfiller)
-		listeners = append(listeners, listener)
+		if mismatch {
+			eng.Shutdown()
+			os.Exit(2)
+		}
+		return
 	}
 
-	// Ensure all fulfillers are closed on exit
-	defer func() {
-		for _, f := range fulfillers {
-			f.Close()
+	// One-shot diagnostic: `tone-fulfillment-engine simulate <vault> <quote-amount>` prints the token
+	// amounts a deposit would be fulfilled with, using live prices and weights, without sending anything
+	if len(args) > 0 && args[0] == "simulate" {
+		if len(args) != 3 {
+			os.Stderr.WriteString("usage: tone-fulfillment-engine simulate <vault> <quote-amount>\n")
+			eng.Shutdown()
+			os.Exit(2)
 		}
-	}()
-
-	// Start listening for events
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Handle graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	// Track listener completion
-	var wg sync.WaitGroup
-	listenerErr := make(chan error, len(listeners))
-
-	// Start all listeners in goroutines
-	for i, listener := range listeners {
-		wg.Add(1)
-		vaultName := config.SectorVaults[i].Name
-
-		go func(l *EventListener, name string) {
-			defer wg.Done()
-			Logger.Info("Starting event listener", "vault_name", name)
-			if err := l.Start(ctx); err != nil && err != context.Canceled {
-				Logger.Error("Listener error", "vault_name", name, "error", err)
-				listenerErr <- err
-			}
-		}(listener, vaultName)
+		if err := eng.Simulate(os.Stdout, args[1], args[2]); err != nil {
+			logger.Error("Simulation failed", "error", err)
+			eng.Shutdown()
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Wait for shutdown signal or listener error
-	select {
-	case <-sigChan:
-		Logger.Info("Shutdown signal received, initiating graceful shutdown",
-			"shutdown_timeout", config.ShutdownTimeout,
-		)
-
-		// Cancel context to stop listener polling
-		cancel()
-
-		// Create shutdown context with timeout
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
-		defer shutdownCancel()
-
-		// Wait for in-flight fulfillments with timeout
-		shutdownComplete := make(chan struct{})
-		go func() {
-			Logger.Info("Waiting for in-flight fulfillments to complete")
-			for _, f := range fulfillers {
-				f.Wait()
+	// Handle graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Re-read the configuration on SIGHUP and apply the settings that are safe to change live
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupChan:
+				logger.Info("SIGHUP received, reloading config")
+				eng.Reload()
 			}
-			close(shutdownComplete)
-		}()
-
-		select {
-		case <-shutdownComplete:
-			Logger.Info("All in-flight fulfillments completed")
-		case <-shutdownCtx.Done():
-			Logger.Warn("Shutdown timeout reached, forcing exit",
-				"timeout", config.ShutdownTimeout,
-			)
 		}
+	}()
 
-		// Wait for all listeners to stop
-		wg.Wait()
-		Logger.Info("Fulfillment engine stopped gracefully")
-
-	case err := <-listenerErr:
-		cancel()
-		Logger.Error("Listener error, shutting down", "error", err)
-		// Wait for all listeners to stop before exiting
-		wg.Wait()
+	if err := eng.Run(ctx); err != nil {
+		logger.Error("Fulfillment engine stopped", "error", err)
+		eng.Shutdown()
 		os.Exit(1)
 	}
 }
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -10,19 +13,31 @@ import (
 	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
 )
 
 const (
-	vaultAddress          = "0x70E6a36bb71549C78Cd9c9f660B0f67B13B3f772"
+	// Vault queried when neither -vault nor the engine's vault settings name one
+	defaultVaultAddress   = "0x70E6a36bb71549C78Cd9c9f660B0f67B13B3f772"
 	depositRequestedTopic = "0x827893a5f98dbfaba92dbe0bb2cafe8b9fd5573711d9768ce5cd4e2af44601ac"
 	// Etherscan API v2 unified endpoint
 	etherscanAPI = "https://api.etherscan.io/v2/api"
-	// Base Sepolia chain ID
-	baseSepoliaChainID = "84532"
+	// Base Sepolia chain ID, overridable with -chainid or CHAIN_ID
+	defaultChainID = "84532"
+	// Default public Base Sepolia RPC, overridable with -rpc, RPC_URL or BASE_SEPOLIA_RPC_URL
+	defaultRPCURL = "https://sepolia.base.org"
+
+	// Minimal ABIs for resolving the quote token decimals
+	quoteTokenABI = `[{"inputs":[],"name":"QUOTE_TOKEN","outputs":[{"name":"","type":"address"}],"stateMutability":"view","type":"function"}]`
+	decimalsABI   = `[{"inputs":[],"name":"decimals","outputs":[{"name":"","type":"uint8"}],"stateMutability":"view","type":"function"}]`
 )
 
 type Deposit struct {
@@ -32,12 +47,12 @@ type Deposit struct {
 	Timestamp *big.Int
 }
 
-func queryLogs(apiKey string) ([]map[string]interface{}, error) {
+func queryLogs(apiKey string, vault common.Address, chainID string) ([]map[string]interface{}, error) {
 	params := url.Values{}
-	params.Add("chainid", baseSepoliaChainID)
+	params.Add("chainid", chainID)
 	params.Add("module", "logs")
 	params.Add("action", "getLogs")
-	params.Add("address", vaultAddress)
+	params.Add("address", vault.Hex())
 	params.Add("topic0", depositRequestedTopic)
 	params.Add("fromBlock", "0")
 	params.Add("toBlock", "latest")
@@ -45,7 +60,8 @@ func queryLogs(apiKey string) ([]map[string]interface{}, error) {
 
 	fullURL := etherscanAPI + "?" + params.Encode()
 
-	fmt.Printf("Querying Etherscan API v2...\n")
+	// Progress goes to stderr so CSV or JSON written to stdout stays clean
+	fmt.Fprintf(os.Stderr, "Querying Etherscan API v2...\n")
 	resp, err := http.Get(fullURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query API: %v", err)
@@ -90,95 +106,149 @@ func queryLogs(apiKey string) ([]map[string]interface{}, error) {
 	return logs, nil
 }
 
-func main() {
-	// Load .env file
-	if err := godotenv.Load("../.env"); err != nil {
-		log.Printf("Warning: could not load .env file: %v", err)
+// callView calls a no-argument view function and unpacks its single return value
+func callView(ctx context.Context, client *ethclient.Client, abiJSON string, to common.Address, method string) (interface{}, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %v", err)
 	}
-
-	apiKey := os.Getenv("BASESCAN_API_KEY")
-	if apiKey == "" {
-		log.Fatal("BASESCAN_API_KEY not found in environment or .env file")
+	data, err := parsedABI.Pack(method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %v", method, err)
 	}
-
-	logs, err := queryLogs(apiKey)
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: data}, nil)
 	if err != nil {
-		log.Fatalf("Failed to query logs: %v", err)
+		return nil, fmt.Errorf("failed to call %s: %v", method, err)
+	}
+	values, err := parsedABI.Unpack(method, result)
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("failed to unpack %s: %v", method, err)
 	}
+	return values[0], nil
+}
 
-	if len(logs) == 0 {
-		fmt.Println("=== Tone Finance Depositors ===\n")
-		fmt.Println("No deposits found")
-		return
+// resolveVault picks the vault to query: the -vault flag, then the engine's SECTOR_VAULTS,
+// SECTOR_VAULT_<NAME> and SECTOR_VAULT settings, then defaultVaultAddress.
+// More than one configured vault is an error, since the script reports on one at a time.
+func resolveVault(flagValue string) (common.Address, error) {
+	var vaults []string
+	if flagValue != "" {
+		vaults = []string{flagValue}
+	} else {
+		for _, addr := range strings.Split(os.Getenv("SECTOR_VAULTS"), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				vaults = append(vaults, addr)
+			}
+		}
+		for _, name := range []string{"AI", "MIA", "DEFI", "GAMING", "MEME"} {
+			if addr := os.Getenv("SECTOR_VAULT_" + name); addr != "" {
+				vaults = append(vaults, addr)
+			}
+		}
+		if len(vaults) == 0 {
+			if addr := os.Getenv("SECTOR_VAULT"); addr != "" {
+				vaults = append(vaults, addr)
+			}
+		}
 	}
 
+	switch len(vaults) {
+	case 0:
+		return common.HexToAddress(defaultVaultAddress), nil
+	case 1:
+		if !common.IsHexAddress(vaults[0]) {
+			return common.Address{}, fmt.Errorf("invalid vault address %q", vaults[0])
+		}
+		return common.HexToAddress(vaults[0]), nil
+	default:
+		return common.Address{}, fmt.Errorf("%d vaults configured (%s) - pass -vault to pick one", len(vaults), strings.Join(vaults, ", "))
+	}
+}
 
-	// Parse logs and extract unique depositors
-	depositors := make(map[string]bool)
-	deposits := make([]Deposit, 0)
+// resolveChainID returns the -chainid flag, then CHAIN_ID, then defaultChainID
+func resolveChainID(flagValue string) (string, error) {
+	chainID := flagValue
+	if chainID == "" {
+		chainID = os.Getenv("CHAIN_ID")
+	}
+	if chainID == "" {
+		return defaultChainID, nil
+	}
+	if _, err := strconv.ParseUint(chainID, 10, 64); err != nil {
+		return "", fmt.Errorf("invalid chain ID %q", chainID)
+	}
+	return chainID, nil
+}
 
-	for _, logItem := range logs {
-		topics, ok := logItem["topics"].([]interface{})
-		if !ok || len(topics) < 2 {
-			continue
+// resolveRPCURL returns the -rpc flag, then RPC_URL (as used by the engine), then
+// BASE_SEPOLIA_RPC_URL, then defaultRPCURL
+func resolveRPCURL(flagValue string) string {
+	for _, rpcURL := range []string{flagValue, os.Getenv("RPC_URL"), os.Getenv("BASE_SEPOLIA_RPC_URL")} {
+		if rpcURL != "" {
+			return rpcURL
 		}
+	}
+	return defaultRPCURL
+}
 
-		data, ok := logItem["data"].(string)
-		if !ok {
-			continue
-		}
+// fetchQuoteDecimals reads the vault's QUOTE_TOKEN and returns its decimals(). It fails if the RPC
+// serves a different chain than chainID.
+func fetchQuoteDecimals(rpcURL, chainID string, vault common.Address) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-		// Extract user address from topic[1] (indexed parameter)
-		userTopic, ok := topics[1].(string)
-		if !ok {
-			continue
-		}
-		// Topic is already an address (padded to 32 bytes), extract the last 40 hex chars (20 bytes)
-		userAddress := "0x" + userTopic[len(userTopic)-40:]
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to RPC: %v", err)
+	}
+	defer client.Close()
 
-		// Parse data: remove 0x prefix and parse hex
-		data = strings.TrimPrefix(data, "0x")
-		// Event has 2 uint256 parameters: amount and requestId
-		if len(data) < 128 { // 2 * 64 hex chars for 2 uint256 params
-			continue
-		}
+	rpcChainID, err := client.ChainID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chain ID from RPC: %v", err)
+	}
+	if rpcChainID.String() != chainID {
+		return 0, fmt.Errorf("RPC %s is on chain %s, not chain %s - pass -rpc or set RPC_URL for chain %s", rpcURL, rpcChainID, chainID, chainID)
+	}
 
-		// amount (first 32 bytes / 64 hex chars)
-		amountHex := "0x" + data[0:64]
-		amount := new(big.Int)
-		amount.SetString(amountHex, 0)
+	quoteToken, err := callView(ctx, client, quoteTokenABI, vault, "QUOTE_TOKEN")
+	if err != nil {
+		return 0, err
+	}
+	decimals, err := callView(ctx, client, decimalsABI, quoteToken.(common.Address), "decimals")
+	if err != nil {
+		return 0, err
+	}
+	return int(decimals.(uint8)), nil
+}
 
-		// requestId (second 32 bytes / 64 hex chars)
-		requestIDHex := "0x" + data[64:128]
-		requestID := new(big.Int)
-		requestID.SetString(requestIDHex, 0)
+// formatUnits renders amount in human-readable decimal form with full precision
+func formatUnits(amount *big.Int, decimals int) string {
+	if decimals <= 0 {
+		return amount.String()
+	}
+	base := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(amount), base, new(big.Int))
 
-		// Get timestamp from the log
-		timeStampStr, ok := logItem["timeStamp"].(string)
-		if !ok {
-			continue
-		}
-		timeStampStr = strings.TrimPrefix(timeStampStr, "0x")
-		timestamp := new(big.Int)
-		timestamp.SetString(timeStampStr, 16) // Parse as hex
-
-		userLower := strings.ToLower(userAddress)
-		depositors[userLower] = true
-		deposits = append(deposits, Deposit{
-			ID:        requestID,
-			User:      userLower,
-			Amount:    amount,
-			Timestamp: timestamp,
-		})
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	fracStr := strings.TrimRight(fmt.Sprintf("%0*s", decimals, frac.String()), "0")
+	if fracStr == "" {
+		return sign + whole.String()
 	}
+	return sign + whole.String() + "." + fracStr
+}
 
-	// Print results
-	fmt.Println("=== Tone Finance Depositors ===")
-	fmt.Printf("Total deposit requests: %d\n", len(deposits))
-	fmt.Printf("Unique depositors: %d\n\n", len(depositors))
+// writeText prints the human-readable depositor report
+func writeText(w io.Writer, deposits []Deposit, depositors map[string]bool, decimals int) {
+	fmt.Fprintln(w, "=== Tone Finance Depositors ===")
+	fmt.Fprintf(w, "Total deposit requests: %d\n", len(deposits))
+	fmt.Fprintf(w, "Unique depositors: %d\n\n", len(depositors))
 
 	// Print unique depositors list
-	fmt.Println("=== Depositor List ===")
+	fmt.Fprintln(w, "=== Depositor List ===")
 	uniqueDepositors := make([]string, 0, len(depositors))
 	for addr := range depositors {
 		uniqueDepositors = append(uniqueDepositors, addr)
@@ -186,25 +256,254 @@ func main() {
 	sort.Strings(uniqueDepositors)
 
 	for i, addr := range uniqueDepositors {
-		fmt.Printf("%d. %s\n", i+1, addr)
+		fmt.Fprintf(w, "%d. %s\n", i+1, addr)
 	}
 
 	// Print deposit details
-	fmt.Println("\n=== Deposit Details ===")
+	fmt.Fprintln(w, "\n=== Deposit Details ===")
 	for _, deposit := range deposits {
-		// Convert amount from wei to USDC (6 decimals)
-		usdcAmount := new(big.Float).Quo(
-			new(big.Float).SetInt(deposit.Amount),
-			new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(6), nil)),
-		)
-
 		// Format timestamp as readable date
 		ts := time.Unix(deposit.Timestamp.Int64(), 0).UTC()
 
-		fmt.Printf("Deposit #%s\n", deposit.ID.String())
-		fmt.Printf("  User: %s\n", deposit.User)
-		fmt.Printf("  Amount: %s USDC\n", usdcAmount.String())
-		fmt.Printf("  Timestamp: %s\n", ts.Format("2006-01-02 15:04:05 UTC"))
-		fmt.Println()
+		fmt.Fprintf(w, "Deposit #%s\n", deposit.ID.String())
+		fmt.Fprintf(w, "  User: %s\n", deposit.User)
+		fmt.Fprintf(w, "  Amount: %s USDC\n", formatUnits(deposit.Amount, decimals))
+		fmt.Fprintf(w, "  Timestamp: %s\n", ts.Format("2006-01-02 15:04:05 UTC"))
+		fmt.Fprintln(w)
+	}
+}
+
+// writeCSV writes one row per deposit: id, user, raw amount, decimal amount and ISO-8601 timestamp
+func writeCSV(w io.Writer, deposits []Deposit, decimals int) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "user", "amount_raw", "amount_usdc", "timestamp_iso"}); err != nil {
+		return err
+	}
+	for _, deposit := range deposits {
+		ts := time.Unix(deposit.Timestamp.Int64(), 0).UTC()
+		if err := cw.Write([]string{
+			deposit.ID.String(),
+			deposit.User,
+			deposit.Amount.String(),
+			formatUnits(deposit.Amount, decimals),
+			ts.Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// jsonReport is the document written with -json
+type jsonReport struct {
+	Vault         string        `json:"vault"`
+	ChainID       string        `json:"chain_id"`
+	QuoteDecimals int           `json:"quote_decimals"`
+	TotalDeposits int           `json:"total_deposits"`
+	UniqueCount   int           `json:"unique_depositors"`
+	Depositors    []string      `json:"depositors"`
+	Deposits      []jsonDeposit `json:"deposits"`
+}
+
+type jsonDeposit struct {
+	ID         string `json:"id"`
+	User       string `json:"user"`
+	AmountRaw  string `json:"amount_raw"`
+	AmountUSDC string `json:"amount_usdc"`
+	Timestamp  string `json:"timestamp_iso"`
+}
+
+// writeJSON writes the deposits and sorted unique depositors as one JSON document.
+// Amounts are strings so large values keep full precision.
+func writeJSON(w io.Writer, vault common.Address, chainID string, deposits []Deposit, depositors map[string]bool, decimals int) error {
+	report := jsonReport{
+		Vault:         strings.ToLower(vault.Hex()),
+		ChainID:       chainID,
+		QuoteDecimals: decimals,
+		TotalDeposits: len(deposits),
+		UniqueCount:   len(depositors),
+		Depositors:    make([]string, 0, len(depositors)),
+		Deposits:      make([]jsonDeposit, 0, len(deposits)),
+	}
+	for addr := range depositors {
+		report.Depositors = append(report.Depositors, addr)
+	}
+	sort.Strings(report.Depositors)
+
+	for _, deposit := range deposits {
+		report.Deposits = append(report.Deposits, jsonDeposit{
+			ID:         deposit.ID.String(),
+			User:       deposit.User,
+			AmountRaw:  deposit.Amount.String(),
+			AmountUSDC: formatUnits(deposit.Amount, decimals),
+			Timestamp:  time.Unix(deposit.Timestamp.Int64(), 0).UTC().Format(time.RFC3339),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// parseWord parses a 32-byte hex word (with or without 0x prefix)
+func parseWord(word string) (*big.Int, bool) {
+	word = strings.TrimPrefix(word, "0x")
+	if len(word) != 64 {
+		return nil, false
+	}
+	return new(big.Int).SetString(word, 16)
+}
+
+// decodeDepositLog decodes an Etherscan log for
+// DepositRequested(address indexed user, uint256 indexed depositId, uint256 quoteAmount, uint256 timestamp):
+// topics are [signature, user, depositId] and data holds quoteAmount followed by timestamp
+func decodeDepositLog(logItem map[string]interface{}) (Deposit, bool) {
+	topics, ok := logItem["topics"].([]interface{})
+	if !ok || len(topics) < 3 {
+		return Deposit{}, false
+	}
+
+	// user from topic[1]: address left-padded to 32 bytes
+	userTopic, ok := topics[1].(string)
+	if !ok {
+		return Deposit{}, false
+	}
+	user, ok := parseWord(userTopic)
+	if !ok {
+		return Deposit{}, false
+	}
+
+	// depositId from topic[2]
+	depositIDTopic, ok := topics[2].(string)
+	if !ok {
+		return Deposit{}, false
+	}
+	depositID, ok := parseWord(depositIDTopic)
+	if !ok {
+		return Deposit{}, false
+	}
+
+	// data: quoteAmount (first 32 bytes) and timestamp (second 32 bytes)
+	data, ok := logItem["data"].(string)
+	if !ok {
+		return Deposit{}, false
+	}
+	data = strings.TrimPrefix(data, "0x")
+	if len(data) < 128 {
+		return Deposit{}, false
+	}
+	amount, ok := parseWord(data[0:64])
+	if !ok {
+		return Deposit{}, false
+	}
+	timestamp, ok := parseWord(data[64:128])
+	if !ok {
+		return Deposit{}, false
+	}
+
+	return Deposit{
+		ID:        depositID,
+		User:      strings.ToLower(common.BigToAddress(user).Hex()),
+		Amount:    amount,
+		Timestamp: timestamp,
+	}, true
+}
+
+func main() {
+	format := flag.String("format", "text", "output format: text, csv or json")
+	jsonOut := flag.Bool("json", false, "emit a JSON document (same as -format json)")
+	outPath := flag.String("out", "", "write output to this file instead of stdout")
+	vaultFlag := flag.String("vault", "", "vault address (default: SECTOR_VAULTS, SECTOR_VAULT_<NAME> or SECTOR_VAULT, else "+defaultVaultAddress+")")
+	chainIDFlag := flag.String("chainid", "", "chain ID for the Etherscan query (default: CHAIN_ID, else "+defaultChainID+")")
+	decimals := flag.Int("decimals", -1, "quote token decimals for the human-readable amount (fetched from chain if unset)")
+	rpcFlag := flag.String("rpc", "", "RPC URL for fetching the quote token decimals, on the -chainid chain (default: RPC_URL, BASE_SEPOLIA_RPC_URL, else "+defaultRPCURL+")")
+	flag.Parse()
+
+	if *jsonOut {
+		*format = "json"
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		log.Fatalf("Unsupported -format %q (expected text, csv or json)", *format)
+	}
+
+	// Load .env file
+	if err := godotenv.Load("../.env"); err != nil {
+		log.Printf("Warning: could not load .env file: %v", err)
+	}
+
+	vault, err := resolveVault(*vaultFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chainID, err := resolveChainID(*chainIDFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "Vault %s on chain %s\n", vault.Hex(), chainID)
+
+	apiKey := os.Getenv("BASESCAN_API_KEY")
+	if apiKey == "" {
+		log.Fatal("BASESCAN_API_KEY not found in environment or .env file")
+	}
+
+	if *decimals < 0 {
+		fetched, err := fetchQuoteDecimals(resolveRPCURL(*rpcFlag), chainID, vault)
+		if err != nil {
+			log.Fatalf("Failed to fetch quote token decimals (pass -decimals to skip): %v", err)
+		}
+		*decimals = fetched
+	}
+
+	logs, err := queryLogs(apiKey, vault, chainID)
+	if err != nil {
+		log.Fatalf("Failed to query logs: %v", err)
+	}
+
+	if len(logs) == 0 && *format == "text" {
+		fmt.Print("=== Tone Finance Depositors ===\n\n")
+		fmt.Println("No deposits found")
+		return
+	}
+
+	// Parse logs and extract unique depositors
+	depositors := make(map[string]bool)
+	deposits := make([]Deposit, 0)
+
+	for _, logItem := range logs {
+		deposit, ok := decodeDepositLog(logItem)
+		if !ok {
+			continue
+		}
+
+		depositors[deposit.User] = true
+		deposits = append(deposits, deposit)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	switch *format {
+	case "csv":
+		if err := writeCSV(out, deposits, *decimals); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+	case "json":
+		if err := writeJSON(out, vault, chainID, deposits, depositors, *decimals); err != nil {
+			log.Fatalf("Failed to write JSON: %v", err)
+		}
+	default:
+		writeText(out, deposits, depositors, *decimals)
+	}
+
+	if *outPath != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d deposits to %s\n", len(deposits), *outPath)
 	}
 }